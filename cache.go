@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/heather7532/nuro/cache"
+	"github.com/heather7532/nuro/provider"
+	"github.com/spf13/pflag"
+)
+
+// runCache implements "nuro cache stats|prune|clear" for the on-disk response cache.
+func runCache(args []string) {
+	fs := pflag.NewFlagSet("cache", pflag.ContinueOnError)
+	configName := fs.StringP("cfg", "c", "", "Use a named configuration profile from .nuro file")
+	ttlArg := fs.String("ttl", "", "Maximum age of entries for stats and prune (default: cache_ttl or 24h).")
+	jsonOut := fs.Bool("json", false, "Emit stats as JSON.")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: nuro cache stats|prune|clear [--ttl 24h] [--cfg name] [--json]")
+		fs.PrintDefaults()
+	}
+	parseSubcommand(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		exitWithErr(usageError("expected one of stats, prune, clear"), 2)
+	}
+
+	prof, err := loadProfile(*configName)
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	// --ttl, then the selected profile, NURO_CACHE_TTL and the default profile
+	ttlStr := *ttlArg
+	if ttlStr == "" && prof != nil && prof.selected {
+		ttlStr = prof.CacheTTL
+	}
+	if ttlStr == "" {
+		ttlStr = cliEnv.Env["NURO_CACHE_TTL"]
+	}
+	if ttlStr == "" && prof != nil {
+		ttlStr = prof.CacheTTL
+	}
+	ttl := cache.DefaultTTL
+	if ttlStr != "" {
+		d, err := parseCacheTTL(ttlStr)
+		if err != nil {
+			exitWithErr(err, 2)
+		}
+		ttl = d
+	}
+
+	dir, err := cache.DefaultDir(cliEnv.Env["NURO_CACHE_DIR"])
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	store := &cache.Store{Dir: dir}
+
+	switch fs.Arg(0) {
+	case "stats":
+		st, err := store.Stats(ttl)
+		if err != nil {
+			exitWithErr(err, 2)
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(st)
+			return
+		}
+		fmt.Printf("dir:     %s\n", st.Dir)
+		fmt.Printf("entries: %d (%s)\n", st.Entries, formatBytes(int(st.Bytes)))
+		fmt.Printf("expired: %d (ttl %s)\n", st.Expired, ttl)
+		if st.Entries > 0 {
+			fmt.Printf("oldest:  %s\n", st.Oldest.Local().Format(time.RFC3339))
+			fmt.Printf("newest:  %s\n", st.Newest.Local().Format(time.RFC3339))
+		}
+	case "prune":
+		if ttl == 0 {
+			exitWithErr(usageError("prune needs a non-zero --ttl"), 2)
+		}
+		n, err := store.Prune(ttl)
+		if err != nil {
+			exitWithErr(err, 2)
+		}
+		fmt.Printf("removed %d entries older than %s\n", n, ttl)
+	case "clear":
+		n, err := store.Clear()
+		if err != nil {
+			exitWithErr(err, 2)
+		}
+		fmt.Printf("removed %d entries\n", n)
+	default:
+		fs.Usage()
+		exitWithErr(usageError(fmt.Sprintf("unknown cache command %q", fs.Arg(0))), 2)
+	}
+}
+
+// newCacheProvider wraps prov, talking to baseURL, with the response cache in the
+// default cache directory.
+func newCacheProvider(
+	prov provider.Provider, baseURL, ttlStr string, only bool,
+) (*cache.Provider, error) {
+	ttl, err := parseCacheTTL(ttlStr)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := cache.DefaultDir(cliEnv.Env["NURO_CACHE_DIR"])
+	if err != nil {
+		return nil, err
+	}
+	return &cache.Provider{
+		Inner: prov, BaseURL: baseURL, Store: &cache.Store{Dir: dir}, TTL: ttl, Only: only,
+	}, nil
+}
+
+// parseCacheTTL parses a cache TTL; "0" disables expiry.
+func parseCacheTTL(s string) (time.Duration, error) {
+	if s == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, usageError(fmt.Sprintf("invalid cache TTL %q (use a duration like 24h, or 0)", s))
+	}
+	return d, nil
+}
+
+// reportCache prints cache hits and misses under --verbose.
+func reportCache(c *cache.Provider, verbose bool) {
+	if c == nil || !verbose {
+		return
+	}
+	_, _ = fmt.Fprintf(
+		os.Stderr, "nuro: cache hits=%d misses=%d dir=%s\n", c.Hits, c.Misses, c.Store.Dir,
+	)
+}
@@ -0,0 +1,352 @@
+// Package cache stores completed responses on disk, keyed by a fingerprint of the
+// request, so identical requests (e.g. in CI or notebooks) are answered without calling
+// the provider again.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+// DefaultTTL is how long entries are served when no TTL is configured.
+const DefaultTTL = 24 * time.Hour
+
+// ErrMiss is returned in cache-only mode when no fresh entry exists for a request.
+var ErrMiss = errors.New("no cached response for this request (--cache-only)")
+
+// Entry is one cached response.
+type Entry struct {
+	Key          string         `json:"key"`
+	Provider     string         `json:"provider"`
+	Model        string         `json:"model"`
+	Text         string         `json:"text"`
+	Usage        provider.Usage `json:"usage"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// Store is a directory of cache entries, sharded by the first two hex digits of the key.
+type Store struct {
+	Dir string
+}
+
+// DefaultDir returns configured, the directory set by the caller (nuro uses
+// $NURO_CACHE_DIR), or "nuro" under the user's cache directory when it is empty.
+func DefaultDir(configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate a cache directory (set NURO_CACHE_DIR): %w", err)
+	}
+	return filepath.Join(d, "nuro"), nil
+}
+
+// fingerprint holds everything that changes the answer. Transport settings (stream,
+// timeout, keep_alive) are left out so they can vary between runs.
+type fingerprint struct {
+	Provider     string `json:"provider"`
+	BaseURL      string `json:"base_url,omitempty"`
+	Model        string `json:"model"`
+	System       string `json:"system,omitempty"`
+	Prompt       string `json:"prompt"`
+	Data         string `json:"data,omitempty"`
+	Continuation string `json:"continuation,omitempty"`
+
+	MaxTokens       int      `json:"max_tokens"`
+	Temperature     float64  `json:"temperature"`
+	TopP            float64  `json:"top_p"`
+	Seed            *int     `json:"seed,omitempty"`
+	Stop            []string `json:"stop,omitempty"`
+	NumCtx          int      `json:"num_ctx,omitempty"`
+	RepeatPenalty   float64  `json:"repeat_penalty,omitempty"`
+	TopK            int      `json:"top_k,omitempty"`
+	MinP            float64  `json:"min_p,omitempty"`
+	Mirostat        int      `json:"mirostat,omitempty"`
+	OllamaAPI       string   `json:"ollama_api,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+	Verbosity       string   `json:"verbosity,omitempty"`
+}
+
+// Key returns the hex SHA-256 fingerprint of a request to the named provider at
+// baseURL, so endpoints serving the same model name are cached apart.
+func Key(providerName, baseURL string, args provider.CompletionArgs) string {
+	b, _ := json.Marshal(
+		fingerprint{
+			Provider:        providerName,
+			BaseURL:         baseURL,
+			Model:           args.Model,
+			System:          args.System,
+			Prompt:          args.Prompt,
+			Data:            args.Data,
+			Continuation:    args.Continuation,
+			MaxTokens:       args.MaxTokens,
+			Temperature:     args.Temperature,
+			TopP:            args.TopP,
+			Seed:            args.Seed,
+			Stop:            args.Stop,
+			NumCtx:          args.NumCtx,
+			RepeatPenalty:   args.RepeatPenalty,
+			TopK:            args.TopK,
+			MinP:            args.MinP,
+			Mirostat:        args.Mirostat,
+			OllamaAPI:       args.OllamaAPI,
+			ReasoningEffort: args.ReasoningEffort,
+			Verbosity:       args.Verbosity,
+		},
+	)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.Dir, key[:2], key+".json")
+}
+
+// Get returns the entry for key if it exists and is younger than ttl (0 means no expiry).
+func (s *Store) Get(key string, ttl time.Duration) (*Entry, bool) {
+	b, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil || e.Key != key {
+		return nil, false
+	}
+	if ttl > 0 && time.Since(e.CreatedAt) > ttl {
+		return nil, false
+	}
+	return &e, true
+}
+
+// Put stores an entry, replacing any previous entry for the same key atomically.
+func (s *Store) Put(e *Entry) error {
+	p := s.path(e.Key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Stats summarizes the contents of a store.
+type Stats struct {
+	Dir     string    `json:"dir"`
+	Entries int       `json:"entries"`
+	Bytes   int64     `json:"bytes"`
+	Expired int       `json:"expired"`
+	Oldest  time.Time `json:"oldest,omitempty"`
+	Newest  time.Time `json:"newest,omitempty"`
+}
+
+// Stats walks the store; entries older than ttl (when ttl > 0) are counted as expired.
+func (s *Store) Stats(ttl time.Duration) (Stats, error) {
+	st := Stats{Dir: s.Dir}
+	err := s.walk(
+		func(path string, info fs.FileInfo) error {
+			st.Entries++
+			st.Bytes += info.Size()
+			created := entryTime(path, info)
+			if st.Oldest.IsZero() || created.Before(st.Oldest) {
+				st.Oldest = created
+			}
+			if created.After(st.Newest) {
+				st.Newest = created
+			}
+			if ttl > 0 && time.Since(created) > ttl {
+				st.Expired++
+			}
+			return nil
+		},
+	)
+	return st, err
+}
+
+// Prune removes entries older than ttl and returns how many were removed.
+func (s *Store) Prune(ttl time.Duration) (int, error) {
+	n := 0
+	err := s.walk(
+		func(path string, info fs.FileInfo) error {
+			if time.Since(entryTime(path, info)) <= ttl {
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			n++
+			return nil
+		},
+	)
+	return n, err
+}
+
+// Clear removes every entry and returns how many were removed.
+func (s *Store) Clear() (int, error) {
+	n := 0
+	err := s.walk(
+		func(path string, _ fs.FileInfo) error {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			n++
+			return nil
+		},
+	)
+	return n, err
+}
+
+// walk calls fn for every entry file. A missing store directory is an empty store.
+func (s *Store) walk(fn func(path string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(
+		s.Dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".json") || strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return fn(path, info)
+		},
+	)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// entryTime reads the creation time of an entry, falling back to the file's mtime.
+func entryTime(path string, info fs.FileInfo) time.Time {
+	b, err := os.ReadFile(path)
+	if err == nil {
+		var e struct {
+			CreatedAt time.Time `json:"created_at"`
+		}
+		if json.Unmarshal(b, &e) == nil && !e.CreatedAt.IsZero() {
+			return e.CreatedAt
+		}
+	}
+	return info.ModTime()
+}
+
+// Provider wraps another provider and answers repeated requests from a Store. Hits are
+// replayed through onDelta when streaming, one line per delta, so callers cannot tell
+// them from live answers.
+type Provider struct {
+	Inner provider.Provider
+	// BaseURL is the endpoint Inner talks to; it is part of the key.
+	BaseURL string
+	Store   *Store
+	TTL     time.Duration
+	Only    bool // serve from the cache only; misses return ErrMiss
+
+	// Hits and Misses count the lookups made through this provider.
+	Hits, Misses int
+}
+
+func (p *Provider) Name() string { return p.Inner.Name() }
+
+func (p *Provider) Complete(ctx context.Context, args provider.CompletionArgs) (
+	string, provider.Usage, string, error,
+) {
+	return p.lookup(
+		args, func() (string, provider.Usage, string, error) {
+			return p.Inner.Complete(ctx, args)
+		}, nil,
+	)
+}
+
+func (p *Provider) Stream(
+	ctx context.Context, args provider.CompletionArgs, onDelta func(delta string),
+) (string, provider.Usage, string, error) {
+	return p.lookup(
+		args, func() (string, provider.Usage, string, error) {
+			return p.Inner.Stream(ctx, args, onDelta)
+		}, onDelta,
+	)
+}
+
+func (p *Provider) lookup(
+	args provider.CompletionArgs, call func() (string, provider.Usage, string, error),
+	onDelta func(string),
+) (string, provider.Usage, string, error) {
+	key := Key(p.Inner.Name(), p.BaseURL, args)
+	if e, ok := p.Store.Get(key, p.TTL); ok {
+		p.Hits++
+		if onDelta != nil {
+			for _, line := range strings.SplitAfter(e.Text, "\n") {
+				if line != "" {
+					onDelta(line)
+				}
+			}
+		}
+		return e.Text, e.Usage, e.FinishReason, nil
+	}
+	p.Misses++
+	if p.Only {
+		return "", provider.Usage{}, "", ErrMiss
+	}
+
+	text, usage, finish, err := call()
+	if err != nil {
+		return text, usage, finish, err
+	}
+	// A degraded answer, e.g. from a fallback link, is not stored: it would be served
+	// under the first link's key and model long after that provider has recovered
+	if d, ok := p.Inner.(provider.Degrader); ok && d.Degraded() {
+		return text, usage, finish, nil
+	}
+	// A failed write only costs a future cache miss, so it is not reported as an error
+	_ = p.Store.Put(
+		&Entry{
+			Key:          key,
+			Provider:     p.Inner.Name(),
+			Model:        args.Model,
+			Text:         text,
+			Usage:        usage,
+			FinishReason: finish,
+			CreatedAt:    time.Now().UTC(),
+		},
+	)
+	return text, usage, finish, nil
+}
+
+// Has reports whether a fresh answer for args is stored, without counting a lookup.
+func (p *Provider) Has(args provider.CompletionArgs) bool {
+	_, ok := p.Store.Get(Key(p.Inner.Name(), p.BaseURL, args), p.TTL)
+	return ok
+}
+
+// Cached reports whether every lookup so far was answered from the cache.
+func (p *Provider) Cached() bool { return p.Hits > 0 && p.Misses == 0 }
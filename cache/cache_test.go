@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Complete(_ context.Context, args provider.CompletionArgs) (
+	string, provider.Usage, string, error,
+) {
+	p.calls++
+	return "answer to " + args.Prompt, provider.Usage{TotalTokens: 7}, provider.FinishStop, nil
+}
+
+func (p *countingProvider) Stream(
+	ctx context.Context, args provider.CompletionArgs, onDelta func(string),
+) (string, provider.Usage, string, error) {
+	text, usage, finish, err := p.Complete(ctx, args)
+	onDelta(text)
+	return text, usage, finish, err
+}
+
+func TestKey(t *testing.T) {
+	base := provider.CompletionArgs{Model: "m", Prompt: "p", MaxTokens: 10, Temperature: 0.7}
+	k := Key("openai", "", base)
+
+	same := base
+	same.Stream = true
+	same.Timeout = time.Minute
+	same.KeepAlive = "10m"
+	if Key("openai", "", same) != k {
+		t.Error("transport settings should not change the key")
+	}
+
+	seed := 1
+	changes := map[string]func(a *provider.CompletionArgs){
+		"model":       func(a *provider.CompletionArgs) { a.Model = "other" },
+		"prompt":      func(a *provider.CompletionArgs) { a.Prompt = "q" },
+		"data":        func(a *provider.CompletionArgs) { a.Data = "d" },
+		"system":      func(a *provider.CompletionArgs) { a.System = "s" },
+		"temperature": func(a *provider.CompletionArgs) { a.Temperature = 0.2 },
+		"seed":        func(a *provider.CompletionArgs) { a.Seed = &seed },
+		"stop":        func(a *provider.CompletionArgs) { a.Stop = []string{"x"} },
+		"continuation": func(a *provider.CompletionArgs) {
+			a.Continuation = "partial"
+		},
+	}
+	for name, change := range changes {
+		t.Run(
+			name, func(t *testing.T) {
+				a := base
+				change(&a)
+				if Key("openai", "", a) == k {
+					t.Errorf("changing %s should change the key", name)
+				}
+			},
+		)
+	}
+	if Key("ollama", "", base) == k {
+		t.Error("provider should be part of the key")
+	}
+	if Key("openai", "http://localhost:8000/v1", base) == k {
+		t.Error("base URL should be part of the key")
+	}
+}
+
+func TestProviderHitReplaysThroughOnDelta(t *testing.T) {
+	inner := &countingProvider{}
+	p := &Provider{Inner: inner, Store: &Store{Dir: t.TempDir()}, TTL: time.Hour}
+	args := provider.CompletionArgs{Model: "m", Prompt: "hi\nthere"}
+
+	text, _, _, err := p.Complete(context.Background(), args)
+	if err != nil || text != "answer to hi\nthere" {
+		t.Fatalf("Complete: %q, %v", text, err)
+	}
+	if p.Cached() {
+		t.Error("first request should be a miss")
+	}
+
+	var deltas []string
+	text, usage, finish, err := p.Stream(
+		context.Background(), args, func(d string) { deltas = append(deltas, d) },
+	)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected the second request to be served from the cache, got %d calls", inner.calls)
+	}
+	if text != "answer to hi\nthere" || usage.TotalTokens != 7 || finish != provider.FinishStop {
+		t.Errorf("unexpected cached result %q %+v %q", text, usage, finish)
+	}
+	if strings.Join(deltas, "|") != "answer to hi\n|there" {
+		t.Errorf("cached text should be replayed through onDelta line by line, got %q", deltas)
+	}
+	if p.Hits != 1 || p.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %d/%d", p.Hits, p.Misses)
+	}
+}
+
+func TestProviderTTLAndCacheOnly(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+	args := provider.CompletionArgs{Model: "m", Prompt: "old"}
+	key := Key("counting", "", args)
+	if err := store.Put(
+		&Entry{Key: key, Text: "stale", CreatedAt: time.Now().Add(-2 * time.Hour)},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	only := &Provider{Inner: &countingProvider{}, Store: store, TTL: time.Hour, Only: true}
+	if _, _, _, err := only.Complete(context.Background(), args); !errors.Is(err, ErrMiss) {
+		t.Errorf("expired entry should be a miss in cache-only mode, got %v", err)
+	}
+
+	noExpiry := &Provider{Inner: &countingProvider{}, Store: store, Only: true}
+	if text, _, _, err := noExpiry.Complete(context.Background(), args); err != nil || text != "stale" {
+		t.Errorf("TTL 0 should never expire, got %q, %v", text, err)
+	}
+
+	if only.Has(args) || !noExpiry.Has(args) || only.Hits+only.Misses != 1 {
+		t.Errorf("Has should follow the TTL without counting lookups, got %+v", only)
+	}
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) Complete(context.Context, provider.CompletionArgs) (
+	string, provider.Usage, string, error,
+) {
+	return "", provider.Usage{}, "", &provider.APIError{Op: "failing", StatusCode: 503}
+}
+
+func (p failingProvider) Stream(
+	ctx context.Context, args provider.CompletionArgs, _ func(string),
+) (string, provider.Usage, string, error) {
+	return p.Complete(ctx, args)
+}
+
+func TestProviderSkipsFallbackAnswers(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+	backup := &countingProvider{}
+	args := provider.CompletionArgs{Model: "primary-model", Prompt: "hi"}
+	for range 2 {
+		fb := &provider.Fallback{
+			Links: []provider.FallbackLink{
+				{Provider: failingProvider{}, Model: "primary-model"},
+				{Provider: backup, Model: "backup-model"},
+			},
+		}
+		p := &Provider{Inner: fb, Store: store, TTL: time.Hour}
+		if text, _, _, err := p.Complete(context.Background(), args); err != nil || text != "answer to hi" {
+			t.Fatalf("Complete: %q, %v", text, err)
+		}
+	}
+	if backup.calls != 2 {
+		t.Errorf("a fallback answer should not be served from the cache, got %d calls", backup.calls)
+	}
+	if st, err := store.Stats(0); err != nil || st.Entries != 0 {
+		t.Errorf("expected no entries stored, got %+v, %v", st, err)
+	}
+
+	// Once the first link answers again, its answers are cached as usual
+	p := &Provider{
+		Inner: &provider.Fallback{Links: []provider.FallbackLink{{Provider: backup, Model: "m"}}},
+		Store: store, TTL: time.Hour,
+	}
+	for range 2 {
+		if _, _, _, err := p.Complete(context.Background(), args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.Hits != 1 {
+		t.Errorf("expected the first link's answer to be cached, got %d hits", p.Hits)
+	}
+}
+
+// degradedProvider answers like countingProvider but reports a degraded answer.
+type degradedProvider struct{ countingProvider }
+
+func (*degradedProvider) Degraded() bool { return true }
+
+func TestProviderSkipsDegradedAnswers(t *testing.T) {
+	inner := &degradedProvider{}
+	p := &Provider{Inner: inner, Store: &Store{Dir: t.TempDir()}, TTL: time.Hour}
+	for range 2 {
+		if _, _, _, err := p.Complete(context.Background(), provider.CompletionArgs{Prompt: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.calls != 2 || p.Hits != 0 {
+		t.Errorf("a degraded answer should not be cached, got %d calls and %d hits", inner.calls, p.Hits)
+	}
+}
+
+func TestStoreStatsPruneClear(t *testing.T) {
+	store := &Store{Dir: filepath.Join(t.TempDir(), "cache")}
+
+	// A store that was never written to is empty, not an error
+	if st, err := store.Stats(time.Hour); err != nil || st.Entries != 0 {
+		t.Fatalf("empty store: %+v, %v", st, err)
+	}
+
+	now := time.Now()
+	for i, age := range []time.Duration{time.Minute, 3 * time.Hour, 48 * time.Hour} {
+		key := Key("p", "", provider.CompletionArgs{Prompt: string(rune('a' + i))})
+		if err := store.Put(&Entry{Key: key, CreatedAt: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st, err := store.Stats(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Entries != 3 || st.Expired != 2 || st.Bytes == 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	n, err := store.Prune(24 * time.Hour)
+	if err != nil || n != 1 {
+		t.Errorf("Prune removed %d (%v), want 1", n, err)
+	}
+	n, err = store.Clear()
+	if err != nil || n != 2 {
+		t.Errorf("Clear removed %d (%v), want 2", n, err)
+	}
+	if _, err := os.Stat(store.Dir); err != nil {
+		t.Errorf("Clear should keep the cache directory: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/heather7532/nuro/cassette"
+	"github.com/heather7532/nuro/provider"
+)
+
+// useCassette routes provider HTTP traffic through a cassette in dir. mode is
+// cassette.ModeRecord, cassette.ModeReplay, or "" to replay when dir already holds
+// recordings and record otherwise (as with NURO_CASSETTE). It replaces any cassette set
+// up before, so --record and --replay take precedence over NURO_CASSETTE.
+func useCassette(dir, mode string, verbose bool) error {
+	var (
+		rt  http.RoundTripper
+		err error
+	)
+	switch mode {
+	case cassette.ModeRecord:
+		rt, err = cassette.NewRecorder(dir, nil)
+	case cassette.ModeReplay:
+		rt, err = cassette.Load(dir)
+	default:
+		rt, mode, err = cassette.Open(dir, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("cassette %s: %w", dir, err)
+	}
+	provider.HTTPTransport = rt
+	if verbose {
+		_, _ = fmt.Fprintf(os.Stderr, "nuro: cassette %s mode=%s\n", dir, mode)
+	}
+	return nil
+}
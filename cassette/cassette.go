@@ -0,0 +1,318 @@
+// Package cassette records provider HTTP traffic to a directory and replays it offline,
+// so provider adapters and scripts can be tested without network access or a live
+// Ollama. Streaming (SSE and NDJSON) responses are stored verbatim. Credentials are
+// redacted before anything is written.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Modes returned by Open
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// Redacted replaces credentials in recorded headers and URLs.
+const Redacted = "REDACTED"
+
+// Interaction is one recorded request/response pair, stored as one JSON file.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// secretHeaders are never written to a cassette.
+var secretHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Api-Key", "X-Api-Key", "Cookie", "Set-Cookie",
+	"Openai-Organization", "Openai-Project",
+}
+
+// secretParams are query parameters that carry credentials.
+var secretParams = []string{"key", "api_key", "api-key", "token", "access_token"}
+
+// Open returns a transport for dir: it replays when dir already holds recorded
+// interactions and records through next otherwise.
+func Open(dir string, next http.RoundTripper) (http.RoundTripper, string, error) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) > 0 {
+		p, err := Load(dir)
+		return p, ModeReplay, err
+	}
+	r, err := NewRecorder(dir, next)
+	return r, ModeRecord, err
+}
+
+// Recorder is an http.RoundTripper that saves every exchange made through it.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecorder creates dir if needed and records through next (nil for
+// http.DefaultTransport). New interactions are numbered after any already in dir.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	return &Recorder{dir: dir, next: next, seq: len(files)}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readAndRestore(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	it := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: redactHeader(req.Header),
+			Body:   string(reqBody),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     redactHeader(resp.Header),
+		},
+	}
+	// The response is saved once the caller closes the body, so streams are captured
+	// as they were delivered without being buffered up front.
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body, save: func(body []byte) error {
+			it.Response.Body = string(body)
+			return r.save(&it)
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(it *Interaction) error {
+	r.mu.Lock()
+	r.seq++
+	name := fmt.Sprintf("%03d-%s-%s.json", r.seq, strings.ToLower(it.Request.Method), slug(it.Request.URL))
+	r.mu.Unlock()
+
+	b, err := json.MarshalIndent(it, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, name), append(b, '\n'), 0o644)
+}
+
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	save func([]byte) error
+	once sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// Close drains what the caller did not read (e.g. the bytes after an SSE [DONE]) so the
+// cassette holds the complete response.
+func (b *recordingBody) Close() error {
+	var saveErr error
+	b.once.Do(
+		func() {
+			_, _ = io.Copy(&b.buf, b.ReadCloser)
+			saveErr = b.save(b.buf.Bytes())
+		},
+	)
+	if err := b.ReadCloser.Close(); err != nil {
+		return err
+	}
+	return saveErr
+}
+
+// Player is an http.RoundTripper that answers requests from recorded interactions. It
+// never touches the network.
+type Player struct {
+	mu    sync.Mutex
+	queue map[string][]*Interaction
+	last  map[string]*Interaction
+}
+
+// Load reads the interactions recorded in dir.
+func Load(dir string) (*Player, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded interactions in %s", dir)
+	}
+	sort.Strings(files)
+	p := &Player{queue: map[string][]*Interaction{}, last: map[string]*Interaction{}}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var it Interaction
+		if err := json.Unmarshal(b, &it); err != nil {
+			return nil, fmt.Errorf("invalid cassette file %s: %w", f, err)
+		}
+		u, err := url.Parse(it.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL in cassette file %s: %w", f, err)
+		}
+		k := matchKey(it.Request.Method, u, []byte(it.Request.Body))
+		p.queue[k] = append(p.queue[k], &it)
+	}
+	return p, nil
+}
+
+// RoundTrip serves matching interactions in the order they were recorded; once they are
+// used up, the last one keeps being served so a cassette can be replayed repeatedly.
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readAndRestore(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	u := *req.URL
+	u.RawQuery = redactQuery(u.Query()).Encode()
+	k := matchKey(req.Method, &u, body)
+
+	p.mu.Lock()
+	it := p.last[k]
+	if q := p.queue[k]; len(q) > 0 {
+		it, p.queue[k] = q[0], q[1:]
+		p.last[k] = it
+	}
+	p.mu.Unlock()
+	if it == nil {
+		return nil, fmt.Errorf("cassette: no recorded interaction for %s %s", req.Method, req.URL.Path)
+	}
+
+	header := it.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode:    it.Response.StatusCode,
+		Status:        it.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(it.Response.Body)),
+		ContentLength: int64(len(it.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matchKey identifies a request by method, path, query and body. Scheme and host are
+// ignored so a cassette can be replayed against any base URL, and JSON bodies are
+// compared by content rather than formatting.
+func matchKey(method string, u *url.URL, body []byte) string {
+	var v any
+	if json.Unmarshal(body, &v) == nil {
+		if b, err := json.Marshal(v); err == nil {
+			body = b
+		}
+	}
+	return method + " " + u.Path + "?" + u.Query().Encode() + "\n" + string(body)
+}
+
+func readAndRestore(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*body)
+	_ = (*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+func redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for _, name := range secretHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, Redacted)
+		}
+	}
+	return out
+}
+
+func redactQuery(q url.Values) url.Values {
+	for _, name := range secretParams {
+		if q.Has(name) {
+			q.Set(name, Redacted)
+		}
+	}
+	return q
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	c.RawQuery = redactQuery(c.Query()).Encode()
+	return c.String()
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns the path of a URL into a short file name fragment, e.g. "api-generate".
+func slug(rawURL string) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	s := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(path), "-"), "-")
+	if len(s) > 40 {
+		s = s[len(s)-40:]
+	}
+	if s == "" {
+		s = "root"
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplayOffline(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Set-Cookie", "session=secret")
+				flusher := w.(http.Flusher)
+				for _, chunk := range []string{"data: {\"n\":1}\n\n", "data: {\"n\":2}\n\n", "data: [DONE]\n\n"} {
+					_, _ = io.WriteString(w, chunk)
+					flusher.Flush()
+				}
+			},
+		),
+	)
+
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rec}
+	req, _ := http.NewRequest(
+		"POST", srv.URL+"/v1/chat/completions?api_key=sk-query", strings.NewReader(`{"model":"m","stream":true}`),
+	)
+	req.Header.Set("Authorization", "Bearer sk-live-secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read only the first event, as a client stopping early would
+	buf := make([]byte, 14)
+	_, _ = io.ReadFull(resp.Body, buf)
+	_ = resp.Body.Close()
+	srv.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 || filepath.Base(files[0]) != "001-post-v1-chat-completions.json" {
+		t.Fatalf("unexpected cassette files %v", files)
+	}
+	raw, _ := os.ReadFile(files[0])
+	for _, secret := range []string{"sk-live-secret", "sk-query", "session=secret"} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("cassette contains secret %q", secret)
+		}
+	}
+
+	player, mode, err := Open(dir, nil)
+	if err != nil || mode != ModeReplay {
+		t.Fatalf("Open: mode=%s err=%v", mode, err)
+	}
+	client = &http.Client{Transport: player}
+	// Different host, key and JSON formatting: still the same request
+	req, _ = http.NewRequest(
+		"POST", "http://offline.invalid/v1/chat/completions?api_key=other",
+		strings.NewReader(`{ "stream": true, "model": "m" }`),
+	)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("headers not replayed: %v", resp.Header)
+	}
+	want := "data: {\"n\":1}\n\ndata: {\"n\":2}\n\ndata: [DONE]\n\n"
+	if string(body) != want {
+		t.Errorf("replayed body %q, want the complete stream %q", body, want)
+	}
+	if calls != 1 {
+		t.Errorf("replay should not reach the server, got %d calls", calls)
+	}
+}
+
+func TestReplayOrderAndMisses(t *testing.T) {
+	n := 0
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				n++
+				if n == 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+				}
+				_, _ = io.WriteString(w, strings.Repeat("x", n))
+			},
+		),
+	)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	rec, _ := NewRecorder(dir, nil)
+	for i := 0; i < 2; i++ {
+		resp, err := (&http.Client{Transport: rec}).Get(srv.URL + "/api/tags")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	player, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: player}
+	for i, want := range []struct {
+		status int
+		body   string
+	}{{429, "x"}, {200, "xx"}, {200, "xx"}} {
+		resp, err := client.Get("http://localhost:11434/api/tags")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != want.status || string(b) != want.body {
+			t.Errorf("request %d: got %d %q, want %d %q", i, resp.StatusCode, b, want.status, want.body)
+		}
+	}
+
+	if _, err := client.Get("http://localhost:11434/api/ps"); err == nil ||
+		!strings.Contains(err.Error(), "no recorded interaction for GET /api/ps") {
+		t.Errorf("expected a miss error, got %v", err)
+	}
+}
+
+func TestOpenRecordsIntoEmptyDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "new")
+	_, mode, err := Open(dir, nil)
+	if err != nil || mode != ModeRecord {
+		t.Fatalf("Open: mode=%s err=%v", mode, err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Load of an empty cassette should fail")
+	}
+}
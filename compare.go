@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+const compareUsage = "usage: nuro compare -m model -m model [-m ...] -p prompt [--data ...] [--json]"
+
+// compareResult is one model's answer in "nuro compare".
+type compareResult struct {
+	Provider     string         `json:"provider"`
+	Model        string         `json:"model"`
+	Text         string         `json:"text"`
+	Usage        provider.Usage `json:"usage"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	LatencyMS    int64          `json:"latency_ms"`
+	CostUSD      *float64       `json:"cost_usd,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// runCompare implements "nuro compare": send the same prompt and data to several models
+// concurrently and show the answers side by side with latency, tokens and cost.
+func runCompare(args []string) {
+	fs := pflag.NewFlagSet("compare", pflag.ContinueOnError)
+	models := fs.StringArrayP(
+		"model", "m", nil, "Model to compare (repeatable); provider:model picks the provider explicitly.",
+	)
+	var f cliFlags
+	fs.StringVarP(&f.promptFlag, "prompt", "p", "", "Prompt text.")
+	fs.BoolVarP(&f.promptUseStdin, "prompt-stdin", "s", false, "Read prompt from stdin.")
+	fs.StringVar(&f.promptFile, "prompt-file", "", "Read the prompt from a file.")
+	fs.StringArrayVar(
+		&f.allowIncludes, "allow-include", nil,
+		"File or directory outside the working directory, or hidden, that @path may read (repeatable).",
+	)
+	fs.StringVar(&f.dataInline, "data", "", "Inline data/payload string.")
+	fs.StringArrayVar(
+		&f.dataFiles, "data-file", nil, "File, directory or glob containing data/payload (repeatable).",
+	)
+	fs.StringVar(&f.system, "system", "", "System prompt sent ahead of the user message.")
+	fs.IntVar(&f.maxTokens, "max-tokens", 1024, "Max tokens for each completion.")
+	fs.Float64Var(&f.temperature, "temperature", 0.7, "Sampling temperature.")
+	fs.Float64Var(&f.topP, "top-p", 1.0, "Top-p (nucleus sampling).")
+	fs.IntVar(&f.timeoutSec, "timeout", 120, "Timeout in seconds for each model.")
+	fs.BoolVar(&f.jsonOut, "json", false, "Emit the comparison as JSON.")
+	fs.BoolVarP(&f.force, "force", "f", false, "Force sending large data without warnings.")
+	fs.BoolVar(&f.noExtract, "no-extract", false, "Send --data-file and stdin as-is, without extracting text.")
+	fs.StringVarP(&f.configName, "cfg", "c", "", "Use a named configuration profile from .nuro file")
+	fs.BoolVar(&f.verbose, "verbose", false, "Verbose diagnostics to stderr.")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, compareUsage)
+		fs.PrintDefaults()
+	}
+	parseSubcommand(fs, args)
+	if len(*models) < 2 || fs.NArg() > 0 {
+		fs.Usage()
+		exitWithErr(usageError("compare needs at least two -m models"), 2)
+	}
+
+	profile, settings, err := loadSettings(f.configName)
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	// Profiles and NURO_* variables fill in the request like they do for "nuro -c profile"
+	params, err := resolveParams(&f, fs, profile, cliEnv.Env)
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	prompt, data, err := resolvePromptAndData(&f)
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	if strings.TrimSpace(prompt) == "" && strings.TrimSpace(data) == "" {
+		exitWithErr(usageError("compare needs a prompt or data"), 2)
+	}
+	if err := validateDataSize(data, f.includedBytes, f.force, f.verbose); err != nil {
+		exitWithErr(err, 2)
+	}
+	enforceNoRedactionPolicy("compare")
+	enforceDataPolicy(len(data)+f.includedBytes, f.system, buildCombinedContent(prompt, data))
+
+	// Resolve every model before sending anything, so a typo fails fast
+	provs := make([]provider.Provider, len(*models))
+	resolved := make([]*provider.ProviderResolution, len(*models))
+	for i, m := range *models {
+		res, err := resolver.ResolveModelRef(settings, m)
+		if err != nil {
+			exitWithErr(fmt.Errorf("%s: %w", m, err), 3)
+		}
+		if err := checkTargetPolicy(res); err != nil {
+			exitWithErr(fmt.Errorf("%s: %w", m, err), exitPolicy)
+		}
+		prov, err := provider.BuildProvider(res)
+		if err != nil {
+			exitWithErr(fmt.Errorf("%s: %w", m, err), 3)
+		}
+		provs[i], resolved[i] = prov, res
+		if f.verbose {
+			_, _ = fmt.Fprintf(
+				os.Stderr, "nuro: compare provider=%s model=%s key=%s source=%s\n", res.ProviderName,
+				res.Model, redactKey(res.APIKey), res.KeySource,
+			)
+		}
+	}
+
+	base := completionArgs(&f, params)
+	base.Prompt, base.Data = prompt, data
+	results := compareModels(context.Background(), provs, resolved, base, f.verbose)
+
+	if f.jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(struct {
+			Results []compareResult `json:"results"`
+		}{results})
+	} else {
+		printComparison(os.Stdout, results, terminalWidth())
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		exitWithErr(fmt.Errorf("%d of %d models failed", failed, len(results)), 4)
+	}
+}
+
+// compareModels runs args against every provider concurrently, each with its own
+// timeout, and returns the results in the order given.
+func compareModels(
+	ctx context.Context, provs []provider.Provider, resolved []*provider.ProviderResolution,
+	args provider.CompletionArgs, verbose bool,
+) []compareResult {
+	ctx = context.WithValue(ctx, "nuro_verbose", verbose)
+	results := make([]compareResult, len(provs))
+	var wg sync.WaitGroup
+	for i := range provs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prov, res := provs[i], resolved[i]
+			a := args
+			a.Model = res.Model
+			actx, cancel := context.WithTimeout(ctx, a.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			text, usage, finish, err := prov.Complete(actx, a)
+			r := compareResult{
+				Provider:     prov.Name(),
+				Model:        res.Model,
+				Text:         text,
+				Usage:        usage,
+				FinishReason: finish,
+				LatencyMS:    time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				r.Error = err.Error()
+			} else if cost, ok := provider.EstimateCost(r.Provider, r.Model, usage); ok {
+				r.CostUSD = &cost
+			}
+			results[i] = r
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// compareMinColumn is the narrowest column worth printing side by side; below it the
+// answers are printed one after another.
+const compareMinColumn = 24
+
+// wrapLine breaks one line of an answer into pieces of at most width visible characters.
+// Unlike wrapWords it keeps the spacing of the line, so code stays indented: a line that
+// fits is kept as it is, and a longer one is broken at spaces and continued at its
+// indentation. Words longer than a line are left whole.
+func wrapLine(line string, width int) []string {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	if visibleWidth(line) <= width {
+		return []string{line}
+	}
+	body := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(body)]
+	if visibleWidth(indent) > width/2 {
+		indent = indent[:width/2]
+	}
+	var lines []string
+	cur, empty := line[:len(line)-len(body)], true
+	for _, word := range strings.Split(body, " ") {
+		if !empty && visibleWidth(cur)+1+visibleWidth(word) > width {
+			lines = append(lines, strings.TrimRight(cur, " "))
+			cur, empty = indent, true
+		}
+		if !empty {
+			cur += " "
+		}
+		cur += word
+		empty = false
+	}
+	return append(lines, cur)
+}
+
+// printComparison prints the answers in columns that fit width, followed by a table of
+// latency, tokens and cost.
+func printComparison(w io.Writer, results []compareResult, width int) {
+	const sep = " │ "
+	col := (width - visibleWidth(sep)*(len(results)-1)) / len(results)
+
+	answer := func(r compareResult) string {
+		if r.Error != "" {
+			return "error: " + r.Error
+		}
+		return r.Text
+	}
+	if col < compareMinColumn {
+		for i, r := range results {
+			if i > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+			_, _ = fmt.Fprintf(w, "=== %s (%s) ===\n%s\n", r.Model, r.Provider, strings.TrimRight(answer(r), "\n"))
+		}
+	} else {
+		columns := make([][]string, len(results))
+		rows := 0
+		for i, r := range results {
+			lines := wrapWords(r.Model+" ("+r.Provider+")", col, "", "")
+			lines = append(lines, strings.Repeat("─", col))
+			for _, line := range strings.Split(strings.TrimRight(answer(r), "\n"), "\n") {
+				lines = append(lines, wrapLine(line, col)...)
+			}
+			columns[i] = lines
+			rows = max(rows, len(lines))
+		}
+		for row := 0; row < rows; row++ {
+			cells := make([]string, len(columns))
+			for i, lines := range columns {
+				cell := ""
+				if row < len(lines) {
+					cell = lines[row]
+				}
+				if i < len(columns)-1 {
+					cell += strings.Repeat(" ", max(0, col-visibleWidth(cell)))
+				}
+				cells[i] = cell
+			}
+			_, _ = fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, sep), " "))
+		}
+	}
+
+	_, _ = fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "MODEL\tPROVIDER\tLATENCY\tPROMPT\tCOMPLETION\tTOTAL\tCOST\tFINISH")
+	for _, r := range results {
+		cost := "-"
+		switch {
+		case r.CostUSD == nil:
+		case *r.CostUSD == 0:
+			cost = "$0"
+		default:
+			cost = fmt.Sprintf("$%.6f", *r.CostUSD)
+		}
+		finish := r.FinishReason
+		if r.Error != "" {
+			finish = "error"
+		}
+		_, _ = fmt.Fprintf(
+			tw, "%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n", r.Model, r.Provider,
+			(time.Duration(r.LatencyMS) * time.Millisecond).String(), r.Usage.PromptTokens,
+			r.Usage.CompletionTokens, r.Usage.TotalTokens, cost, finish,
+		)
+	}
+	_ = tw.Flush()
+}
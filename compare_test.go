@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/provider/providertest"
+)
+
+func TestCompareModelsRunsConcurrently(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	slow := providertest.NewServer(
+		providertest.Reply{Text: "slow answer", Delay: delay, PromptTokens: 10, CompletionTokens: 2},
+	)
+	defer slow.Close()
+	failing := providertest.NewServer(providertest.Reply{Status: 500, ErrorMessage: "boom", Delay: delay})
+	defer failing.Close()
+
+	provs := []provider.Provider{
+		provider.NewOpenAIProvider("sk-test-key", slow.URL),
+		provider.NewOllamaProvider(failing.URL),
+		provider.NewOpenAIProvider("sk-test-key", slow.URL),
+	}
+	resolved := []*provider.ProviderResolution{
+		{ProviderName: "openai", Model: "gpt-4o-mini"},
+		{ProviderName: "ollama", Model: "llama3.1:8b"},
+		{ProviderName: "openai", Model: "gpt-4o"},
+	}
+	args := provider.CompletionArgs{Prompt: "hi", Timeout: 5 * time.Second}
+
+	start := time.Now()
+	results := compareModels(context.Background(), provs, resolved, args, false)
+	if elapsed := time.Since(start); elapsed >= 2*delay {
+		t.Errorf("expected the models to run concurrently, comparison took %s", elapsed)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Model != "gpt-4o-mini" || results[0].Text != "slow answer" || results[0].Error != "" {
+		t.Errorf("unexpected first result %+v", results[0])
+	}
+	if results[0].LatencyMS < delay.Milliseconds() {
+		t.Errorf("expected latency of at least %s, got %dms", delay, results[0].LatencyMS)
+	}
+	if results[0].CostUSD == nil || *results[0].CostUSD <= 0 {
+		t.Errorf("expected a cost estimate for gpt-4o-mini, got %v", results[0].CostUSD)
+	}
+	if results[1].Error == "" || !strings.Contains(results[1].Error, "boom") || results[1].CostUSD != nil {
+		t.Errorf("expected the ollama model to fail, got %+v", results[1])
+	}
+	if results[2].Model != "gpt-4o" {
+		t.Errorf("expected results in -m order, got %s", results[2].Model)
+	}
+}
+
+func TestPrintComparison(t *testing.T) {
+	cost := 0.000123
+	results := []compareResult{
+		{
+			Provider: "openai", Model: "gpt-4o-mini", Text: "Paris is the capital of France.",
+			Usage: provider.Usage{PromptTokens: 12, CompletionTokens: 8, TotalTokens: 20}, FinishReason: "stop",
+			LatencyMS: 1250, CostUSD: &cost,
+		},
+		{Provider: "ollama", Model: "llama3.1:8b", Error: "connection refused", LatencyMS: 3},
+	}
+
+	tests := []struct {
+		name  string
+		width int
+		want  []string
+	}{
+		{
+			name:  "side by side",
+			width: 70,
+			want: []string{
+				"gpt-4o-mini (openai)", "│ llama3.1:8b (ollama)", "│ error: connection refused",
+				"$0.000123", "1.25s", "error",
+			},
+		},
+		{
+			name:  "stacked when narrow",
+			width: 40,
+			want:  []string{"=== gpt-4o-mini (openai) ===\nParis is the capital of France.", "=== llama3.1:8b (ollama) ==="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				printComparison(&buf, results, tt.width)
+				out := buf.String()
+				for _, w := range tt.want {
+					if !strings.Contains(out, w) {
+						t.Errorf("expected %q in output:\n%s", w, out)
+					}
+				}
+				for _, line := range strings.Split(out, "\n") {
+					if strings.Contains(line, "│") && visibleWidth(line) > tt.width {
+						t.Errorf("line wider than %d: %q", tt.width, line)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []string
+	}{
+		{
+			name:  "fitting line keeps its spacing",
+			line:  "    if x  == 1 {",
+			width: 30,
+			want:  []string{"    if x  == 1 {"},
+		},
+		{
+			name:  "tabs become spaces",
+			line:  "\treturn nil",
+			width: 30,
+			want:  []string{"    return nil"},
+		},
+		{
+			name:  "long line continues at its indentation",
+			line:  "  fmt.Println(alpha, beta, gamma)",
+			width: 20,
+			want:  []string{"  fmt.Println(alpha,", "  beta, gamma)"},
+		},
+		{
+			name:  "long word is left whole",
+			line:  "see https://example.com/a/very/long/path",
+			width: 20,
+			want:  []string{"see", "https://example.com/a/very/long/path"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := wrapLine(tt.line, tt.width)
+				if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+					t.Errorf("wrapLine(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -6,25 +6,111 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
+	"slices"
 	"strings"
+	"time"
 )
 
 // Profile represents the configuration for a specific LLM setup
 type Profile struct {
-	APIKey      string  `json:"api_key,omitempty"`
-	BaseURL     string  `json:"base_url,omitempty"`
-	Provider    string  `json:"provider,omitempty"`
-	Model       string  `json:"model,omitempty"`
-	MaxTokens   int     `json:"max_tokens,omitempty"`
-	Temperature float64 `json:"temperature,omitempty"`
-	TopP        float64 `json:"top_p,omitempty"`
+	APIKey      string   `json:"api_key,omitempty"`
+	BaseURL     string   `json:"base_url,omitempty"`
+	Provider    string   `json:"provider,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"` // nil when unset, so 0 can be chosen
+	TopP        *float64 `json:"top_p,omitempty"`
+	KeepAlive   string   `json:"keep_alive,omitempty"` // Ollama only, e.g. "10m" or "-1"
+	System      string   `json:"system,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+
+	// Ollama model options; those with a meaningful zero are nil when unset
+	NumCtx        int      `json:"num_ctx,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	MinP          *float64 `json:"min_p,omitempty"`
+	Mirostat      *int     `json:"mirostat,omitempty"`
+	OllamaAPI     string   `json:"ollama_api,omitempty"` // "generate" (default) or "chat"
+
+	// Response cache
+	Cache    bool   `json:"cache,omitempty"`
+	CacheTTL string `json:"cache_ttl,omitempty"` // e.g. "24h", "0" for no expiry
+
+	// Provider fallback chain, tried in order when the profile's provider fails
+	Fallback        []FallbackTarget `json:"fallback,omitempty"`
+	FallbackOn      []string         `json:"fallback_on,omitempty"`      // error classes: 5xx, 429, timeout, network
+	FallbackTimeout string           `json:"fallback_timeout,omitempty"` // per-provider time limit, e.g. "20s"
+}
+
+// FallbackErrorClasses are the error classes a fallback can be triggered by.
+var FallbackErrorClasses = []string{"5xx", "429", "timeout", "network"}
+
+// FallbackTarget is one provider of a fallback chain. In .nuro it is written either as
+// "provider:model" (e.g. "ollama:llama3.1:8b") or as an object with its own api_key and
+// base_url; otherwise the provider's usual environment variables are used.
+type FallbackTarget struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+}
+
+// ParseFallbackTarget parses "provider:model" or just "provider". The model may itself
+// contain colons, as Ollama tags do.
+func ParseFallbackTarget(s string) (FallbackTarget, error) {
+	prov, model, _ := strings.Cut(strings.TrimSpace(s), ":")
+	if prov == "" {
+		return FallbackTarget{}, fmt.Errorf("invalid fallback %q: want provider:model", s)
+	}
+	return FallbackTarget{Provider: strings.ToLower(prov), Model: model}, nil
+}
+
+func (t *FallbackTarget) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		parsed, err := ParseFallbackTarget(s)
+		if err != nil {
+			return err
+		}
+		*t = parsed
+		return nil
+	}
+	type plain FallbackTarget
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return fmt.Errorf("fallback entries must be \"provider:model\" strings or objects: %w", err)
+	}
+	*t = FallbackTarget(p)
+	return nil
+}
+
+func (t FallbackTarget) String() string {
+	if t.Model == "" {
+		return t.Provider
+	}
+	return t.Provider + ":" + t.Model
 }
 
 // Config represents the structure of the .nuro configuration file
 type Config struct {
 	Default  string             `json:"default,omitempty"`
 	Profiles map[string]Profile `json:"profiles,omitempty"`
+	Prompts  map[string]Prompt  `json:"prompts,omitempty"`
+	Redact   *Redaction         `json:"redact,omitempty"`
+	Policy   *Policy            `json:"policy,omitempty"`
+}
+
+// Redaction configures the replacement of sensitive values in prompts and data with
+// placeholders before they are sent, for every profile.
+type Redaction struct {
+	Enabled bool `json:"enabled,omitempty"`
+	Restore bool `json:"restore,omitempty"` // put the original values back into the answer
+	// Detectors names the built-in detectors to use; empty uses all of them.
+	Detectors []string `json:"detectors,omitempty"`
+	// Patterns maps placeholder labels to regular expressions; when a pattern has a
+	// capturing group only the group is replaced.
+	Patterns map[string]string `json:"patterns,omitempty"`
 }
 
 // FindConfigFile looks for .nuro file in current directory, then in home directory
@@ -71,8 +157,9 @@ func LoadConfig() (*Config, error) {
 	return &config, nil
 }
 
-// GetProfile returns a specific profile by name, with environment variable substitution
-func (c *Config) GetProfile(name string) (*Profile, error) {
+// GetProfile returns a specific profile by name, with references to the environment
+// variables env (e.g. "$OPENAI_API_KEY") substituted.
+func (c *Config) GetProfile(name string, env map[string]string) (*Profile, error) {
 	if c.Profiles == nil {
 		return nil, fmt.Errorf("no profiles defined in config")
 	}
@@ -84,13 +171,40 @@ func (c *Config) GetProfile(name string) (*Profile, error) {
 
 	// Apply environment variable substitution to profile values
 	resolved := Profile{
-		APIKey:      resolveEnvVars(profile.APIKey),
-		BaseURL:     resolveEnvVars(profile.BaseURL),
+		APIKey:      resolveEnvVars(profile.APIKey, env),
+		BaseURL:     resolveEnvVars(profile.BaseURL, env),
 		Provider:    profile.Provider,
-		Model:       resolveEnvVars(profile.Model),
+		Model:       resolveEnvVars(profile.Model, env),
 		MaxTokens:   profile.MaxTokens,
 		Temperature: profile.Temperature,
 		TopP:        profile.TopP,
+		KeepAlive:   profile.KeepAlive,
+		System:      resolveEnvVars(profile.System, env),
+		Seed:        profile.Seed,
+		Stop:        profile.Stop,
+
+		NumCtx:        profile.NumCtx,
+		RepeatPenalty: profile.RepeatPenalty,
+		TopK:          profile.TopK,
+		MinP:          profile.MinP,
+		Mirostat:      profile.Mirostat,
+		OllamaAPI:     profile.OllamaAPI,
+
+		Cache:    profile.Cache,
+		CacheTTL: profile.CacheTTL,
+
+		FallbackOn:      profile.FallbackOn,
+		FallbackTimeout: profile.FallbackTimeout,
+	}
+	for _, t := range profile.Fallback {
+		resolved.Fallback = append(
+			resolved.Fallback, FallbackTarget{
+				Provider: t.Provider,
+				Model:    resolveEnvVars(t.Model, env),
+				APIKey:   resolveEnvVars(t.APIKey, env),
+				BaseURL:  resolveEnvVars(t.BaseURL, env),
+			},
+		)
 	}
 
 	return &resolved, nil
@@ -98,7 +212,7 @@ func (c *Config) GetProfile(name string) (*Profile, error) {
 
 // Validate checks if the configuration values are valid
 func (c *Config) Validate() error {
-	if c.Profiles == nil {
+	if c.Profiles == nil && c.Prompts == nil && c.Redact == nil && c.Policy == nil {
 		return fmt.Errorf("config file must contain 'profiles' object")
 	}
 
@@ -112,127 +226,123 @@ func (c *Config) Validate() error {
 	// Validate each profile
 	for name, profile := range c.Profiles {
 		// Validate provider
-		if profile.Provider != "" {
-			validProviders := []string{
-				"openai", "anthropic", "google", "azureopenai", "openrouter", "groq", "mistral",
-				"together", "cohere", "ollama",
-			}
-			valid := false
-			for _, prov := range validProviders {
-				if profile.Provider == prov {
-					valid = true
-					break
-				}
-			}
-			if !valid {
-				return fmt.Errorf(
-					"invalid provider '%s' in profile '%s': must be one of openai, anthropic, google, azureopenai, openrouter, groq, mistral, together, cohere, ollama",
-					profile.Provider, name,
-				)
-			}
+		if profile.Provider != "" && !validProvider(profile.Provider) {
+			return fmt.Errorf(
+				"invalid provider '%s' in profile '%s': must be one of %s",
+				profile.Provider, name, strings.Join(validProviders, ", "),
+			)
 		}
 
 		if profile.MaxTokens < 0 {
 			return fmt.Errorf("max_tokens in profile '%s' must be non-negative", name)
 		}
 
-		if profile.Temperature < 0 || profile.Temperature > 2.0 {
+		if t := profile.Temperature; t != nil && (*t < 0 || *t > 2.0) {
 			return fmt.Errorf("temperature in profile '%s' must be between 0 and 2", name)
 		}
 
-		if profile.TopP < 0 || profile.TopP > 1.0 {
+		if t := profile.TopP; t != nil && (*t < 0 || *t > 1.0) {
 			return fmt.Errorf("top_p in profile '%s' must be between 0 and 1", name)
 		}
-	}
-
-	return nil
-}
 
-// Apply applies the default profile's configuration by setting environment variables
-func (c *Config) Apply() error {
-	if c.Profiles == nil {
-		return nil // No profiles to apply
-	}
-
-	// Choose profile: CLI flag > config.Default > first profile
-	var profileName string
-	if c.Default != "" {
-		profileName = c.Default
-	} else {
-		// Pick the first profile as default
-		for name := range c.Profiles {
-			profileName = name
-			break
+		if profile.NumCtx < 0 {
+			return fmt.Errorf("num_ctx in profile '%s' must be non-negative", name)
 		}
-	}
 
-	// Get and apply the chosen profile
-	profile, err := c.GetProfile(profileName)
-	if err != nil {
-		return err
-	}
+		if r := profile.RepeatPenalty; r != nil && *r < 0 {
+			return fmt.Errorf("repeat_penalty in profile '%s' must be non-negative", name)
+		}
 
-	return profile.Apply()
-}
+		if k := profile.TopK; k != nil && *k < 0 {
+			return fmt.Errorf("top_k in profile '%s' must be non-negative", name)
+		}
 
-// ApplyProfile applies a specific named profile's configuration by setting environment variables
-func (c *Config) ApplyProfile(name string) error {
-	if c.Profiles == nil {
-		return fmt.Errorf("no profiles defined in config")
-	}
+		if m := profile.MinP; m != nil && (*m < 0 || *m > 1.0) {
+			return fmt.Errorf("min_p in profile '%s' must be between 0 and 1", name)
+		}
 
-	profile, err := c.GetProfile(name)
-	if err != nil {
-		return err
-	}
+		if m := profile.Mirostat; m != nil && (*m < 0 || *m > 2) {
+			return fmt.Errorf("mirostat in profile '%s' must be 0, 1 or 2", name)
+		}
 
-	return profile.Apply()
-}
+		if profile.OllamaAPI != "" && profile.OllamaAPI != "generate" && profile.OllamaAPI != "chat" {
+			return fmt.Errorf("ollama_api in profile '%s' must be 'generate' or 'chat'", name)
+		}
 
-// ApplyProfile applies a profile's configuration by setting environment variables
-func (p *Profile) Apply() error {
-	// Set environment variables
-	if p.APIKey != "" {
-		if err := os.Setenv("NURO_API_KEY", p.APIKey); err != nil {
-			return fmt.Errorf("failed to set NURO_API_KEY: %w", err)
+		if profile.CacheTTL != "" && profile.CacheTTL != "0" {
+			if d, err := time.ParseDuration(profile.CacheTTL); err != nil || d < 0 {
+				return fmt.Errorf("cache_ttl in profile '%s' must be a duration like 24h", name)
+			}
 		}
-	}
-	if p.BaseURL != "" {
-		if err := os.Setenv("NURO_BASE_URL", p.BaseURL); err != nil {
-			return fmt.Errorf("failed to set NURO_BASE_URL: %w", err)
+
+		for _, t := range profile.Fallback {
+			if !validProvider(t.Provider) {
+				return fmt.Errorf(
+					"invalid fallback provider '%s' in profile '%s': must be one of %s",
+					t.Provider, name, strings.Join(validProviders, ", "),
+				)
+			}
 		}
-	}
-	if p.Provider != "" {
-		if err := os.Setenv("NURO_PROVIDER", p.Provider); err != nil {
-			return fmt.Errorf("failed to set NURO_PROVIDER: %w", err)
+		for _, class := range profile.FallbackOn {
+			if !slices.Contains(FallbackErrorClasses, class) {
+				return fmt.Errorf(
+					"invalid fallback_on '%s' in profile '%s': must be one of %s",
+					class, name, strings.Join(FallbackErrorClasses, ", "),
+				)
+			}
 		}
-	}
-	if p.Model != "" {
-		if err := os.Setenv("NURO_MODEL", p.Model); err != nil {
-			return fmt.Errorf("failed to set NURO_MODEL: %w", err)
+		if profile.FallbackTimeout != "" {
+			if d, err := time.ParseDuration(profile.FallbackTimeout); err != nil || d <= 0 {
+				return fmt.Errorf("fallback_timeout in profile '%s' must be a duration like 20s", name)
+			}
 		}
 	}
-	if p.MaxTokens > 0 {
-		if err := os.Setenv("NURO_MAX_TOKENS", strconv.Itoa(p.MaxTokens)); err != nil {
-			return fmt.Errorf("failed to set NURO_MAX_TOKENS: %w", err)
+
+	for name, prompt := range c.Prompts {
+		prompt.Name = name
+		if err := prompt.Validate(); err != nil {
+			return err
 		}
-	}
-	if p.Temperature > 0 {
-		if err := os.Setenv("NURO_TEMPERATURE", fmt.Sprintf("%.2f", p.Temperature)); err != nil {
-			return fmt.Errorf("failed to set NURO_TEMPERATURE: %w", err)
+		if prompt.Profile != "" {
+			if _, exists := c.Profiles[prompt.Profile]; !exists {
+				return fmt.Errorf("profile '%s' of prompt '%s' not found in 'profiles'", prompt.Profile, name)
+			}
 		}
 	}
-	if p.TopP > 0 {
-		if err := os.Setenv("NURO_TOP_P", fmt.Sprintf("%.2f", p.TopP)); err != nil {
-			return fmt.Errorf("failed to set NURO_TOP_P: %w", err)
+
+	if c.Policy != nil {
+		if err := c.Policy.Validate(); err != nil {
+			return fmt.Errorf("invalid policy: %w", err)
 		}
 	}
 
 	return nil
 }
 
+var validProviders = []string{
+	"openai", "anthropic", "google", "azureopenai", "openrouter", "groq", "mistral",
+	"together", "cohere", "ollama", "mock",
+}
+
+func validProvider(name string) bool {
+	return slices.Contains(validProviders, name)
+}
+
+// DefaultProfileName returns the profile used when none is selected: the "default"
+// profile, or else the first one.
+func (c *Config) DefaultProfileName() string {
+	if c.Default != "" {
+		return c.Default
+	}
+	// Pick the first profile as default
+	for name := range c.Profiles {
+		return name
+	}
+	return ""
+}
+
 // resolveEnvVars substitutes environment variable references (e.g., "$VAR") in a string
-func resolveEnvVars(value string) string {
+func resolveEnvVars(value string, env map[string]string) string {
 	if value == "" {
 		return ""
 	}
@@ -245,7 +355,7 @@ func resolveEnvVars(value string) string {
 			if strings.HasPrefix(match, "${") {
 				varName = match[2 : len(match)-1] // Extract content between ${}
 			}
-			if envValue := os.Getenv(varName); envValue != "" {
+			if envValue := env[varName]; envValue != "" {
 				return envValue
 			}
 			// Return original if env var not found or empty
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -68,7 +70,7 @@ func TestLoadValidateAndGetProfile(t *testing.T) {
 	}
 
 	// GetProfile should resolve env vars
-	p, err := cfg.GetProfile("test1")
+	p, err := cfg.GetProfile("test1", envMap())
 	if err != nil {
 		t.Fatalf("GetProfile: %v", err)
 	}
@@ -87,10 +89,10 @@ func TestLoadValidateAndGetProfile(t *testing.T) {
 	if p.MaxTokens != 1500 {
 		t.Errorf("MaxTokens mismatch: %d", p.MaxTokens)
 	}
-	if p.Temperature != 1.0 {
+	if p.Temperature == nil || *p.Temperature != 1.0 {
 		t.Errorf("Temperature mismatch: %v", p.Temperature)
 	}
-	if p.TopP != 0.8 {
+	if p.TopP == nil || *p.TopP != 0.8 {
 		t.Errorf("TopP mismatch: %v", p.TopP)
 	}
 }
@@ -129,86 +131,107 @@ func TestConfigValidationErrorForBadProvider(t *testing.T) {
 	}
 }
 
-func TestApplyProfileSetsEnv(t *testing.T) {
-	tmp := t.TempDir()
+func TestResolveEnvVarsStandalone(t *testing.T) {
+	// defensive unit test for the internal resolver behavior your config.go uses
+	t.Setenv("FOO", "bar")
+	t.Setenv("BAZ", "qux")
+	in := "x $FOO y ${BAZ} z $MISSING"
+	out := resolveEnvVars(in, envMap())
+	if !strings.Contains(out, "x bar y qux z") {
+		t.Fatalf("resolveEnvVars failed, got: %q", out)
+	}
+}
 
-	cfgJSON := `{
-  "default": "test1",
+func TestValidateOllamaOptions(t *testing.T) {
+	seed := 7
+	good := &Config{
+		Profiles: map[string]Profile{
+			"local": {
+				Provider: "ollama", NumCtx: 8192, TopK: ref(40), MinP: ref(0.05), Mirostat: ref(2),
+				RepeatPenalty: ref(1.1), Seed: &seed, Stop: []string{"###"}, OllamaAPI: "chat",
+			},
+		},
+	}
+	if err := good.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	bad := []Profile{
+		{Mirostat: ref(3)},
+		{MinP: ref(1.5)},
+		{TopK: ref(-1)},
+		{NumCtx: -1},
+		{OllamaAPI: "completions"},
+	}
+	for _, p := range bad {
+		c := &Config{Profiles: map[string]Profile{"p": p}}
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", p)
+		}
+	}
+}
+
+func TestFallbackConfig(t *testing.T) {
+	t.Setenv("BACKUP_KEY", "sk-backup")
+	var cfg Config
+	err := json.Unmarshal(
+		[]byte(`{
   "profiles": {
-    "test1": {
-      "api_key": "testkey123",
-      "base_url": "https://api.test.com",
+    "resilient": {
       "provider": "openai",
       "model": "gpt-4o-mini",
-      "max_tokens": 1500,
-      "temperature": 1.0,
-      "top_p": 0.8
+      "fallback": [
+        "anthropic:claude-3-5-sonnet",
+        {"provider": "openai", "model": "gpt-4o", "api_key": "$BACKUP_KEY"},
+        "ollama:llama3.1:8b"
+      ],
+      "fallback_on": ["5xx", "429", "timeout"],
+      "fallback_timeout": "20s"
     }
   }
-}`
-	_ = writeTempConfig(t, tmp, cfgJSON)
-
-	oldwd, err := os.Getwd()
+}`), &cfg,
+	)
 	if err != nil {
-		t.Fatalf("getwd: %v", err)
+		t.Fatalf("unmarshal: %v", err)
 	}
-	t.Cleanup(func() { _ = os.Chdir(oldwd) })
-	if err := os.Chdir(tmp); err != nil {
-		t.Fatalf("chdir: %v", err)
-	}
-
-	// Clear any existing NURO_* env to avoid false positives
-	for _, k := range []string{
-		"NURO_API_KEY", "NURO_BASE_URL", "NURO_PROVIDER", "NURO_MODEL",
-		"NURO_MAX_TOKENS", "NURO_TEMPERATURE", "NURO_TOP_P",
-	} {
-		_ = os.Unsetenv(k)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
 	}
-
-	cfg, err := LoadConfig()
+	p, err := cfg.GetProfile("resilient", envMap())
 	if err != nil {
-		t.Fatalf("LoadConfig error: %v", err)
+		t.Fatal(err)
 	}
-	if cfg == nil {
-		t.Fatal("nil cfg")
+	want := []FallbackTarget{
+		{Provider: "anthropic", Model: "claude-3-5-sonnet"},
+		{Provider: "openai", Model: "gpt-4o", APIKey: "sk-backup"},
+		{Provider: "ollama", Model: "llama3.1:8b"},
 	}
-
-	// This uses Config.ApplyProfile which internally resolves then Profile.Apply()
-	if err := cfg.ApplyProfile("test1"); err != nil {
-		t.Fatalf("ApplyProfile: %v", err)
+	if !reflect.DeepEqual(p.Fallback, want) {
+		t.Errorf("expected fallback %+v, got %+v", want, p.Fallback)
 	}
 
-	// Check envs set by Profile.Apply (note temperature/top_p formatted to 2 decimals)
-	if os.Getenv("NURO_API_KEY") != "testkey123" {
-		t.Errorf("NURO_API_KEY not set correctly, got %q", os.Getenv("NURO_API_KEY"))
-	}
-	if os.Getenv("NURO_BASE_URL") != "https://api.test.com" {
-		t.Errorf("NURO_BASE_URL not set correctly, got %q", os.Getenv("NURO_BASE_URL"))
+	bad := []Profile{
+		{Fallback: []FallbackTarget{{Provider: "nope"}}},
+		{FallbackOn: []string{"4xx"}},
+		{FallbackTimeout: "soon"},
 	}
-	if os.Getenv("NURO_PROVIDER") != "openai" {
-		t.Errorf("NURO_PROVIDER not set correctly, got %q", os.Getenv("NURO_PROVIDER"))
-	}
-	if os.Getenv("NURO_MODEL") != "gpt-4o-mini" {
-		t.Errorf("NURO_MODEL not set correctly, got %q", os.Getenv("NURO_MODEL"))
-	}
-	if os.Getenv("NURO_MAX_TOKENS") != "1500" {
-		t.Errorf("NURO_MAX_TOKENS not set correctly, got %q", os.Getenv("NURO_MAX_TOKENS"))
-	}
-	if os.Getenv("NURO_TEMPERATURE") != "1.00" {
-		t.Errorf("NURO_TEMPERATURE not set correctly, got %q", os.Getenv("NURO_TEMPERATURE"))
-	}
-	if os.Getenv("NURO_TOP_P") != "0.80" {
-		t.Errorf("NURO_TOP_P not set correctly, got %q", os.Getenv("NURO_TOP_P"))
+	for _, p := range bad {
+		c := &Config{Profiles: map[string]Profile{"p": p}}
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", p)
+		}
 	}
 }
 
-func TestResolveEnvVarsStandalone(t *testing.T) {
-	// defensive unit test for the internal resolver behavior your config.go uses
-	t.Setenv("FOO", "bar")
-	t.Setenv("BAZ", "qux")
-	in := "x $FOO y ${BAZ} z $MISSING"
-	out := resolveEnvVars(in)
-	if !strings.Contains(out, "x bar y qux z") {
-		t.Fatalf("resolveEnvVars failed, got: %q", out)
+// ref returns a pointer to v, for the optional profile settings.
+func ref[T any](v T) *T { return &v }
+
+// envMap returns the process environment as GetProfile takes it.
+func envMap() map[string]string {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
 	}
+	return env
 }
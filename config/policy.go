@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Policy restricts where nuro may send prompts and data, and what it may send. Unlike
+// profiles, a policy is not chosen: every policy in .nuro files of the current
+// directory, its parents and the home directory applies.
+type Policy struct {
+	// Providers lists the allowed providers; empty allows any.
+	Providers []string `json:"providers,omitempty"`
+	// Models lists the allowed models as globs, matched against "model" and
+	// "provider:model" (e.g. "llama3.*", "ollama:*"); empty allows any.
+	Models []string `json:"models,omitempty"`
+	// ForbiddenPatterns are regular expressions that the prompt, data and system prompt
+	// must not match, after redaction.
+	ForbiddenPatterns []string `json:"forbidden_patterns,omitempty"`
+	// MaxDataSize limits the data and included files, e.g. "200KB"; 0 is no limit.
+	MaxDataSize ByteSize `json:"max_data_size,omitempty"`
+	// RequireRedaction turns on redaction, which then cannot be turned off.
+	RequireRedaction bool `json:"require_redaction,omitempty"`
+
+	Source string `json:"-"` // .nuro file the policy was loaded from
+}
+
+// ByteSize is a size in bytes, written in .nuro as a number or a string with a unit
+// ("500KB", "2MB").
+type ByteSize int64
+
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("size must be a number of bytes or a string like \"500KB\"")
+	}
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// ParseByteSize parses sizes like "512", "500KB", "1.5MB" or "1GB" (units of 1024).
+func ParseByteSize(s string) (ByteSize, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range []struct {
+		suffix string
+		mult   float64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if rest, ok := strings.CutSuffix(t, u.suffix); ok {
+			t, mult = strings.TrimSpace(rest), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(t, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: want e.g. 500KB or 2MB", s)
+	}
+	return ByteSize(n * mult), nil
+}
+
+// LoadPolicies returns the policies of the .nuro files in the current directory, each
+// of its parents and the home directory. A .nuro that cannot be read or parsed is an
+// error, so that a broken file does not silently lift its policy.
+func LoadPolicies() ([]Policy, error) {
+	var dirs []string
+	if cwd, err := os.Getwd(); err == nil {
+		for dir := cwd; ; dir = filepath.Dir(dir) {
+			dirs = append(dirs, dir)
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil && !slices.Contains(dirs, home) {
+		dirs = append(dirs, home)
+	}
+
+	var policies []Policy
+	for _, dir := range dirs {
+		path := filepath.Join(dir, ".nuro")
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var cfg struct {
+			Policy *Policy `json:"policy"`
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if cfg.Policy == nil {
+			continue
+		}
+		cfg.Policy.Source = path
+		if err := cfg.Policy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid policy in %s: %w", path, err)
+		}
+		policies = append(policies, *cfg.Policy)
+	}
+	return policies, nil
+}
+
+// Validate checks the providers, model globs and patterns of the policy.
+func (p *Policy) Validate() error {
+	for _, prov := range p.Providers {
+		if !validProvider(prov) {
+			return fmt.Errorf(
+				"invalid provider '%s': must be one of %s", prov, strings.Join(validProviders, ", "),
+			)
+		}
+	}
+	for _, glob := range p.Models {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid model pattern %q: %w", glob, err)
+		}
+	}
+	for _, pattern := range p.ForbiddenPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid forbidden pattern %q: %w", pattern, err)
+		}
+	}
+	if p.MaxDataSize < 0 {
+		return fmt.Errorf("max_data_size must not be negative")
+	}
+	return nil
+}
+
+// CheckTarget reports whether the policy allows sending to model at provider.
+func (p *Policy) CheckTarget(provider, model string) error {
+	if len(p.Providers) > 0 && !slices.Contains(p.Providers, strings.ToLower(provider)) {
+		return fmt.Errorf(
+			"provider '%s' is not allowed by the policy in %s (allowed: %s)", provider, p.Source,
+			strings.Join(p.Providers, ", "),
+		)
+	}
+	if len(p.Models) == 0 {
+		return nil
+	}
+	for _, glob := range p.Models {
+		for _, name := range []string{model, provider + ":" + model} {
+			if ok, _ := path.Match(glob, name); ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf(
+		"model '%s' is not allowed by the policy in %s (allowed: %s)", model, p.Source,
+		strings.Join(p.Models, ", "),
+	)
+}
+
+// CheckContent reports whether text, about to be sent, matches a forbidden pattern. The
+// matching text is not repeated in the error.
+func (p *Policy) CheckContent(text string) error {
+	for _, pattern := range p.ForbiddenPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err // validated when loaded
+		}
+		if loc := re.FindStringIndex(text); loc != nil {
+			line := strings.Count(text[:loc[0]], "\n") + 1
+			return fmt.Errorf(
+				"content matches forbidden pattern %q of the policy in %s (line %d)", pattern, p.Source, line,
+			)
+		}
+	}
+	return nil
+}
+
+// CheckDataSize reports whether size bytes of data are within the policy's limit.
+func (p *Policy) CheckDataSize(size int) error {
+	if p.MaxDataSize > 0 && int64(size) > int64(p.MaxDataSize) {
+		return fmt.Errorf(
+			"data size %d bytes exceeds max_data_size %d bytes of the policy in %s", size, p.MaxDataSize,
+			p.Source,
+		)
+	}
+	return nil
+}
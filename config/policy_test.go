@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPolicies(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	writeTempConfig(
+		t, root,
+		`{"policy": {"providers": ["ollama", "mock"], "max_data_size": "1KB", "require_redaction": true}}`,
+	)
+	sub := filepath.Join(root, "service")
+	writeFile(
+		t, filepath.Join(sub, ".nuro"),
+		`{"profiles": {"p": {"provider": "mock"}}, "policy": {"models": ["llama3*", "mock:*"],
+		  "forbidden_patterns": ["(?i)internal only"]}}`,
+	)
+	chdir(t, sub)
+
+	policies, err := LoadPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 2 {
+		t.Fatalf("expected the policies of the directory and its parent, got %+v", policies)
+	}
+	if policies[0].Source != filepath.Join(sub, ".nuro") || policies[1].MaxDataSize != 1024 ||
+		!policies[1].RequireRedaction {
+		t.Errorf("unexpected policies %+v", policies)
+	}
+
+	tests := []struct {
+		name  string
+		check func() error
+		want  string // part of the error, "" when allowed
+	}{
+		{"allowed model", func() error { return checkAll(policies, "ollama", "llama3.2") }, ""},
+		{"provider glob", func() error { return checkAll(policies, "mock", "anything") }, ""},
+		{"provider", func() error { return checkAll(policies, "openai", "llama3") }, "provider 'openai'"},
+		{"model", func() error { return checkAll(policies, "ollama", "qwen2") }, "model 'qwen2'"},
+		{"size", func() error { return policies[1].CheckDataSize(1025) }, "exceeds max_data_size 1024"},
+		{"size ok", func() error { return policies[1].CheckDataSize(1024) }, ""},
+		{"content", func() error { return policies[0].CheckContent("a\nInternal Only: x") }, "(line 2)"},
+		{"content ok", func() error { return policies[0].CheckContent("public") }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := tt.check()
+				if tt.want == "" && err != nil {
+					t.Errorf("expected no violation, got %v", err)
+				}
+				if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+					t.Errorf("expected error containing %q, got %v", tt.want, err)
+				}
+			},
+		)
+	}
+}
+
+func checkAll(policies []Policy, provider, model string) error {
+	for _, p := range policies {
+		if err := p.CheckTarget(provider, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	for _, tt := range []struct{ json, want string }{
+		{`{"policy": {"providers": ["nope"]}}`, "invalid provider 'nope'"},
+		{`{"policy": {"models": ["["]}}`, "invalid model pattern"},
+		{`{"policy": {"forbidden_patterns": ["("]}}`, "invalid forbidden pattern"},
+		{`{"policy": {"max_data_size": "lots"}}`, "invalid size"},
+	} {
+		dir := t.TempDir()
+		t.Setenv("HOME", dir)
+		writeTempConfig(t, dir, tt.json)
+		chdir(t, dir)
+		if _, err := LoadPolicies(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error %q, got %v", tt.json, tt.want, err)
+		}
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	oldwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(oldwd) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// PromptDir is the directory of prompt files looked up next to a .nuro file, in the
+// current directory and in the home directory.
+const PromptDir = "prompts"
+
+// promptExts are the extensions of prompt files; the file name without it is the
+// prompt's name.
+var promptExts = []string{".md", ".prompt", ".txt"}
+
+// Prompt is a named, reusable prompt from the "prompts" section of .nuro or from a file
+// in a prompts/ directory. {{name}} placeholders in the prompt and system prompt are
+// filled from vars.
+type Prompt struct {
+	Name        string `json:"-"`
+	Description string `json:"description,omitempty"`
+	Profile     string `json:"profile,omitempty"` // .nuro profile used unless --cfg is given
+	Model       string `json:"model,omitempty"`   // model used unless -m is given
+	System      string `json:"system,omitempty"`
+	// Vars maps variable names to default values; a null default makes the variable
+	// required.
+	Vars map[string]any `json:"vars,omitempty"`
+	// Schema is a JSON Schema the answer must match; the model is asked for JSON.
+	Schema map[string]any `json:"schema,omitempty"`
+	Text   string         `json:"prompt"`
+
+	Source string `json:"-"` // file the prompt was loaded from
+}
+
+// LoadPrompts returns the prompt library: prompts from .nuro and from prompts/ files, in
+// the home directory and then the current directory, which takes precedence for prompts
+// with the same name.
+func LoadPrompts() (map[string]*Prompt, error) {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	if cwd, err := os.Getwd(); err == nil && (len(dirs) == 0 || cwd != dirs[0]) {
+		dirs = append(dirs, cwd)
+	}
+	prompts := map[string]*Prompt{}
+	for _, dir := range dirs {
+		found, err := loadPromptsIn(dir)
+		if err != nil {
+			return nil, err
+		}
+		for name, p := range found {
+			prompts[name] = p
+		}
+	}
+	return prompts, nil
+}
+
+// loadPromptsIn reads the prompts defined in dir/.nuro and dir/prompts/.
+func loadPromptsIn(dir string) (map[string]*Prompt, error) {
+	prompts := map[string]*Prompt{}
+	path := filepath.Join(dir, ".nuro")
+	if data, err := os.ReadFile(path); err == nil {
+		var cfg Config
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse .nuro config file: %w", err)
+		}
+		for name, p := range cfg.Prompts {
+			p.Name, p.Source = name, path
+			prompts[name] = &p
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, PromptDir))
+	if err != nil {
+		return prompts, nil // no prompts/ directory
+	}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || !slices.Contains(promptExts, ext) {
+			continue
+		}
+		path := filepath.Join(dir, PromptDir, e.Name())
+		name := strings.TrimSuffix(e.Name(), ext)
+		if prev, dup := prompts[name]; dup {
+			return nil, fmt.Errorf("prompt '%s' is defined in both %s and %s", name, prev.Source, path)
+		}
+		p, err := ParsePromptFile(path)
+		if err != nil {
+			return nil, err
+		}
+		prompts[name] = p
+	}
+	return prompts, nil
+}
+
+// ParsePromptFile reads a prompt file: optional YAML front-matter between "---" lines
+// (description, profile, model, system, vars, schema), followed by the prompt text.
+func ParsePromptFile(path string) (*Prompt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	p := &Prompt{Name: name, Source: path}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		front, body, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			if front, found = strings.CutSuffix(rest, "\n---"); !found {
+				return nil, fmt.Errorf("%s: front-matter is not closed with ---", path)
+			}
+		}
+		v, err := DecodeYAML([]byte(front))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if v != nil {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(p); err != nil {
+				return nil, fmt.Errorf("%s: invalid front-matter: %w", path, err)
+			}
+		}
+		if p.Text != "" {
+			return nil, fmt.Errorf("%s: the prompt text follows the front-matter, not a 'prompt' key", path)
+		}
+		text = body
+	}
+	p.Text = strings.TrimSpace(text)
+	return p, nil
+}
+
+// Validate checks that the prompt has text and that its placeholders are declared.
+func (p *Prompt) Validate() error {
+	if strings.TrimSpace(p.Text) == "" {
+		return fmt.Errorf("prompt '%s' has no text", p.Name)
+	}
+	for _, s := range []string{p.Text, p.System} {
+		for _, m := range placeholderRe.FindAllStringSubmatch(s, -1) {
+			if _, ok := p.Vars[m[1]]; !ok {
+				return fmt.Errorf("prompt '%s' uses {{%s}} but does not declare it in vars", p.Name, m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// VarNames returns the prompt's variables in order, required ones marked with "*".
+func (p *Prompt) VarNames() []string {
+	names := make([]string, 0, len(p.Vars))
+	for name, def := range p.Vars {
+		if def == nil {
+			name += "*"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render returns the prompt and system prompt with vars (and the defaults of those not
+// given) filled in.
+func (p *Prompt) Render(vars map[string]string) (prompt, system string, err error) {
+	values := map[string]string{}
+	for name, def := range p.Vars {
+		if def != nil {
+			values[name] = fmt.Sprint(def)
+		}
+	}
+	for name, v := range vars {
+		if _, ok := p.Vars[name]; !ok {
+			return "", "", fmt.Errorf(
+				"prompt '%s' has no variable '%s' (variables: %s)", p.Name, name,
+				strings.Join(p.VarNames(), ", "),
+			)
+		}
+		values[name] = v
+	}
+	for _, name := range p.VarNames() {
+		if n, required := strings.CutSuffix(name, "*"); required {
+			if _, ok := values[n]; !ok {
+				return "", "", fmt.Errorf("prompt '%s' needs a value for variable '%s'", p.Name, n)
+			}
+		}
+	}
+	if prompt, err = ExpandVars(p.Text, values); err != nil {
+		return "", "", err
+	}
+	if system, err = ExpandVars(p.System, values); err != nil {
+		return "", "", err
+	}
+	return prompt, system, nil
+}
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// ExpandVars replaces {{name}} placeholders in s with vars; an undefined name is an
+// error.
+func ExpandVars(s string, vars map[string]string) (string, error) {
+	var missing string
+	out := placeholderRe.ReplaceAllStringFunc(
+		s, func(m string) string {
+			name := placeholderRe.FindStringSubmatch(m)[1]
+			v, ok := vars[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return v
+		},
+	)
+	if missing != "" {
+		return "", fmt.Errorf("undefined variable {{%s}}", missing)
+	}
+	return out, nil
+}
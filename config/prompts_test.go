@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParsePromptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "summarize-logs.md")
+	writeFile(
+		t, path, `---
+description: Summarize application logs
+model: gpt-4o-mini
+system: You are an SRE. Focus on {{focus}}.
+vars:
+  focus: errors
+  team:
+schema:
+  type: object
+  required: [summary]
+---
+Summarize these logs for the {{team}} team.
+`,
+	)
+	p, err := ParsePromptFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "summarize-logs" || p.Model != "gpt-4o-mini" || p.Schema["type"] != "object" {
+		t.Errorf("unexpected prompt %+v", p)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(p.VarNames(), ","); got != "focus,team*" {
+		t.Errorf("VarNames = %s", got)
+	}
+
+	tests := []struct {
+		name         string
+		vars         map[string]string
+		prompt, sys  string
+		wantErrorMsg string
+	}{
+		{
+			name:   "defaults",
+			vars:   map[string]string{"team": "infra"},
+			prompt: "Summarize these logs for the infra team.",
+			sys:    "You are an SRE. Focus on errors.",
+		},
+		{
+			name:   "override",
+			vars:   map[string]string{"team": "infra", "focus": "latency"},
+			prompt: "Summarize these logs for the infra team.",
+			sys:    "You are an SRE. Focus on latency.",
+		},
+		{name: "missing required", vars: nil, wantErrorMsg: "needs a value for variable 'team'"},
+		{name: "unknown", vars: map[string]string{"team": "x", "tem": "y"}, wantErrorMsg: "has no variable 'tem'"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				prompt, sys, err := p.Render(tt.vars)
+				if tt.wantErrorMsg != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErrorMsg) {
+						t.Fatalf("expected error %q, got %v", tt.wantErrorMsg, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if prompt != tt.prompt || sys != tt.sys {
+					t.Errorf("Render = %q, %q", prompt, sys)
+				}
+			},
+		)
+	}
+}
+
+func TestPromptFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content, want string
+	}{
+		{"unclosed", "---\nmodel: x\nhello\n", "not closed"},
+		{"unknown key", "---\nmodle: x\n---\nhi\n", "unknown field"},
+		{"prompt key", "---\nprompt: x\n---\nhi\n", "follows the front-matter"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				path := filepath.Join(dir, tt.name+".md")
+				writeFile(t, path, tt.content)
+				if _, err := ParsePromptFile(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("expected error containing %q, got %v", tt.want, err)
+				}
+			},
+		)
+	}
+
+	p := &Prompt{Name: "x", Text: "Hello {{who}}"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "does not declare it") {
+		t.Errorf("expected an undeclared variable error, got %v", err)
+	}
+
+	cfg := &Config{Prompts: map[string]Prompt{"x": {Text: "hi", Profile: "nope"}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "profile 'nope' of prompt 'x'") {
+		t.Errorf("expected a missing profile error, got %v", err)
+	}
+}
+
+func TestLoadPrompts(t *testing.T) {
+	home, cwd := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	wd, _ := os.Getwd()
+	if err := os.Chdir(cwd); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	writeFile(t, filepath.Join(home, ".nuro"), `{"prompts": {"greet": {"prompt": "Hello from home"}}}`)
+	writeFile(t, filepath.Join(home, "prompts", "review.md"), "Review this code.")
+	writeFile(t, filepath.Join(home, "prompts", "notes.json"), "ignored")
+	writeFile(t, filepath.Join(cwd, "prompts", "greet.prompt"), "---\ndescription: project greeting\n---\nHello from the project")
+
+	prompts, err := LoadPrompts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 prompts, got %v", prompts)
+	}
+	if p := prompts["greet"]; p.Text != "Hello from the project" || p.Description != "project greeting" {
+		t.Errorf("expected the current directory to take precedence, got %+v", p)
+	}
+	if p := prompts["review"]; p.Text != "Review this code." || p.Source != filepath.Join(home, "prompts", "review.md") {
+		t.Errorf("unexpected home prompt %+v", p)
+	}
+
+	// The same name twice in one directory is ambiguous
+	writeFile(t, filepath.Join(cwd, ".nuro"), `{"prompts": {"greet": {"prompt": "Hello again"}}}`)
+	if _, err := LoadPrompts(); err == nil || !strings.Contains(err.Error(), "defined in both") {
+		t.Errorf("expected a duplicate prompt error, got %v", err)
+	}
+}
@@ -0,0 +1,510 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DecodeYAML parses the subset of YAML used by eval suites and prompt front-matter into
+// maps, slices and scalars: block mappings and sequences, flow collections ([a, b],
+// {k: v}), plain and quoted scalars, literal (|) and folded (>) block scalars, and
+// comments. Anchors, tags and multiple documents are not supported. JSON documents are
+// valid input.
+func DecodeYAML(src []byte) (any, error) {
+	text := strings.TrimPrefix(string(src), "\ufeff")
+	if t := strings.TrimSpace(text); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		var v any
+		if err := json.Unmarshal([]byte(t), &v); err == nil {
+			return v, nil
+		}
+	}
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")}
+	if !p.skipBlank() {
+		return nil, nil
+	}
+	if l := strings.TrimSpace(p.lines[p.i]); l == "---" {
+		p.i++
+		if !p.skipBlank() {
+			return nil, nil
+		}
+	}
+	v, err := p.node(indentOf(p.lines[p.i]))
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank() {
+		return nil, p.errorf("unexpected content")
+	}
+	return v, nil
+}
+
+type yamlParser struct {
+	lines []string
+	i     int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("yaml line %d: %s", p.i+1, fmt.Sprintf(format, args...))
+}
+
+// skipBlank advances past blank and comment-only lines and reports whether a content
+// line remains.
+func (p *yamlParser) skipBlank() bool {
+	for ; p.i < len(p.lines); p.i++ {
+		l := strings.TrimSpace(p.lines[p.i])
+		if l != "" && !strings.HasPrefix(l, "#") && l != "..." {
+			return true
+		}
+	}
+	return false
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isSeqItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// node parses the block collection starting at the current line, indented by indent.
+func (p *yamlParser) node(indent int) (any, error) {
+	content := strings.TrimSpace(stripComment(p.lines[p.i]))
+	if isSeqItem(content) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(content); ok {
+		return p.mapping(indent)
+	}
+	// A lone scalar (or flow collection) document or nested value
+	p.i++
+	return parseInline(content)
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	out := []any{}
+	for p.skipBlank() {
+		line := p.lines[p.i]
+		ind := indentOf(line)
+		content := strings.TrimSpace(stripComment(line))
+		if ind < indent || !isSeqItem(content) {
+			break
+		}
+		if ind > indent {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(content, "-"))
+		switch {
+		case item == "":
+			p.i++
+			v, err := p.nested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		case isSeqItem(item):
+			// "- - a": a sequence nested in the entry, continued two columns in
+			p.lines[p.i] = strings.Repeat(" ", ind+2) + strings.TrimLeft(line[ind+1:], " ")
+			v, err := p.sequence(indentOf(p.lines[p.i]))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		default:
+			if _, _, ok := splitKey(item); ok && !strings.HasPrefix(item, "{") {
+				// "- key: value" starts a mapping whose keys line up with "key"
+				col := ind + 1 + (len(line[ind+1:]) - len(strings.TrimLeft(line[ind+1:], " ")))
+				p.lines[p.i] = strings.Repeat(" ", col) + strings.TrimLeft(line[ind+1:], " ")
+				v, err := p.mapping(col)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+				continue
+			}
+			v, err := p.value(item, indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	out := map[string]any{}
+	for p.skipBlank() {
+		line := p.lines[p.i]
+		ind := indentOf(line)
+		content := strings.TrimSpace(stripComment(line))
+		if ind < indent || (ind == indent && isSeqItem(content)) {
+			break
+		}
+		if ind > indent {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+		key, rest, ok := splitKey(content)
+		if !ok {
+			return nil, p.errorf("expected \"key: value\", got %q", content)
+		}
+		if _, dup := out[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		if rest == "" {
+			p.i++
+			v, err := p.nested(indent, true)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+			continue
+		}
+		v, err := p.value(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// nested parses the value of a key or sequence entry given on the following lines: a
+// block more indented than parent, or (for mapping values) a sequence at the same
+// indentation. An absent value is null.
+func (p *yamlParser) nested(parent int, allowSameIndentSeq bool) (any, error) {
+	if !p.skipBlank() {
+		return nil, nil
+	}
+	line := p.lines[p.i]
+	ind := indentOf(line)
+	content := strings.TrimSpace(stripComment(line))
+	if ind > parent || (allowSameIndentSeq && ind == parent && isSeqItem(content)) {
+		return p.node(ind)
+	}
+	return nil, nil
+}
+
+// value parses an inline value on the current line, which may start a block scalar.
+func (p *yamlParser) value(text string, parent int) (any, error) {
+	raw := strings.TrimSpace(stripComment(text))
+	if strings.HasPrefix(raw, "|") || strings.HasPrefix(raw, ">") {
+		return p.blockScalar(raw, parent)
+	}
+	p.i++
+	v, err := parseInline(raw)
+	if err != nil {
+		return nil, fmt.Errorf("yaml line %d: %w", p.i, err)
+	}
+	return v, nil
+}
+
+// blockScalar reads a literal (|) or folded (>) scalar with an optional chomping
+// indicator (- strips the final newline, + keeps trailing blank lines).
+func (p *yamlParser) blockScalar(header string, parent int) (string, error) {
+	folded := header[0] == '>'
+	chomp := ""
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = string(c)
+		case c >= '1' && c <= '9':
+			// explicit indentation indicators are accepted and inferred instead
+		default:
+			return "", p.errorf("invalid block scalar header %q", header)
+		}
+	}
+	p.i++
+
+	var lines []string
+	blockIndent := -1
+	for ; p.i < len(p.lines); p.i++ {
+		line := p.lines[p.i]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		ind := indentOf(line)
+		if ind <= parent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = ind
+		}
+		if ind < blockIndent {
+			break
+		}
+		lines = append(lines, line[blockIndent:])
+	}
+
+	// Trailing blank lines belong to the scalar only with "+"
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var body string
+	if folded {
+		var sb strings.Builder
+		for i, l := range lines {
+			switch {
+			case i == 0, lines[i-1] == "" && l != "":
+			case l == "" || strings.HasPrefix(l, " ") || strings.HasPrefix(lines[i-1], " "):
+				sb.WriteString("\n")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(l)
+		}
+		body = sb.String()
+	} else {
+		body = strings.Join(lines, "\n")
+	}
+	switch {
+	case len(lines) == 0:
+		return "", nil
+	case chomp == "-":
+		return body, nil
+	case chomp == "+":
+		return body + "\n" + strings.Repeat("\n", trailing), nil
+	default:
+		return body + "\n", nil
+	}
+}
+
+// stripComment removes a "#" comment that starts a line or follows whitespace, outside
+// quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" \t[{,:", rune(s[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimRight(s[:i], " \t")
+		}
+	}
+	return s
+}
+
+// splitKey splits "key: value" (or "key:") at the first ": " outside quotes and flow
+// collections.
+func splitKey(s string) (key, rest string, ok bool) {
+	if s == "" || s[0] == '[' || s[0] == '{' {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i == len(s)-1 || s[i+1] == ' ' || s[i+1] == '\t'):
+			k := strings.TrimSpace(s[:i])
+			if uq, err := unquote(k); err == nil {
+				k = uq
+			}
+			return k, strings.TrimSpace(s[i+1:]), k != ""
+		}
+	}
+	return "", "", false
+}
+
+func unquote(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		var out string
+		if err := json.Unmarshal([]byte(s), &out); err == nil {
+			return out, nil
+		}
+		return strconv.Unquote(s)
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return "", fmt.Errorf("not quoted: %s", s)
+}
+
+// parseInline parses a scalar or flow collection written on one line.
+func parseInline(s string) (any, error) {
+	f := &flowParser{s: s}
+	v, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	f.space()
+	if f.i != len(f.s) {
+		if s[0] == '"' || s[0] == '\'' || s[0] == '[' || s[0] == '{' {
+			return nil, fmt.Errorf("unexpected %q after value", f.s[f.i:])
+		}
+		// A plain scalar may contain any of the flow indicators outside a collection
+		return plainScalar(s), nil
+	}
+	return v, nil
+}
+
+type flowParser struct {
+	s     string
+	i     int
+	depth int
+}
+
+func (f *flowParser) space() {
+	for f.i < len(f.s) && (f.s[f.i] == ' ' || f.s[f.i] == '\t') {
+		f.i++
+	}
+}
+
+func (f *flowParser) value() (any, error) {
+	f.space()
+	if f.i >= len(f.s) {
+		return nil, nil
+	}
+	switch f.s[f.i] {
+	case '[':
+		return f.seq()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		return f.quoted()
+	}
+	start := f.i
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		if f.depth > 0 && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+		if f.depth > 0 && c == ':' && (f.i+1 == len(f.s) || f.s[f.i+1] == ' ') {
+			break
+		}
+		f.i++
+	}
+	return plainScalar(strings.TrimSpace(f.s[start:f.i])), nil
+}
+
+func (f *flowParser) quoted() (string, error) {
+	q := f.s[f.i]
+	start := f.i
+	f.i++
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		if q == '"' && c == '\\' {
+			f.i += 2
+			continue
+		}
+		if c == q {
+			if q == '\'' && f.i+1 < len(f.s) && f.s[f.i+1] == '\'' {
+				f.i += 2
+				continue
+			}
+			f.i++
+			return unquote(f.s[start:f.i])
+		}
+		f.i++
+	}
+	return "", fmt.Errorf("unterminated quoted string %s", f.s[start:])
+}
+
+func (f *flowParser) seq() ([]any, error) {
+	f.i++ // [
+	f.depth++
+	defer func() { f.depth-- }()
+	out := []any{}
+	for {
+		f.space()
+		if f.i >= len(f.s) {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		if f.s[f.i] == ']' {
+			f.i++
+			return out, nil
+		}
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		// Each entry ends at a comma or the closing bracket; anything else (like "a}" or
+		// "a: b") would stop the next value where it started
+		f.space()
+		switch {
+		case f.i < len(f.s) && f.s[f.i] == ',':
+			f.i++
+		case f.i < len(f.s) && f.s[f.i] != ']':
+			return nil, fmt.Errorf("unexpected %q in flow sequence", f.s[f.i])
+		}
+	}
+}
+
+func (f *flowParser) mapping() (map[string]any, error) {
+	f.i++ // {
+	f.depth++
+	defer func() { f.depth-- }()
+	out := map[string]any{}
+	for {
+		f.space()
+		if f.i >= len(f.s) {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		if f.s[f.i] == '}' {
+			f.i++
+			return out, nil
+		}
+		k, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		f.space()
+		if f.i >= len(f.s) || f.s[f.i] != ':' {
+			return nil, fmt.Errorf("expected ':' in flow mapping")
+		}
+		f.i++
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		out[fmt.Sprint(k)] = v
+		f.space()
+		switch {
+		case f.i < len(f.s) && f.s[f.i] == ',':
+			f.i++
+		case f.i < len(f.s) && f.s[f.i] != '}':
+			return nil, fmt.Errorf("unexpected %q in flow mapping", f.s[f.i])
+		}
+	}
+}
+
+// plainScalar resolves an unquoted scalar to null, a boolean, a number or a string.
+func plainScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXnN") {
+		return f
+	}
+	return s
+}
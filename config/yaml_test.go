@@ -0,0 +1,171 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want any
+	}{
+		{
+			name: "mapping with scalars",
+			src: `name: smoke   # comment
+count: 3
+ratio: 0.5
+on: true
+nothing: ~
+quoted: "a: b # not a comment"
+single: 'it''s'
+url: http://localhost:11434
+`,
+			want: map[string]any{
+				"name": "smoke", "count": int64(3), "ratio": 0.5, "on": true, "nothing": nil,
+				"quoted": "a: b # not a comment", "single": "it's", "url": "http://localhost:11434",
+			},
+		},
+		{
+			name: "sequences at the key's indentation and nested",
+			src: `tests:
+- name: one
+  assert:
+    - contains: x
+    - regex: "^y$"
+      ignore_case: true
+- name: two
+  vars: {city: Paris, n: 2}
+  tags: [a, "b, c"]
+`,
+			want: map[string]any{
+				"tests": []any{
+					map[string]any{
+						"name": "one",
+						"assert": []any{
+							map[string]any{"contains": "x"},
+							map[string]any{"regex": "^y$", "ignore_case": true},
+						},
+					},
+					map[string]any{
+						"name": "two",
+						"vars": map[string]any{"city": "Paris", "n": int64(2)},
+						"tags": []any{"a", "b, c"},
+					},
+				},
+			},
+		},
+		{
+			name: "block scalars",
+			src: `literal: |
+  line one
+    indented
+
+  line three
+stripped: |-
+  no newline
+folded: >
+  joined
+  words
+
+  new paragraph
+after: x
+`,
+			want: map[string]any{
+				"literal":  "line one\n  indented\n\nline three\n",
+				"stripped": "no newline",
+				"folded":   "joined words\nnew paragraph\n",
+				"after":    "x",
+			},
+		},
+		{
+			name: "json document",
+			src:  `{"tests": [{"prompt": "hi", "assert": [{"equals": "hi"}]}]}`,
+			want: map[string]any{
+				"tests": []any{map[string]any{"prompt": "hi", "assert": []any{map[string]any{"equals": "hi"}}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := DecodeYAML([]byte(tt.src))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("DecodeYAML =\n%#v\nwant\n%#v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestDecodeYAMLErrors(t *testing.T) {
+	for _, src := range []string{
+		"a: 1\na: 2\n",
+		"a: 1\n   b: 2\n",
+		"a: [1, 2\n",
+		"a: \"open\n",
+		"a: [b}\n",
+		"a: [b: c]\n",
+		"a: {b: c]\n",
+	} {
+		if _, err := DecodeYAML([]byte(src)); err == nil {
+			t.Errorf("expected an error for %q", src)
+		}
+	}
+}
+
+// FuzzDecodeYAML checks that any input decodes to plain values or an error, without a
+// panic or an endless loop. Seeds are in testdata/fuzz/FuzzDecodeYAML.
+func FuzzDecodeYAML(f *testing.F) {
+	f.Add([]byte("name: smoke\ntests:\n- prompt: |\n    hi\n  assert: [{contains: hi}]\n"))
+	f.Add([]byte("a: >-\n  folded\n  text\nb: {x: [1, 'two'], y: \"3\"}\n"))
+	f.Fuzz(
+		func(t *testing.T, src []byte) {
+			type result struct {
+				v     any
+				panic any
+			}
+			done := make(chan result, 1)
+			go func() {
+				var r result
+				defer func() {
+					r.panic = recover()
+					done <- r
+				}()
+				r.v, _ = DecodeYAML(src)
+			}()
+			select {
+			case r := <-done:
+				if r.panic != nil {
+					t.Fatalf("DecodeYAML panicked: %v", r.panic)
+				}
+				checkPlain(t, r.v)
+			case <-time.After(10 * time.Second):
+				t.Fatal("DecodeYAML did not return within 10s")
+			}
+		},
+	)
+}
+
+// checkPlain fails t unless v is built only from the values DecodeYAML documents.
+func checkPlain(t *testing.T, v any) {
+	t.Helper()
+	switch v := v.(type) {
+	case nil, string, bool, int64, float64:
+	case map[string]any:
+		for _, e := range v {
+			checkPlain(t, e)
+		}
+	case []any:
+		for _, e := range v {
+			checkPlain(t, e)
+		}
+	default:
+		t.Fatalf("unexpected value %#v of type %T", v, v)
+	}
+}
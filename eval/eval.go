@@ -0,0 +1,247 @@
+// Package eval runs prompt regression suites: test cases with assertions on the answer,
+// run against one or more models.
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/heather7532/nuro/config"
+)
+
+// Suite is a set of test cases loaded from a YAML (or JSON) suite file.
+type Suite struct {
+	Name string `json:"name,omitempty"`
+	// Profiles and Models are the default targets when none are given on the command
+	// line: .nuro profile names and model references ("provider:model").
+	Profiles []string `json:"profiles,omitempty"`
+	Models   []string `json:"models,omitempty"`
+	// Grader is the model reference that grades rubric assertions; empty uses the model
+	// under test.
+	Grader string `json:"grader,omitempty"`
+
+	System      Text     `json:"system,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	Vars  map[string]Text `json:"vars,omitempty"` // defaults for every case
+	Tests []Case          `json:"tests"`
+}
+
+// Case is one prompt (with optional data) and the assertions its answer must pass.
+// {{name}} placeholders in the prompt, data and assertion values are replaced by vars.
+type Case struct {
+	Name     string          `json:"name,omitempty"`
+	Prompt   Text            `json:"prompt,omitempty"`
+	Data     Text            `json:"data,omitempty"`
+	DataFile string          `json:"data_file,omitempty"` // relative to the suite file
+	Vars     map[string]Text `json:"vars,omitempty"`
+	Assert   []Assertion     `json:"assert"`
+}
+
+// Assertion checks an answer. Exactly one of the check fields is set.
+type Assertion struct {
+	Contains    *Text          `json:"contains,omitempty"`
+	NotContains *Text          `json:"not_contains,omitempty"`
+	Equals      *Text          `json:"equals,omitempty"` // ignores surrounding whitespace
+	Regex       *Text          `json:"regex,omitempty"`
+	JSONSchema  map[string]any `json:"json_schema,omitempty"`
+	Rubric      *Text          `json:"rubric,omitempty"` // graded by a model
+	IgnoreCase  bool           `json:"ignore_case,omitempty"`
+}
+
+// Text is a string that may be written as any YAML scalar, so "equals: 42" and
+// "vars: {n: 3}" need no quotes.
+type Text string
+
+// UnmarshalJSON accepts strings, numbers and booleans.
+func (t *Text) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = Text(s)
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*t = Text(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		*t = Text(strconv.FormatBool(v))
+	case nil:
+		*t = ""
+	default:
+		return fmt.Errorf("expected a scalar, got %s", b)
+	}
+	return nil
+}
+
+// Kind names the check an assertion performs.
+func (a Assertion) Kind() string {
+	var kinds []string
+	for _, k := range []struct {
+		name string
+		set  bool
+	}{
+		{"contains", a.Contains != nil},
+		{"not_contains", a.NotContains != nil},
+		{"equals", a.Equals != nil},
+		{"regex", a.Regex != nil},
+		{"json_schema", a.JSONSchema != nil},
+		{"rubric", a.Rubric != nil},
+	} {
+		if k.set {
+			kinds = append(kinds, k.name)
+		}
+	}
+	return strings.Join(kinds, "+")
+}
+
+// Load reads and validates a suite file. Data files are read relative to the suite.
+func Load(path string) (*Suite, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i := range s.Tests {
+		c := &s.Tests[i]
+		if c.DataFile == "" {
+			continue
+		}
+		p := c.DataFile
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: test %q: %w", path, c.Name, err)
+		}
+		c.Data = Text(data)
+	}
+	return s, nil
+}
+
+// Parse decodes and validates a suite.
+func Parse(src []byte) (*Suite, error) {
+	v, err := config.DecodeYAML(src)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var s Suite
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid suite: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Suite) validate() error {
+	if len(s.Tests) == 0 {
+		return fmt.Errorf("suite has no tests")
+	}
+	seen := map[string]bool{}
+	for i := range s.Tests {
+		c := &s.Tests[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("test %d", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate test name %q", c.Name)
+		}
+		seen[c.Name] = true
+		if strings.TrimSpace(string(c.Prompt)) == "" && c.Data == "" && c.DataFile == "" {
+			return fmt.Errorf("test %q: needs a prompt or data", c.Name)
+		}
+		if c.Data != "" && c.DataFile != "" {
+			return fmt.Errorf("test %q: data and data_file are mutually exclusive", c.Name)
+		}
+		if len(c.Assert) == 0 {
+			return fmt.Errorf("test %q: has no assertions", c.Name)
+		}
+		vars := s.vars(c)
+		for _, t := range []Text{c.Prompt, c.Data} {
+			if _, err := config.ExpandVars(string(t), vars); err != nil {
+				return fmt.Errorf("test %q: %w", c.Name, err)
+			}
+		}
+		for j, a := range c.Assert {
+			switch k := a.Kind(); {
+			case k == "":
+				return fmt.Errorf("test %q: assertion %d has no check", c.Name, j+1)
+			case strings.Contains(k, "+"):
+				return fmt.Errorf("test %q: assertion %d has several checks (%s); use one per entry", c.Name, j+1, k)
+			}
+			if _, err := a.expand(vars); err != nil {
+				return fmt.Errorf("test %q: assertion %d: %w", c.Name, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// vars merges the suite defaults with the case's own vars.
+func (s *Suite) vars(c *Case) map[string]string {
+	vars := make(map[string]string, len(s.Vars)+len(c.Vars))
+	for k, v := range s.Vars {
+		vars[k] = string(v)
+	}
+	for k, v := range c.Vars {
+		vars[k] = string(v)
+	}
+	return vars
+}
+
+// expand returns a copy of the assertion with placeholders in its values replaced, and
+// its regular expression compiled to check it.
+func (a Assertion) expand(vars map[string]string) (Assertion, error) {
+	for _, p := range []**Text{&a.Contains, &a.NotContains, &a.Equals, &a.Regex, &a.Rubric} {
+		if *p == nil {
+			continue
+		}
+		s, err := config.ExpandVars(string(**p), vars)
+		if err != nil {
+			return a, err
+		}
+		t := Text(s)
+		*p = &t
+	}
+	if a.Regex != nil {
+		if _, err := a.regexp(); err != nil {
+			return a, err
+		}
+	}
+	return a, nil
+}
+
+func (a Assertion) regexp() (*regexp.Regexp, error) {
+	expr := string(*a.Regex)
+	if a.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return re, nil
+}
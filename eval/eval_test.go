@@ -0,0 +1,217 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+const testSuite = `name: capitals
+vars:
+  country: France
+tests:
+  - name: capital
+    prompt: What is the capital of {{country}}?
+    assert:
+      - contains: Paris
+      - equals: paris
+        ignore_case: true
+  - name: other country
+    prompt: What is the capital of {{country}}?
+    vars: {country: Italy}
+    assert:
+      - regex: ^Rome$
+      - rubric: names the capital of {{country}}
+  - name: profile
+    prompt: Describe {{who}} as JSON.
+    vars: {who: Ada}
+    assert:
+      - json_schema:
+          type: object
+          required: [name, born]
+          properties:
+            name: {type: string}
+            born: {type: integer, minimum: 1800}
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(testSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "capitals" || len(s.Tests) != 3 || len(s.Tests[0].Assert) != 2 {
+		t.Fatalf("unexpected suite %+v", s)
+	}
+	if a := s.Tests[0].Assert[1]; a.Kind() != "equals" || !a.IgnoreCase {
+		t.Errorf("unexpected assertion %+v", a)
+	}
+
+	tests := []struct {
+		name, src, want string
+	}{
+		{"no tests", "name: x\n", "no tests"},
+		{"unknown field", "tests:\n  - prompt: hi\n    asserts: []\n", "unknown field"},
+		{"no assertions", "tests:\n  - prompt: hi\n", "no assertions"},
+		{"undefined var", "tests:\n  - prompt: hi {{who}}\n    assert: [{contains: x}]\n", "undefined variable {{who}}"},
+		{"two checks", "tests:\n  - prompt: hi\n    assert: [{contains: x, regex: y}]\n", "several checks"},
+		{"bad regex", "tests:\n  - prompt: hi\n    assert: [{regex: '('}]\n", "invalid regex"},
+		{"duplicate", "tests:\n  - {name: a, prompt: x, assert: [{contains: x}]}\n  - {name: a, prompt: y, assert: [{contains: y}]}\n", "duplicate test name"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := Parse([]byte(tt.src))
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("expected error containing %q, got %v", tt.want, err)
+				}
+			},
+		)
+	}
+}
+
+func TestLoadDataFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.txt"), []byte("sales up 5%"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	suite := "tests:\n  - prompt: summarize\n    data_file: report.txt\n    assert: [{contains: '5%'}]\n"
+	path := filepath.Join(dir, "suite.yaml")
+	if err := os.WriteFile(path, []byte(suite), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Tests[0].Data != "sales up 5%" {
+		t.Errorf("expected the data file relative to the suite, got %q", s.Tests[0].Data)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	text := func(s string) *Text { v := Text(s); return &v }
+	schema := map[string]any{
+		"type":     "object",
+		"required": []any{"tags"},
+		"properties": map[string]any{
+			"tags": map[string]any{"type": "array", "items": map[string]any{"enum": []any{"a", "b"}}},
+		},
+		"additionalProperties": false,
+	}
+	grader := func(_ context.Context, rubric, output string) (bool, string, error) {
+		return strings.Contains(output, "polite"), "not polite enough", nil
+	}
+	tests := []struct {
+		name   string
+		a      Assertion
+		output string
+		want   string
+	}{
+		{"contains", Assertion{Contains: text("Paris")}, "It is Paris.", ""},
+		{"contains fails", Assertion{Contains: text("paris")}, "It is Paris.", `contains "paris": not found`},
+		{"contains ignoring case", Assertion{Contains: text("paris"), IgnoreCase: true}, "It is Paris.", ""},
+		{"not contains", Assertion{NotContains: text("sorry")}, "Sorry, I can't", ""},
+		{"equals trims", Assertion{Equals: text("42")}, " 42\n", ""},
+		{"equals fails", Assertion{Equals: text("42")}, "43", `equals "42": got "43"`},
+		{"regex", Assertion{Regex: text(`^\d+$`)}, "123", ""},
+		{"regex fails", Assertion{Regex: text(`^\d+$`)}, "12a", "no match"},
+		{"schema", Assertion{JSONSchema: schema}, "```json\n{\"tags\": [\"a\"]}\n```", ""},
+		{"schema not json", Assertion{JSONSchema: schema}, "tags: a", "not valid JSON"},
+		{"schema enum", Assertion{JSONSchema: schema}, `{"tags": ["c"]}`, "$.tags[0]: c is not one of [a b]"},
+		{"schema missing", Assertion{JSONSchema: schema}, `{}`, `$: missing required property "tags"`},
+		{"schema extra", Assertion{JSONSchema: schema}, `{"tags": [], "x": 1}`, `unexpected property "x"`},
+		{"rubric", Assertion{Rubric: text("is polite")}, "a polite answer", ""},
+		{"rubric fails", Assertion{Rubric: text("is polite")}, "no", `rubric "is polite": not polite enough`},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := tt.a.check(context.Background(), tt.output, grader)
+				if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+					t.Errorf("check = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		reply  string
+		pass   bool
+		reason string
+		err    bool
+	}{
+		{`{"pass": true, "reason": "ok"}`, true, "ok", false},
+		{"```json\n{\"pass\": false, \"reason\": \"wrong city\"}\n```", false, "wrong city", false},
+		{"PASS", true, "", false},
+		{"FAIL\nmissing the year", false, "missing the year", false},
+		{"maybe?", false, "", true},
+	}
+	for _, tt := range tests {
+		pass, reason, err := parseVerdict(tt.reply)
+		if pass != tt.pass || reason != tt.reason || (err != nil) != tt.err {
+			t.Errorf("parseVerdict(%q) = %t, %q, %v", tt.reply, pass, reason, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.json")
+	rules := `{"rules": [
+		{"match": "Grade|Rubric", "reply": "{\"pass\": true, \"reason\": \"fine\"}"},
+		{"match": "France", "reply": "Paris"},
+		{"match": "Italy", "reply": "Rome"},
+		{"match": "JSON", "reply": "{\"name\": \"Ada\", \"born\": 1815}"}
+	]}`
+	if err := os.WriteFile(script, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	good, err := provider.NewMockProvider(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo, _ := provider.NewMockProvider("")
+	s, err := Parse([]byte(testSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []Target{
+		{Name: "scripted", Provider: good, Model: "mock", Args: provider.CompletionArgs{Timeout: time.Second}},
+		{Name: "echo", Provider: echo, Model: "mock", Args: provider.CompletionArgs{Timeout: time.Second}},
+	}
+	grader := LLMGrader(good, "mock", time.Second)
+	rep := Run(context.Background(), s, targets, grader)
+
+	if p, f, e := rep.Counts(0); p != 3 || f != 0 || e != 0 {
+		t.Errorf("scripted: expected 3 passes, got %d passed %d failed %d errors: %+v", p, f, e, rep.Results[0])
+	}
+	if p, f, _ := rep.Counts(1); p != 0 || f != 3 {
+		t.Errorf("echo: expected 3 failures, got %d passed %d failed: %+v", p, f, rep.Results[1])
+	}
+	if got := rep.Results[1][1].Failures[0]; got != "regex `^Rome$`: no match" {
+		t.Errorf("unexpected failure message %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.WriteJUnit(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 6 || doc.Failures != 3 || len(doc.Suites) != 2 || doc.Suites[1].Name != "capitals [echo]" {
+		t.Errorf("unexpected JUnit summary %+v", doc)
+	}
+	if c := doc.Suites[1].Cases[0]; c.Failure == nil || !strings.Contains(c.Failure.Text, "Paris") {
+		t.Errorf("expected a failure for the echo target, got %+v", c)
+	}
+}
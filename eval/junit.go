@@ -0,0 +1,94 @@
+package eval
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr,omitempty"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML: one <testsuite> per target, one
+// <testcase> per case, with the answer as system-out.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	seconds := func(d time.Duration) string { return fmt.Sprintf("%.3f", d.Seconds()) }
+	out := junitSuites{Name: r.Suite}
+	var total time.Duration
+	for t, target := range r.Targets {
+		suite := junitSuite{Name: target}
+		if r.Suite != "" {
+			suite.Name = r.Suite + " [" + target + "]"
+		}
+		var elapsed time.Duration
+		for _, res := range r.Results[t] {
+			c := junitCase{
+				Name:      res.Case,
+				ClassName: suite.Name,
+				Time:      seconds(res.Duration),
+				SystemOut: res.Output,
+			}
+			switch {
+			case res.Error != "":
+				c.Error = &junitProblem{Message: res.Error, Text: res.Error}
+				suite.Errors++
+			case !res.Passed:
+				c.Failure = &junitProblem{Message: res.Failures[0], Text: strings.Join(res.Failures, "\n")}
+				suite.Failures++
+			}
+			suite.Tests++
+			elapsed += res.Duration
+			suite.Cases = append(suite.Cases, c)
+		}
+		suite.Time = seconds(elapsed)
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Errors += suite.Errors
+		total += elapsed
+		out.Suites = append(out.Suites, suite)
+	}
+	out.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,263 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/provider"
+)
+
+// Target is a model the suite runs against.
+type Target struct {
+	Name     string // profile name or model reference shown in reports
+	Provider provider.Provider
+	Model    string
+	// Args holds the request parameters (system prompt, max tokens, temperature,
+	// timeout); prompt, data and model are set per case.
+	Args provider.CompletionArgs
+}
+
+// Grader decides whether output satisfies a rubric.
+type Grader func(ctx context.Context, rubric, output string) (pass bool, reason string, err error)
+
+// Result is the outcome of one case against one target.
+type Result struct {
+	Case         string         `json:"case"`
+	Target       string         `json:"target"`
+	Provider     string         `json:"provider"`
+	Model        string         `json:"model"`
+	Passed       bool           `json:"passed"`
+	Failures     []string       `json:"failures,omitempty"`
+	Error        string         `json:"error,omitempty"` // the request failed; no assertions ran
+	Output       string         `json:"output"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Usage        provider.Usage `json:"usage"`
+	Duration     time.Duration  `json:"-"`
+	LatencyMS    int64          `json:"latency_ms"`
+}
+
+// Report holds the results of a suite run, indexed [target][case].
+type Report struct {
+	Suite   string     `json:"suite"`
+	Targets []string   `json:"targets"`
+	Cases   []string   `json:"cases"`
+	Results [][]Result `json:"results"`
+}
+
+// Counts returns the number of passed, failed and errored results of target t.
+func (r *Report) Counts(t int) (passed, failed, errored int) {
+	for _, res := range r.Results[t] {
+		switch {
+		case res.Error != "":
+			errored++
+		case res.Passed:
+			passed++
+		default:
+			failed++
+		}
+	}
+	return passed, failed, errored
+}
+
+// Run runs every case against every target; targets run concurrently, the cases of a
+// target one after another. Rubric assertions use grader, or the target's own model
+// when grader is nil.
+func Run(ctx context.Context, s *Suite, targets []Target, grader Grader) *Report {
+	rep := &Report{Suite: s.Name, Results: make([][]Result, len(targets))}
+	for _, c := range s.Tests {
+		rep.Cases = append(rep.Cases, c.Name)
+	}
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		rep.Targets = append(rep.Targets, t.Name)
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			g := grader
+			if g == nil {
+				g = LLMGrader(t.Provider, t.Model, t.Args.Timeout)
+			}
+			results := make([]Result, len(s.Tests))
+			for j := range s.Tests {
+				results[j] = runCase(ctx, s, &s.Tests[j], t, g)
+			}
+			rep.Results[i] = results
+		}(i, t)
+	}
+	wg.Wait()
+	return rep
+}
+
+func runCase(ctx context.Context, s *Suite, c *Case, t Target, grader Grader) Result {
+	res := Result{Case: c.Name, Target: t.Name, Provider: t.Provider.Name(), Model: t.Model}
+	vars := s.vars(c)
+	// Placeholders were checked when the suite was loaded
+	prompt, _ := config.ExpandVars(string(c.Prompt), vars)
+	data, _ := config.ExpandVars(string(c.Data), vars)
+
+	args := t.Args
+	args.Model, args.Prompt, args.Data = t.Model, prompt, data
+	if s.System != "" {
+		args.System = string(s.System)
+	}
+	if s.MaxTokens > 0 {
+		args.MaxTokens = s.MaxTokens
+	}
+	if s.Temperature != nil {
+		args.Temperature = *s.Temperature
+	}
+	cctx := ctx
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		cctx, cancel = context.WithTimeout(ctx, args.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	text, usage, finish, err := t.Provider.Complete(cctx, args)
+	res.Duration = time.Since(start)
+	res.LatencyMS = res.Duration.Milliseconds()
+	res.Output, res.Usage, res.FinishReason = text, usage, finish
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	for _, a := range c.Assert {
+		a, _ = a.expand(vars)
+		if msg := a.check(ctx, text, grader); msg != "" {
+			res.Failures = append(res.Failures, msg)
+		}
+	}
+	res.Passed = len(res.Failures) == 0
+	return res
+}
+
+// check returns why output fails the assertion, or "" when it passes.
+func (a Assertion) check(ctx context.Context, output string, grader Grader) string {
+	fold := func(s string) string {
+		if a.IgnoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch {
+	case a.Contains != nil:
+		if !strings.Contains(fold(output), fold(string(*a.Contains))) {
+			return fmt.Sprintf("contains %q: not found in output", *a.Contains)
+		}
+	case a.NotContains != nil:
+		if strings.Contains(fold(output), fold(string(*a.NotContains))) {
+			return fmt.Sprintf("not_contains %q: found in output", *a.NotContains)
+		}
+	case a.Equals != nil:
+		got, want := strings.TrimSpace(output), strings.TrimSpace(string(*a.Equals))
+		if fold(got) != fold(want) {
+			return fmt.Sprintf("equals %q: got %q", want, got)
+		}
+	case a.Regex != nil:
+		re, err := a.regexp()
+		if err != nil {
+			return fmt.Sprintf("regex `%s`: %v", *a.Regex, err)
+		}
+		if !re.MatchString(output) {
+			return fmt.Sprintf("regex `%s`: no match", *a.Regex)
+		}
+	case a.JSONSchema != nil:
+		if err := CheckSchema(a.JSONSchema, output); err != nil {
+			return fmt.Sprintf("json_schema: %v", err)
+		}
+	case a.Rubric != nil:
+		pass, reason, err := grader(ctx, string(*a.Rubric), output)
+		switch {
+		case err != nil:
+			return fmt.Sprintf("rubric %q: grader failed: %v", *a.Rubric, err)
+		case !pass:
+			return fmt.Sprintf("rubric %q: %s", *a.Rubric, reason)
+		}
+	}
+	return ""
+}
+
+// stripFence unwraps an answer that is a single fenced code block (```json ... ```).
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	body := strings.TrimSuffix(s[3:], "```")
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+		body = body[nl+1:] // drop the info string
+	}
+	return strings.TrimSpace(body)
+}
+
+const graderSystem = `You grade answers against a rubric. Judge only whether the answer given as data ` +
+	`satisfies every point of the rubric. Reply with a single JSON object and nothing else: ` +
+	`{"pass": true or false, "reason": "one short sentence"}`
+
+// LLMGrader grades rubrics with a model: the answer is sent as data and the model replies
+// with {"pass": ..., "reason": ...}.
+func LLMGrader(prov provider.Provider, model string, timeout time.Duration) Grader {
+	return func(ctx context.Context, rubric, output string) (bool, string, error) {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		reply, _, _, err := prov.Complete(
+			ctx, provider.CompletionArgs{
+				Model:     model,
+				System:    graderSystem,
+				Prompt:    "Rubric:\n" + rubric,
+				Data:      output,
+				MaxTokens: 256,
+				TopP:      1.0,
+				Timeout:   timeout,
+			},
+		)
+		if err != nil {
+			return false, "", err
+		}
+		return parseVerdict(reply)
+	}
+}
+
+// parseVerdict reads the grader's JSON verdict, accepting a bare PASS or FAIL as well.
+func parseVerdict(reply string) (bool, string, error) {
+	var v struct {
+		Pass   *bool  `json:"pass"`
+		Reason string `json:"reason"`
+	}
+	if i, j := strings.IndexByte(reply, '{'), strings.LastIndexByte(reply, '}'); i >= 0 && j > i {
+		if err := json.Unmarshal([]byte(reply[i:j+1]), &v); err == nil && v.Pass != nil {
+			if v.Reason == "" && !*v.Pass {
+				v.Reason = "graded as failing"
+			}
+			return *v.Pass, v.Reason, nil
+		}
+	}
+	word, rest, _ := strings.Cut(strings.TrimSpace(reply), "\n")
+	switch strings.ToUpper(strings.Trim(word, " .:*")) {
+	case "PASS":
+		return true, strings.TrimSpace(rest), nil
+	case "FAIL":
+		reason := strings.TrimSpace(rest)
+		if reason == "" {
+			reason = "graded as failing"
+		}
+		return false, reason, nil
+	}
+	return false, "", fmt.Errorf("unrecognized grader reply %q", truncate(reply, 80))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
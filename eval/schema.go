@@ -0,0 +1,199 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// CheckSchema parses output as JSON, unwrapping a fenced code block, and validates it
+// against schema.
+func CheckSchema(schema map[string]any, output string) error {
+	var v any
+	if err := json.Unmarshal([]byte(stripFence(output)), &v); err != nil {
+		return fmt.Errorf("output is not valid JSON: %w", err)
+	}
+	return validateSchema(schema, v, "$")
+}
+
+// validateSchema checks v against the commonly used subset of JSON Schema: type, enum,
+// const, properties, required, additionalProperties, items, minItems/maxItems,
+// minLength/maxLength, pattern and minimum/maximum. Other keywords are ignored. path
+// locates v in error messages ("$.items[2].name").
+func validateSchema(schema map[string]any, v any, path string) error {
+	if t, ok := schema["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []any:
+			for _, x := range t {
+				types = append(types, fmt.Sprint(x))
+			}
+		}
+		if !matchesType(v, types) {
+			return fmt.Errorf("%s: expected %s, got %s", path, joinOr(types), jsonType(v))
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, v) {
+		return fmt.Errorf("%s: expected %v, got %v", path, c, v)
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if req, ok := schema["required"].([]any); ok {
+			for _, r := range req {
+				if _, ok := v[fmt.Sprint(r)]; !ok {
+					return fmt.Errorf("%s: missing required property %q", path, r)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub, ok := props[k].(map[string]any)
+			if !ok {
+				if extra, ok := schema["additionalProperties"].(bool); ok && !extra {
+					return fmt.Errorf("%s: unexpected property %q", path, k)
+				}
+				if extra, ok := schema["additionalProperties"].(map[string]any); ok {
+					sub = extra
+				} else {
+					continue
+				}
+			}
+			if err := validateSchema(sub, v[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if n, ok := number(schema["minItems"]); ok && float64(len(v)) < n {
+			return fmt.Errorf("%s: expected at least %v items, got %d", path, n, len(v))
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(v)) > n {
+			return fmt.Errorf("%s: expected at most %v items, got %d", path, n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, x := range v {
+				if err := validateSchema(items, x, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		l := float64(utf8.RuneCountInString(v))
+		if n, ok := number(schema["minLength"]); ok && l < n {
+			return fmt.Errorf("%s: expected at least %v characters, got %v", path, n, l)
+		}
+		if n, ok := number(schema["maxLength"]); ok && l > n {
+			return fmt.Errorf("%s: expected at most %v characters, got %v", path, n, l)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %w", path, p, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: %q does not match %q", path, v, p)
+			}
+		}
+	case float64:
+		if n, ok := number(schema["minimum"]); ok && v < n {
+			return fmt.Errorf("%s: %v is less than the minimum %v", path, v, n)
+		}
+		if n, ok := number(schema["maximum"]); ok && v > n {
+			return fmt.Errorf("%s: %v is greater than the maximum %v", path, v, n)
+		}
+	}
+	return nil
+}
+
+func matchesType(v any, types []string) bool {
+	for _, t := range types {
+		switch got := jsonType(v); {
+		case t == got:
+			return true
+		case t == "number" && got == "integer":
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a value decoded by encoding/json.
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonEqual compares schema values (decoded from YAML, so integers may be int64) with
+// values decoded from JSON.
+func jsonEqual(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func joinOr(types []string) string {
+	switch len(types) {
+	case 0:
+		return "nothing"
+	case 1:
+		return types[0]
+	}
+	s := types[0]
+	for _, t := range types[1 : len(types)-1] {
+		s += ", " + t
+	}
+	return s + " or " + types[len(types)-1]
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/eval"
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+const evalUsage = "usage: nuro eval suite.yaml [-c profile ...] [-m model ...] [--grader model] [--junit file] [--json]"
+
+// runEval implements "nuro eval": run a suite of prompt test cases against one or more
+// profiles or models and report a pass-rate matrix, optionally as JUnit XML.
+func runEval(args []string) {
+	fs := pflag.NewFlagSet("eval", pflag.ContinueOnError)
+	profiles := fs.StringArrayP("cfg", "c", nil, "Profile from .nuro to evaluate (repeatable).")
+	models := fs.StringArrayP("model", "m", nil, "Model to evaluate (repeatable); provider:model picks the provider.")
+	graderRef := fs.String("grader", "", "Model that grades rubric assertions (default: the model under test).")
+	junitPath := fs.String("junit", "", "Write the results as JUnit XML to this file.")
+	jsonOut := fs.Bool("json", false, "Emit the results as JSON.")
+	timeoutSec := fs.Int("timeout", 120, "Timeout in seconds for each request.")
+	verbose := fs.Bool("verbose", false, "Verbose diagnostics to stderr.")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, evalUsage)
+		fs.PrintDefaults()
+	}
+	parseSubcommand(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		exitWithErr(usageError("eval needs exactly one suite file"), 2)
+	}
+	suite, err := eval.Load(fs.Arg(0))
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	enforceNoRedactionPolicy("eval")
+	enforceSuitePolicy(suite)
+	timeout := time.Duration(*timeoutSec) * time.Second
+
+	// Targets on the command line replace the suite's own list
+	if !fs.Changed("cfg") && !fs.Changed("model") {
+		*profiles, *models = suite.Profiles, suite.Models
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			exitWithErr(fmt.Errorf("invalid .nuro config: %w", err), 2)
+		}
+	}
+	var targets []eval.Target
+	for _, name := range *profiles {
+		t, err := profileTarget(cfg, name, timeout)
+		if err != nil {
+			exitWithErr(fmt.Errorf("profile %s: %w", name, err), 3)
+		}
+		targets = append(targets, t)
+	}
+
+	// Model references and the grader resolve against the default profile, like "nuro -m"
+	_, settings, err := loadSettings("")
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	base := provider.CompletionArgs{MaxTokens: 1024, Temperature: 0.7, TopP: 1.0, Timeout: timeout}
+	for _, ref := range *models {
+		res, prov, err := buildModelRef(settings, ref)
+		if err != nil {
+			exitWithErr(fmt.Errorf("%s: %w", ref, err), 3)
+		}
+		targets = append(targets, eval.Target{Name: ref, Provider: prov, Model: res.Model, Args: base})
+	}
+	if len(targets) == 0 {
+		res, prov, err := buildModelRef(settings, "")
+		if err != nil {
+			exitWithErr(err, 3)
+		}
+		name := res.ProviderName + ":" + res.Model
+		targets = append(targets, eval.Target{Name: name, Provider: prov, Model: res.Model, Args: base})
+	}
+	var grader eval.Grader
+	if *graderRef == "" {
+		*graderRef = suite.Grader
+	}
+	if ref := *graderRef; ref != "" {
+		res, prov, err := buildModelRef(settings, ref)
+		if err != nil {
+			exitWithErr(fmt.Errorf("grader %s: %w", ref, err), 3)
+		}
+		grader = eval.LLMGrader(prov, res.Model, timeout)
+	}
+	if *verbose {
+		for _, t := range targets {
+			_, _ = fmt.Fprintf(os.Stderr, "nuro: eval target=%s provider=%s model=%s\n", t.Name, t.Provider.Name(), t.Model)
+		}
+	}
+
+	ctx := context.WithValue(context.Background(), "nuro_verbose", *verbose)
+	report := eval.Run(ctx, suite, targets, grader)
+
+	if *junitPath != "" {
+		out, err := createAtomic(*junitPath)
+		if err != nil {
+			exitWithErr(err, 2)
+		}
+		if err := report.WriteJUnit(out); err != nil {
+			out.abort()
+			exitWithErr(err, 2)
+		}
+		if err := out.commit(); err != nil {
+			exitWithErr(err, 2)
+		}
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		printEvalReport(os.Stdout, report)
+	}
+
+	var failed, errored int
+	for t := range report.Targets {
+		_, f, e := report.Counts(t)
+		failed, errored = failed+f, errored+e
+	}
+	total := len(report.Targets) * len(report.Cases)
+	switch {
+	case errored > 0:
+		exitWithErr(fmt.Errorf("%d of %d eval runs failed with an error", errored, total), 4)
+	case failed > 0:
+		exitWithErr(fmt.Errorf("%d of %d eval runs failed their assertions", failed, total), 1)
+	}
+}
+
+// profileTarget resolves a .nuro profile into an eval target. Only the profile's own
+// settings are used, not NURO_* variables, so profiles are compared as written.
+func profileTarget(cfg *config.Config, name string, timeout time.Duration) (eval.Target, error) {
+	if cfg == nil {
+		return eval.Target{}, fmt.Errorf(".nuro config not found")
+	}
+	p, err := cfg.GetProfile(name, cliEnv.Env)
+	if err != nil {
+		return eval.Target{}, err
+	}
+	settings := resolver.Settings{
+		APIKey: p.APIKey, BaseURL: p.BaseURL, Provider: p.Provider, Model: p.Model, Env: cliEnv.Env,
+	}
+	res, prov, err := buildModelRef(settings, "")
+	if err != nil {
+		return eval.Target{}, err
+	}
+	args := provider.CompletionArgs{
+		MaxTokens:     p.MaxTokens,
+		Temperature:   valueOr(p.Temperature, 0.7),
+		TopP:          valueOr(p.TopP, 1.0),
+		Timeout:       timeout,
+		KeepAlive:     p.KeepAlive,
+		System:        p.System,
+		Seed:          p.Seed,
+		Stop:          p.Stop,
+		NumCtx:        p.NumCtx,
+		RepeatPenalty: valueOr(p.RepeatPenalty, 0),
+		TopK:          valueOr(p.TopK, 0),
+		MinP:          valueOr(p.MinP, 0),
+		Mirostat:      valueOr(p.Mirostat, 0),
+		OllamaAPI:     p.OllamaAPI,
+	}
+	if args.MaxTokens == 0 {
+		args.MaxTokens = 1024
+	}
+	return eval.Target{Name: name, Provider: prov, Model: res.Model, Args: args}, nil
+}
+
+// buildModelRef resolves a model reference (empty for the configured default) with s and
+// builds its provider, exiting with code 8 when a policy forbids it.
+func buildModelRef(s resolver.Settings, ref string) (*provider.ProviderResolution, provider.Provider, error) {
+	res, err := resolver.ResolveModelRef(s, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	enforceTargetPolicy(res)
+	prov, err := provider.BuildProvider(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, prov, nil
+}
+
+// printEvalReport prints the pass/fail matrix (cases by targets), the pass rate of each
+// target and the reasons of every failure.
+func printEvalReport(w io.Writer, r *eval.Report) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "TEST\t%s\n", strings.Join(r.Targets, "\t"))
+	for c, name := range r.Cases {
+		cells := make([]string, len(r.Targets))
+		for t := range r.Targets {
+			res := r.Results[t][c]
+			switch {
+			case res.Error != "":
+				cells[t] = "ERROR"
+			case res.Passed:
+				cells[t] = "PASS"
+			default:
+				cells[t] = "FAIL"
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\n", name, strings.Join(cells, "\t"))
+	}
+	rates := make([]string, len(r.Targets))
+	for t := range r.Targets {
+		passed, _, _ := r.Counts(t)
+		rates[t] = fmt.Sprintf("%d/%d (%.0f%%)", passed, len(r.Cases), 100*float64(passed)/float64(len(r.Cases)))
+	}
+	_, _ = fmt.Fprintf(tw, "PASS RATE\t%s\n", strings.Join(rates, "\t"))
+	_ = tw.Flush()
+
+	for t, target := range r.Targets {
+		for _, res := range r.Results[t] {
+			switch {
+			case res.Error != "":
+				_, _ = fmt.Fprintf(w, "\nERROR %s [%s]: %s\n", res.Case, target, res.Error)
+			case !res.Passed:
+				_, _ = fmt.Fprintf(w, "\nFAIL %s [%s]\n", res.Case, target)
+				for _, f := range res.Failures {
+					_, _ = fmt.Fprintf(w, "  - %s\n", f)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// maxExpanded bounds the bytes unpacked from one archive, against zip bombs.
+const maxExpanded = 64 << 20
+
+// archiveExtractor is implemented by extractors whose members are extracted in turn,
+// so they need the nesting depth.
+type archiveExtractor interface {
+	extractNested(name string, data []byte, depth int) ([]Doc, error)
+}
+
+// member extracts one file of an archive, labeled archive/member. A member that cannot
+// be extracted (binary data, a corrupt document) is reported as skipped rather than
+// failing the whole archive.
+func member(archive, name string, data []byte, depth int) []Doc {
+	label := archive + "/" + name
+	docs, err := extract(label, data, depth+1)
+	if err != nil {
+		msg := err.Error()
+		if errors.Is(err, ErrBinary) {
+			msg = strings.TrimPrefix(msg, label+": ")
+		}
+		return []Doc{{Name: label, Skipped: msg}}
+	}
+	return docs
+}
+
+// skipMember reports archive entries that are not content: directories, hidden files
+// and macOS resource forks.
+func skipMember(name string) bool {
+	if strings.HasSuffix(name, "/") || strings.HasPrefix(name, "__MACOSX/") {
+		return true
+	}
+	return strings.HasPrefix(path.Base(name), ".")
+}
+
+// readLimited reads r, failing once more than *budget bytes have been read in total.
+// A read error is returned along with the bytes read before it.
+func readLimited(r io.Reader, budget *int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, max(*budget+1, 0)))
+	*budget -= int64(len(b))
+	if *budget < 0 {
+		return nil, fmt.Errorf("expands to more than %dMB", maxExpanded>>20)
+	}
+	return b, err
+}
+
+var zipMagic = []byte("PK\x03\x04")
+
+type zipExtractor struct{}
+
+func (zipExtractor) Kind() string { return "zip" }
+
+func (zipExtractor) Detect(name string, head []byte) bool {
+	return bytes.HasPrefix(head, zipMagic) || bytes.HasPrefix(head, []byte("PK\x05\x06"))
+}
+
+func (e zipExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	return e.extractNested(name, data, 0)
+}
+
+// extractNested expands the files of a zip archive. A DOCX or ODT document, which is a
+// zip archive too, is extracted as a document even without its extension (from stdin).
+func (zipExtractor) extractNested(name string, data []byte, depth int) ([]Doc, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: zip: %w", name, err)
+	}
+	for _, doc := range []Extractor{docxExtractor{}, odtExtractor{}} {
+		if isDocument(zr, doc.Kind()) {
+			docs, err := doc.Extract(name, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", name, doc.Kind(), err)
+			}
+			return docs, nil
+		}
+	}
+
+	budget := int64(maxExpanded)
+	var docs []Doc
+	for _, f := range zr.File {
+		if skipMember(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: zip: %s: %w", name, f.Name, err)
+		}
+		b, err := readLimited(rc, &budget)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: zip: %w", name, err)
+		}
+		docs = append(docs, member(name, f.Name, b, depth)...)
+	}
+	return docs, nil
+}
+
+var tarMagic = []byte("ustar")
+
+type tarExtractor struct{}
+
+func (tarExtractor) Kind() string { return "tar" }
+
+func (tarExtractor) Detect(name string, head []byte) bool {
+	return hasExt(name, ".tar") || (len(head) >= 262 && bytes.Equal(head[257:262], tarMagic))
+}
+
+func (e tarExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	return e.extractNested(name, data, 0)
+}
+
+func (tarExtractor) extractNested(name string, data []byte, depth int) ([]Doc, error) {
+	tr := tar.NewReader(bytes.NewReader(data))
+	budget := int64(maxExpanded)
+	var docs []Doc
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: tar: %w", name, err)
+		}
+		if h.Typeflag != tar.TypeReg || skipMember(h.Name) {
+			continue
+		}
+		b, err := readLimited(tr, &budget)
+		if err != nil {
+			return nil, fmt.Errorf("%s: tar: %w", name, err)
+		}
+		docs = append(docs, member(name, strings.TrimPrefix(h.Name, "./"), b, depth)...)
+	}
+	return docs, nil
+}
+
+type gzipExtractor struct{}
+
+func (gzipExtractor) Kind() string { return "gzip" }
+
+func (gzipExtractor) Detect(name string, head []byte) bool {
+	return bytes.HasPrefix(head, []byte{0x1f, 0x8b})
+}
+
+func (e gzipExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	return e.extractNested(name, data, 0)
+}
+
+// extractNested decompresses the data and extracts the result under the name without
+// .gz, so app.log.gz is read as app.log and logs.tgz as logs.tar.
+func (gzipExtractor) extractNested(name string, data []byte, depth int) ([]Doc, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: gzip: %w", name, err)
+	}
+	budget := int64(maxExpanded)
+	b, err := readLimited(zr, &budget)
+	if err != nil {
+		return nil, fmt.Errorf("%s: gzip: %w", name, err)
+	}
+	inner := name
+	switch {
+	case hasExt(name, ".tgz"):
+		inner = strings.TrimSuffix(name, path.Ext(name)) + ".tar"
+	case hasExt(name, ".gz"):
+		inner = strings.TrimSuffix(name, path.Ext(name))
+	}
+	return extract(inner, b, depth+1)
+}
@@ -0,0 +1,170 @@
+// Package extract turns the files and stdin given as data into text worth sending to a
+// model: readable text from HTML, the text layer of a PDF, DOCX and ODT documents,
+// CSV/TSV as markdown tables, and the files inside tar and zip archives. Binary data is
+// refused rather than sent as mojibake.
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// sniffBytes is how much of the data is looked at to detect its type.
+const sniffBytes = 8000
+
+// maxDepth bounds archives nested inside archives.
+const maxDepth = 4
+
+// Doc is text extracted from data: one per plain file, several for an archive.
+type Doc struct {
+	Name string // label of the text: the data's name, or archive/member for archives
+	Kind string // extractor that produced it: "text", "html", "pdf", ...
+	Text string
+	// Skipped, when set, is why an archive member was left out (e.g. binary data); Text
+	// is empty.
+	Skipped string
+}
+
+// Extractor converts one content type to text.
+type Extractor interface {
+	// Kind names the content type, e.g. "html".
+	Kind() string
+	// Detect reports whether the data is of this type, from its name and its first
+	// bytes (at most 8000).
+	Detect(name string, head []byte) bool
+	// Extract returns the text of the data.
+	Extract(name string, data []byte) ([]Doc, error)
+}
+
+// ErrBinary is returned for data that is neither text nor of a type with an extractor.
+var ErrBinary = errors.New("binary data")
+
+// extractors are tried in order; the first that detects the data extracts it.
+var extractors = []Extractor{
+	docxExtractor{}, odtExtractor{}, zipExtractor{}, tarExtractor{}, gzipExtractor{},
+	pdfExtractor{}, htmlExtractor{}, tableExtractor{},
+}
+
+// Register adds an extractor, tried before the built-in ones.
+func Register(e Extractor) {
+	extractors = append([]Extractor{e}, extractors...)
+}
+
+// Kinds returns the kinds of the registered extractors, in the order they are tried.
+func Kinds() []string {
+	kinds := make([]string, 0, len(extractors))
+	for _, e := range extractors {
+		kinds = append(kinds, e.Kind())
+	}
+	return kinds
+}
+
+// Extract detects the type of data named name and returns its text. Text that no
+// extractor claims is returned as it is (UTF-16 is converted to UTF-8); other data
+// yields an error wrapping ErrBinary.
+func Extract(name string, data []byte) ([]Doc, error) {
+	return extract(name, data, 0)
+}
+
+func extract(name string, data []byte, depth int) ([]Doc, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%s: archives nested more than %d deep", name, maxDepth)
+	}
+	head := data[:min(len(data), sniffBytes)]
+	for _, e := range extractors {
+		if !e.Detect(name, head) {
+			continue
+		}
+		if a, ok := e.(archiveExtractor); ok {
+			return a.extractNested(name, data, depth)
+		}
+		docs, err := e.Extract(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", name, e.Kind(), err)
+		}
+		return docs, nil
+	}
+	text, err := Text(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return []Doc{{Name: name, Kind: "text", Text: text}}, nil
+}
+
+// Text returns data as a string if it is text, converting UTF-16 with a byte order mark
+// to UTF-8, and an error wrapping ErrBinary otherwise.
+func Text(data []byte) (string, error) {
+	if s, ok := decodeUTF16(data); ok {
+		return s, nil
+	}
+	if IsBinary(data) {
+		return "", fmt.Errorf("%w (%s) cannot be sent as text", ErrBinary, contentType(data))
+	}
+	return string(data), nil
+}
+
+// IsBinary reports whether data looks like binary rather than text: it contains NUL
+// bytes, or more than a few percent of it is not valid UTF-8, in its first 8000 bytes.
+func IsBinary(data []byte) bool {
+	head := data[:min(len(data), sniffBytes)]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	invalid := 0
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && size == 1 && len(head)-i >= utf8.UTFMax {
+			invalid++ // a rune cut off at the end of head is not counted
+		}
+		i += size
+	}
+	return invalid*100 > len(head)*2
+}
+
+// contentType names the type of binary data for error messages.
+func contentType(data []byte) string {
+	t := http.DetectContentType(data)
+	if t == "application/octet-stream" {
+		return "unknown type"
+	}
+	t, _, _ = strings.Cut(t, ";")
+	return t
+}
+
+func decodeUTF16(data []byte) (string, bool) {
+	var be bool
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		be = true
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+	default:
+		return "", false
+	}
+	data = data[2:]
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		if be {
+			u[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			u[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(u)), true
+}
+
+// hasExt reports whether name ends with one of exts (lowercase, with the dot).
+func hasExt(name string, exts ...string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
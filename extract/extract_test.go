@@ -0,0 +1,308 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func zipOf(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(files[i+1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzOf(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for i := 0; i+1 < len(files); i += 2 {
+		h := &tar.Header{Name: files[i], Mode: 0o644, Size: int64(len(files[i+1])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write([]byte(files[i+1]))
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+	return buf.Bytes()
+}
+
+const docxXML = `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>Quarterly report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Revenue grew </w:t></w:r><w:r><w:t>5%.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Hiring</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>EU</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>12</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`
+
+const odtXML = `<?xml version="1.0"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"><office:body><office:text>
+<text:h text:outline-level="2">Notes</text:h>
+<text:p>Two<text:s text:c="2"/>spaces<text:note><text:note-body><text:p>a footnote</text:p></text:note-body></text:note></text:p>
+<text:list><text:list-item><text:p>first</text:p></text:list-item></text:list>
+</office:text></office:body></office:document-content>`
+
+// testPDF builds a two-page PDF: the first page uses a simple font, the second a
+// composite font with a ToUnicode map, and both content streams are compressed.
+func testPDF(t testing.TB) []byte {
+	t.Helper()
+	flate := func(s string) string {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write([]byte(s))
+		_ = zw.Close()
+		return buf.String()
+	}
+	stream := func(dict, data string) string {
+		return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfchar <0001> <0048> endbfchar\n" +
+		"1 beginbfrange <0002> <0003> <0069> endbfrange\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 8 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /Differences [39 /quoteright] >> >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /X /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		stream("/Filter /FlateDecode", flate("BT /F1 12 Tf 72 720 Td (It's a ) Tj [(\\(test) -300 (page\\))] TJ 0 -14 Td (line two) Tj ET")),
+		stream("/Filter /FlateDecode", flate("BT /F2 12 Tf 72 720 Td <000100020003> Tj ET")),
+		stream("", cmap),
+	}
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	for i, o := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R /Size 10 >>\n%%EOF\n")
+	return []byte(b.String())
+}
+
+func TestExtract(t *testing.T) {
+	docx := zipOf(t, "[Content_Types].xml", "<Types/>", "word/document.xml", docxXML)
+	odt := zipOf(t, "mimetype", odtMimeType, "content.xml", odtXML)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string // the text of the docs, each as "name [kind]: text"
+	}{
+		{
+			name: "notes.txt",
+			data: []byte("plain text\n"),
+			want: "notes.txt [text]: plain text\n",
+		},
+		{
+			name: "utf16.txt",
+			data: []byte{0xFF, 0xFE, 'h', 0, 'i', 0},
+			want: "utf16.txt [text]: hi",
+		},
+		{
+			name: "page.html",
+			data: []byte(`<html><head><title>T</title><style>p{}</style></head><body>
+<h1>Title &amp; more</h1><script>var x = "<p>";</script>
+<p>Some   <b>bold</b>
+text.</p><ul><li>one</li><li>two<ul><li>nested</li></ul></li></ul>
+<table><tr><th>a</th><th>b</th></tr><tr><td>1</td><td>2</td></tr></table>
+<pre>  keep
+    this</pre></body></html>`),
+			want: "page.html [html]: # Title & more\n\nSome bold text.\n\n- one\n- two\n  - nested\n\n" +
+				"a | b\n1 | 2\n\n  keep\n    this\n",
+		},
+		{
+			name: "stdin",
+			data: []byte("<!DOCTYPE html><p>sniffed</p>"),
+			want: "stdin [html]: sniffed\n",
+		},
+		{
+			name: "sales.csv",
+			data: []byte("region,sales\nEU,12\n\"US, East\",\"a|b\"\n"),
+			want: "sales.csv [csv]: | region | sales |\n| --- | --- |\n| EU | 12 |\n| US, East | a\\|b |\n",
+		},
+		{
+			name: "sales.tsv",
+			data: []byte("a\tb\n1\n"),
+			want: "sales.tsv [csv]: | a | b |\n| --- | --- |\n| 1 |  |\n",
+		},
+		{
+			name: "report.docx",
+			data: docx,
+			want: "report.docx [docx]: # Quarterly report\nRevenue grew 5%.\n- Hiring\n\n" +
+				"| Region | Sales |\n| --- | --- |\n| EU | 12 |\n",
+		},
+		{
+			name: "stdin",
+			data: docx,
+			want: "stdin [docx]: # Quarterly report\nRevenue grew 5%.\n- Hiring\n\n" +
+				"| Region | Sales |\n| --- | --- |\n| EU | 12 |\n",
+		},
+		{
+			name: "notes.odt",
+			data: odt,
+			want: "notes.odt [odt]: ## Notes\nTwo  spaces\n- first\n",
+		},
+		{
+			name: "paper.pdf",
+			data: testPDF(t),
+			want: "paper.pdf [pdf]: It’s a (test page)\nline two\n\nHij\n",
+		},
+		{
+			name: "logs.zip",
+			data: zipOf(t, "app.log", "started\n", "img.png", "\x89PNG\r\n\x1a\n\x00\x00\x00", ".DS_Store", "x"),
+			want: "logs.zip/app.log [text]: started\n" +
+				"logs.zip/img.png skipped: binary data (image/png) cannot be sent as text\n",
+		},
+		{
+			name: "logs.tgz",
+			data: tarGzOf(t, "./a.log", "a\n", "dir/b.csv", "x,y\n1,2\n"),
+			want: "logs.tar/a.log [text]: a\n" +
+				"logs.tar/dir/b.csv [csv]: | x | y |\n| --- | --- |\n| 1 | 2 |\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				docs, err := Extract(tt.name, tt.data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for _, d := range docs {
+					if d.Skipped != "" {
+						got = append(got, d.Name+" skipped: "+d.Skipped+"\n")
+						continue
+					}
+					got = append(got, d.Name+" ["+d.Kind+"]: "+d.Text)
+				}
+				if g := strings.Join(got, ""); g != tt.want {
+					t.Errorf("got:\n%s\nwant:\n%s", g, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestExtractRefusesBinary(t *testing.T) {
+	for _, data := range [][]byte{
+		{0x7f, 'E', 'L', 'F', 2, 1, 1, 0, 0, 0},
+		bytes.Repeat([]byte{0xff, 0xfe, 0xfd, 0x80}, 100)[2:],
+	} {
+		_, err := Extract("blob", data)
+		if !errors.Is(err, ErrBinary) {
+			t.Errorf("expected ErrBinary for %q, got %v", data[:8], err)
+		}
+	}
+	if _, err := Extract("latin.txt", []byte("caf\xe9 au lait, na\xefve, "+strings.Repeat("plain ascii ", 50))); err != nil {
+		t.Errorf("expected a few invalid bytes in text to be accepted, got %v", err)
+	}
+	if _, err := Extract("scan.pdf", []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")); err == nil ||
+		!strings.Contains(err.Error(), "no text layer") {
+		t.Errorf("expected a missing text layer error, got %v", err)
+	}
+}
+
+func TestExtractPDFBomb(t *testing.T) {
+	// maxExpanded+1MB of zeros, compressed twice, is a stream of a few hundred bytes
+	var inner bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&inner, zlib.BestCompression)
+	zeros := make([]byte, 1<<20)
+	for range maxExpanded>>20 + 1 {
+		_, _ = zw.Write(zeros)
+	}
+	_ = zw.Close()
+	var outer bytes.Buffer
+	zw = zlib.NewWriter(&outer)
+	_, _ = zw.Write(inner.Bytes())
+	_ = zw.Close()
+
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	for i, o := range []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		fmt.Sprintf(
+			"<< /Filter [/Fl /Fl] /Length %d >>\nstream\n%s\nendstream", outer.Len(), outer.String(),
+		),
+	} {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+
+	if _, err := Extract("bomb.pdf", []byte(b.String())); err == nil ||
+		!strings.Contains(err.Error(), "expand to more than") {
+		t.Errorf("expected an expansion limit error, got %v", err)
+	}
+}
+
+// FuzzPDF feeds malformed PDFs to the extractor, which must return, with or without an
+// error, rather than panic or loop. Seeds are the test PDFs here and testdata/fuzz.
+func FuzzPDF(f *testing.F) {
+	f.Add(testPDF(f))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 1 0 R >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n"))
+	f.Add([]byte("%PDF-1.7\n1 0 obj\n<< /Length 99 >>\nstream\nBT (x) Tj ET\nendstream\nendobj\n"))
+	f.Fuzz(
+		func(t *testing.T, data []byte) {
+			done := make(chan any, 1)
+			go func() {
+				defer func() { done <- recover() }()
+				_, _ = Extract("fuzz.pdf", data)
+			}()
+			select {
+			case p := <-done:
+				if p != nil {
+					t.Fatalf("Extract panicked: %v", p)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Extract did not return within 10s")
+			}
+		},
+	)
+}
+
+type upperExtractor struct{}
+
+func (upperExtractor) Kind() string { return "upper" }
+
+func (upperExtractor) Detect(name string, head []byte) bool { return hasExt(name, ".up") }
+
+func (upperExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	return []Doc{{Name: name, Kind: "upper", Text: strings.ToUpper(string(data))}}, nil
+}
+
+func TestRegister(t *testing.T) {
+	saved := extractors
+	defer func() { extractors = saved }()
+	Register(upperExtractor{})
+	if Kinds()[0] != "upper" {
+		t.Errorf("expected a registered extractor to be tried first, got %v", Kinds())
+	}
+	docs, err := Extract("a.zip", zipOf(t, "x.up", "shout"))
+	if err != nil || len(docs) != 1 || docs[0].Text != "SHOUT" {
+		t.Errorf("expected archive members to use registered extractors, got %+v, %v", docs, err)
+	}
+}
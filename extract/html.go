@@ -0,0 +1,255 @@
+package extract
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+type htmlExtractor struct{}
+
+func (htmlExtractor) Kind() string { return "html" }
+
+func (htmlExtractor) Detect(name string, head []byte) bool {
+	if hasExt(name, ".html", ".htm", ".xhtml") {
+		return true
+	}
+	h := bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+	return bytes.HasPrefix(h, []byte("<!doctype html")) || bytes.HasPrefix(h, []byte("<html"))
+}
+
+// Extract returns the readable text of an HTML page: markup, scripts, styles and the
+// head are dropped, whitespace is collapsed outside <pre>, and headings, list items and
+// table cells are marked as in markdown.
+func (htmlExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	text, err := Text(data)
+	if err != nil {
+		return nil, err
+	}
+	return []Doc{{Name: name, Kind: "html", Text: htmlText(text)}}, nil
+}
+
+// htmlSkipped are elements whose content is not readable text.
+var htmlSkipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "head": true,
+	"iframe": true, "canvas": true, "object": true, "math": true,
+}
+
+// htmlBlocks are elements that start and end on lines of their own, with the number of
+// newlines around them.
+var htmlBlocks = map[string]int{
+	"p": 2, "div": 1, "section": 2, "article": 2, "header": 1, "footer": 1, "main": 1,
+	"aside": 1, "nav": 1, "blockquote": 2, "pre": 2, "ul": 2, "ol": 2, "dl": 2, "dt": 1,
+	"dd": 1, "table": 2, "tr": 1, "form": 1, "figure": 2, "figcaption": 1, "address": 1,
+	"hr": 2, "h1": 2, "h2": 2, "h3": 2, "h4": 2, "h5": 2, "h6": 2, "li": 1,
+}
+
+func htmlText(src string) string {
+	var w textWriter
+	lists, pre := 0, 0
+	cells := 0 // cells written in the current table row
+	for len(src) > 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt < 0 {
+			w.text(html.UnescapeString(src), pre > 0)
+			break
+		}
+		w.text(html.UnescapeString(src[:lt]), pre > 0)
+		src = src[lt:]
+
+		switch {
+		case strings.HasPrefix(src, "<!--"):
+			src = after(src, "-->")
+			continue
+		case strings.HasPrefix(src, "<!"), strings.HasPrefix(src, "<?"):
+			src = after(src, ">")
+			continue
+		}
+		name, closing, selfClosing, rest, ok := parseTag(src)
+		if !ok {
+			w.text("<", pre > 0) // a stray "<" is text
+			src = src[1:]
+			continue
+		}
+		src = rest
+
+		if htmlSkipped[name] && !closing && !selfClosing {
+			src = afterClosingTag(src, name)
+			continue
+		}
+		switch name {
+		case "br":
+			w.newlines(1, true)
+		case "pre":
+			if closing {
+				pre = max(pre-1, 0)
+			} else {
+				pre++
+			}
+		case "ul", "ol":
+			if closing {
+				lists = max(lists-1, 0)
+			} else {
+				lists++
+			}
+		case "tr":
+			cells = 0
+		case "td", "th":
+			if !closing {
+				if cells > 0 {
+					w.raw(" | ")
+				}
+				cells++
+			}
+		}
+		if n, ok := htmlBlocks[name]; ok {
+			if (name == "ul" || name == "ol") && lists > 1 || closing && lists > 0 {
+				n = 1 // a nested list continues its item
+			}
+			w.newlines(n, false)
+		}
+		if closing {
+			continue
+		}
+		switch name {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			w.raw(strings.Repeat("#", int(name[1]-'0')) + " ")
+		case "li":
+			w.raw(strings.Repeat("  ", max(lists-1, 0)) + "- ")
+		case "hr":
+			w.raw("---")
+			w.newlines(2, false)
+		}
+	}
+	return strings.TrimSpace(w.b.String()) + "\n"
+}
+
+// parseTag parses the tag at the start of s ("<name attrs>" or "</name>"), returning its
+// lowercased name and the text after it. Attribute values may contain ">" when quoted.
+func parseTag(s string) (name string, closing, selfClosing bool, rest string, ok bool) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isAlnum(s[i]) || s[i] == '-' || s[i] == ':') {
+		i++
+	}
+	if i == start || !isAlpha(s[start]) {
+		return "", false, false, s, false
+	}
+	name = strings.ToLower(s[start:i])
+	var quote byte
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return name, closing, s[i-1] == '/', s[i+1:], true
+		}
+	}
+	return name, closing, false, "", true // unterminated tag: drop the rest
+}
+
+// afterClosingTag returns the text after the closing tag of name, or "" if there is none.
+func afterClosingTag(s, name string) string {
+	lower := strings.ToLower(s)
+	for {
+		i := strings.Index(lower, "</"+name)
+		if i < 0 {
+			return ""
+		}
+		end := i + 2 + len(name)
+		if end == len(lower) || lower[end] == '>' || lower[end] == ' ' || lower[end] == '\t' || lower[end] == '\n' {
+			return after(s[i:], ">")
+		}
+		lower, s = lower[end:], s[end:]
+	}
+}
+
+// after returns the text after the first sep in s, or "" if there is none.
+func after(s, sep string) string {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[i+len(sep):]
+	}
+	return ""
+}
+
+func isAlpha(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isAlnum(c byte) bool { return isAlpha(c) || c >= '0' && c <= '9' }
+
+// textWriter writes text with whitespace collapsed and pending line breaks between
+// blocks, so that empty elements don't leave runs of blank lines.
+type textWriter struct {
+	b        strings.Builder
+	newline  int  // line breaks to write before the next text
+	space    bool // a space is pending before the next text
+	lineOpen bool // the last line has text
+	marker   bool // the line ends with a marker ("- ", "# ") that includes its space
+}
+
+func (w *textWriter) text(s string, pre bool) {
+	if s == "" {
+		return
+	}
+	if pre {
+		w.flush()
+		w.b.WriteString(s)
+		w.lineOpen, w.space = !strings.HasSuffix(s, "\n"), false
+		return
+	}
+	if isSpace(s[0]) {
+		w.space = true
+	}
+	for i, field := range strings.Fields(s) {
+		w.flush()
+		if (i > 0 || w.space) && w.lineOpen && !w.marker {
+			w.b.WriteString(" ")
+		}
+		w.b.WriteString(field)
+		w.lineOpen, w.space, w.marker = true, false, false
+	}
+	if isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+// raw writes markup such as "# " or " | " at the current position.
+func (w *textWriter) raw(s string) {
+	w.flush()
+	w.b.WriteString(s)
+	w.lineOpen, w.space, w.marker = true, false, strings.HasSuffix(s, " ")
+}
+
+// newlines asks for n line breaks before the next text; hard breaks (<br>) are written
+// even on an empty line.
+func (w *textWriter) newlines(n int, hard bool) {
+	if hard {
+		w.flush()
+		w.b.WriteString("\n")
+		w.lineOpen, w.space = false, false
+		return
+	}
+	if w.lineOpen || w.newline > 0 {
+		w.newline = max(w.newline, n)
+	}
+	w.space = false
+}
+
+func (w *textWriter) flush() {
+	if w.newline > 0 && w.b.Len() > 0 {
+		w.b.WriteString(strings.Repeat("\n", w.newline))
+		w.lineOpen = false
+		w.space = false
+	}
+	w.newline = 0
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\r' || c == '\n' }
@@ -0,0 +1,353 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const odtMimeType = "application/vnd.oasis.opendocument.text"
+
+type docxExtractor struct{}
+
+func (docxExtractor) Kind() string { return "docx" }
+
+func (docxExtractor) Detect(name string, head []byte) bool {
+	return hasExt(name, ".docx") && bytes.HasPrefix(head, zipMagic)
+}
+
+// Extract returns the paragraphs of word/document.xml, with headings and list items
+// marked as in markdown and tables as markdown tables.
+func (docxExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	part, err := zipPart(data, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	var w docWriter
+	inRun, inText := false, false
+	err = walkXML(
+		part, func(dec *xml.Decoder, tok xml.Token) error {
+			switch t := tok.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "p":
+					w.startPara()
+				case "pStyle":
+					style := attr(t, "val")
+					if n, ok := strings.CutPrefix(style, "Heading"); ok {
+						level, _ := strconv.Atoi(n)
+						w.heading(max(level, 1))
+					} else if style == "Title" {
+						w.heading(1)
+					}
+				case "ilvl":
+					level, _ := strconv.Atoi(attr(t, "val"))
+					w.listItem(level)
+				case "numPr":
+					w.listItem(0)
+				case "r":
+					inRun = true
+				case "t":
+					inText = true
+				case "tab":
+					if inRun {
+						w.text("\t")
+					}
+				case "br", "cr":
+					if inRun {
+						w.text("\n")
+					}
+				case "tbl":
+					w.startTable()
+				case "tr":
+					w.startRow()
+				case "tc":
+					w.startCell()
+				case "txbxContent":
+					return dec.Skip() // text boxes hold paragraphs within the paragraph
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "p":
+					w.endPara()
+				case "r":
+					inRun = false
+				case "t":
+					inText = false
+				case "tbl":
+					w.endTable()
+				case "tr":
+					w.endRow()
+				case "tc":
+					w.endCell()
+				}
+			case xml.CharData:
+				if inText {
+					w.text(string(t))
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return []Doc{{Name: name, Kind: "docx", Text: w.String()}}, nil
+}
+
+type odtExtractor struct{}
+
+func (odtExtractor) Kind() string { return "odt" }
+
+func (odtExtractor) Detect(name string, head []byte) bool {
+	return hasExt(name, ".odt") && bytes.HasPrefix(head, zipMagic)
+}
+
+// Extract returns the text of content.xml, with headings and list items marked as in
+// markdown and tables as markdown tables. Footnotes and comments are left out.
+func (odtExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	part, err := zipPart(data, "content.xml")
+	if err != nil {
+		return nil, err
+	}
+	var w docWriter
+	lists, inPara, listItem := 0, false, false
+	err = walkXML(
+		part, func(dec *xml.Decoder, tok xml.Token) error {
+			switch t := tok.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "p", "h":
+					w.startPara()
+					inPara = true
+					if t.Name.Local == "h" {
+						level, _ := strconv.Atoi(attr(t, "outline-level"))
+						w.heading(max(level, 1))
+					} else if listItem {
+						w.listItem(lists - 1)
+						listItem = false // further paragraphs of the item continue it
+					}
+				case "list":
+					lists++
+				case "list-item":
+					listItem = true
+				case "s":
+					n, err := strconv.Atoi(attr(t, "c"))
+					if err != nil {
+						n = 1
+					}
+					w.text(strings.Repeat(" ", n))
+				case "tab":
+					w.text("\t")
+				case "line-break":
+					w.text("\n")
+				case "table":
+					w.startTable()
+				case "table-row":
+					w.startRow()
+				case "table-cell":
+					w.startCell()
+				case "note", "annotation":
+					return dec.Skip()
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "p", "h":
+					w.endPara()
+					inPara = false
+				case "list":
+					lists--
+				case "table":
+					w.endTable()
+				case "table-row":
+					w.endRow()
+				case "table-cell":
+					w.endCell()
+				}
+			case xml.CharData:
+				if inPara {
+					w.text(string(t))
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return []Doc{{Name: name, Kind: "odt", Text: w.String()}}, nil
+}
+
+// isDocument reports whether a zip archive is a document of the given kind.
+func isDocument(zr *zip.Reader, kind string) bool {
+	for _, f := range zr.File {
+		switch {
+		case kind == "docx" && f.Name == "word/document.xml":
+			return true
+		case kind == "odt" && f.Name == "mimetype":
+			b, err := readZipFile(f)
+			return err == nil && strings.TrimSpace(string(b)) == odtMimeType
+		}
+	}
+	return false
+}
+
+// zipPart returns the contents of one file of a zip archive.
+func zipPart(data []byte, name string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if f.Name == name {
+			return readZipFile(f)
+		}
+	}
+	return nil, fmt.Errorf("%s not found in the document", name)
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	budget := int64(maxExpanded)
+	return readLimited(rc, &budget)
+}
+
+// walkXML calls fn with each token of an XML document.
+func walkXML(data []byte, fn func(dec *xml.Decoder, tok xml.Token) error) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(dec, tok); err != nil {
+			return err
+		}
+	}
+}
+
+// attr returns the value of the attribute with the given local name.
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docWriter assembles the text of a structured document from paragraphs, headings, list
+// items and tables, which are written as markdown tables.
+type docWriter struct {
+	out    strings.Builder
+	para   strings.Builder
+	prefix string
+	tables []*docTable // nested tables, innermost last
+}
+
+type docTable struct {
+	rows [][]string
+	row  []string
+	cell strings.Builder
+}
+
+func (w *docWriter) startPara() {
+	w.para.Reset()
+	w.prefix = ""
+}
+
+func (w *docWriter) heading(level int) { w.prefix = strings.Repeat("#", min(level, 6)) + " " }
+
+func (w *docWriter) listItem(level int) {
+	if !strings.HasPrefix(w.prefix, "#") {
+		w.prefix = strings.Repeat("  ", max(level, 0)) + "- "
+	}
+}
+
+func (w *docWriter) text(s string) { w.para.WriteString(s) }
+
+func (w *docWriter) endPara() {
+	text := strings.TrimSpace(w.para.String())
+	w.para.Reset()
+	if t := w.table(); t != nil {
+		if text != "" {
+			if t.cell.Len() > 0 {
+				t.cell.WriteString(" ")
+			}
+			t.cell.WriteString(text)
+		}
+		return
+	}
+	if text == "" {
+		return
+	}
+	if strings.HasPrefix(w.prefix, "#") && w.out.Len() > 0 {
+		w.out.WriteString("\n")
+	}
+	w.out.WriteString(w.prefix + text + "\n")
+}
+
+func (w *docWriter) table() *docTable {
+	if len(w.tables) == 0 {
+		return nil
+	}
+	return w.tables[len(w.tables)-1]
+}
+
+func (w *docWriter) startTable() { w.tables = append(w.tables, &docTable{}) }
+
+func (w *docWriter) startRow() {
+	if t := w.table(); t != nil {
+		t.row = nil
+	}
+}
+
+func (w *docWriter) startCell() {
+	if t := w.table(); t != nil {
+		t.cell.Reset()
+	}
+}
+
+func (w *docWriter) endCell() {
+	if t := w.table(); t != nil {
+		t.row = append(t.row, t.cell.String())
+	}
+}
+
+func (w *docWriter) endRow() {
+	if t := w.table(); t != nil {
+		t.rows = append(t.rows, t.row)
+	}
+}
+
+// endTable writes the table; a table nested in a cell is flattened into the cell's text.
+func (w *docWriter) endTable() {
+	t := w.table()
+	if t == nil {
+		return
+	}
+	w.tables = w.tables[:len(w.tables)-1]
+	if outer := w.table(); outer != nil {
+		for _, row := range t.rows {
+			outer.cell.WriteString(" " + strings.Join(row, " "))
+		}
+		return
+	}
+	if w.out.Len() > 0 {
+		w.out.WriteString("\n")
+	}
+	w.out.WriteString(markdownTable(t.rows) + "\n")
+}
+
+func (w *docWriter) String() string { return strings.TrimSpace(w.out.String()) + "\n" }
@@ -0,0 +1,921 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+type pdfExtractor struct{}
+
+func (pdfExtractor) Kind() string { return "pdf" }
+
+func (pdfExtractor) Detect(name string, head []byte) bool {
+	return bytes.Contains(head[:min(len(head), 1024)], []byte("%PDF-"))
+}
+
+// Extract returns the text layer of a PDF, page by page. Text drawn with fonts that
+// have neither a ToUnicode map nor a simple encoding is lost, and a PDF without a text
+// layer (a scan) is an error.
+func (pdfExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	if encryptRe.Match(data) {
+		return nil, errors.New("encrypted PDFs are not supported")
+	}
+	p := parsePDF(data)
+	var pages []string
+	for _, page := range p.pages() {
+		if text := strings.TrimSpace(p.pageText(page)); text != "" {
+			pages = append(pages, text)
+		}
+	}
+	if p.budget < 0 {
+		return nil, fmt.Errorf("streams expand to more than %dMB", maxExpanded>>20)
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("no text layer found (a scanned or image-only PDF?)")
+	}
+	return []Doc{{Name: name, Kind: "pdf", Text: strings.Join(pages, "\n\n") + "\n"}}, nil
+}
+
+var (
+	encryptRe = regexp.MustCompile(`/Encrypt\s*\d+\s+\d+\s+R`)
+	objRe     = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	rootRe    = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+)
+
+// The values of a parsed PDF: nil, bool, float64, pdfString, pdfName, pdfKeyword,
+// []any, pdfDict, pdfRef and *pdfStream.
+type (
+	pdfString  string
+	pdfName    string
+	pdfKeyword string
+	pdfDict    map[pdfName]any
+	pdfRef     int // object number
+)
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+// pdfDoc holds the objects of a PDF, found by scanning for "n g obj" rather than by
+// following the cross-reference table, which is often broken.
+type pdfDoc struct {
+	data    []byte
+	objects map[pdfRef]any
+	fonts   map[string]*pdfFont
+	budget  int64 // bytes the streams may still inflate to, against compression bombs
+}
+
+func parsePDF(data []byte) *pdfDoc {
+	p := &pdfDoc{
+		data: data, objects: map[pdfRef]any{}, fonts: map[string]*pdfFont{}, budget: maxExpanded,
+	}
+	for _, m := range objRe.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		lx := &pdfLexer{data: data, pos: m[1]}
+		if v, err := lx.object(true); err == nil {
+			p.objects[pdfRef(num)] = v // later revisions replace earlier ones
+		}
+	}
+
+	// Objects packed into object streams (PDF 1.5)
+	for _, v := range p.objects {
+		s, ok := v.(*pdfStream)
+		if !ok || s.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		body, err := p.decode(s)
+		if err != nil {
+			continue
+		}
+		n, _ := p.resolve(s.dict["N"]).(float64)
+		first, _ := p.resolve(s.dict["First"]).(float64)
+		lx := &pdfLexer{data: body}
+		var nums, offsets []int
+		for i := 0; i < int(n); i++ {
+			num, err1 := lx.object(false)
+			off, err2 := lx.object(false)
+			if err1 != nil || err2 != nil {
+				break
+			}
+			nf, _ := num.(float64)
+			of, _ := off.(float64)
+			nums, offsets = append(nums, int(nf)), append(offsets, int(of))
+		}
+		for i, num := range nums {
+			if _, defined := p.objects[pdfRef(num)]; defined {
+				continue
+			}
+			olx := &pdfLexer{data: body, pos: int(first) + offsets[i]}
+			if v, err := olx.object(false); err == nil {
+				p.objects[pdfRef(num)] = v
+			}
+		}
+	}
+	return p
+}
+
+func (p *pdfDoc) resolve(v any) any {
+	for i := 0; i < 16; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = p.objects[ref]
+	}
+	return nil
+}
+
+func (p *pdfDoc) dict(v any) pdfDict {
+	switch v := p.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// pdfPage is a page and the resources it uses, which may be inherited from the page tree.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages in order from the page tree, or every page object in object
+// number order if the tree cannot be found.
+func (p *pdfDoc) pages() []pdfPage {
+	var pages []pdfPage
+	seen := map[pdfRef]bool{}
+	var walk func(node any, resources pdfDict)
+	walk = func(node any, resources pdfDict) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+		}
+		d := p.dict(node)
+		if d == nil {
+			return
+		}
+		if r := p.dict(d["Resources"]); r != nil {
+			resources = r
+		}
+		if d["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: d, resources: resources})
+			return
+		}
+		kids, _ := p.resolve(d["Kids"]).([]any)
+		for _, kid := range kids {
+			walk(kid, resources)
+		}
+	}
+
+	var root pdfDict
+	if m := rootRe.FindAllSubmatch(p.data, -1); len(m) > 0 {
+		num, _ := strconv.Atoi(string(m[len(m)-1][1]))
+		root = p.dict(pdfRef(num))
+	}
+	if root == nil {
+		for _, v := range p.objects {
+			if d := p.dict(v); d != nil && d["Type"] == pdfName("Catalog") {
+				root = d
+			}
+		}
+	}
+	if root != nil {
+		walk(root["Pages"], nil)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	var refs []int
+	for ref, v := range p.objects {
+		if d := p.dict(v); d != nil && d["Type"] == pdfName("Page") {
+			refs = append(refs, int(ref))
+		}
+	}
+	sort.Ints(refs)
+	for _, ref := range refs {
+		d := p.dict(pdfRef(ref))
+		pages = append(pages, pdfPage{dict: d, resources: p.dict(d["Resources"])})
+	}
+	return pages
+}
+
+// pageText returns the text drawn by a page's content streams.
+func (p *pdfDoc) pageText(page pdfPage) string {
+	var content []byte
+	streams := []any{page.dict["Contents"]}
+	if arr, ok := p.resolve(page.dict["Contents"]).([]any); ok {
+		streams = arr
+	}
+	for _, s := range streams {
+		if s, ok := p.resolve(s).(*pdfStream); ok {
+			if b, err := p.decode(s); err == nil {
+				content = append(append(content, b...), '\n')
+			}
+		}
+	}
+	var w pdfTextWriter
+	p.runContent(content, page.resources, &w, 0)
+	return w.b.String()
+}
+
+// runContent interprets the text operators of a content stream, and form XObjects drawn
+// by it.
+func (p *pdfDoc) runContent(content []byte, resources pdfDict, w *pdfTextWriter, depth int) {
+	if depth > 8 {
+		return
+	}
+	var font *pdfFont
+	var operands []any
+	y, haveY := 0.0, false
+	lx := &pdfLexer{data: content}
+	for {
+		v, err := lx.object(false)
+		if err != nil {
+			return
+		}
+		op, ok := v.(pdfKeyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+		num := func(i int) float64 {
+			if i < len(operands) {
+				f, _ := operands[i].(float64)
+				return f
+			}
+			return 0
+		}
+		switch op {
+		case "Tf":
+			if len(operands) > 0 {
+				name, _ := operands[0].(pdfName)
+				font = p.font(p.dict(p.dict(resources["Font"])[name]))
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				w.show(font.decode(operands[0]))
+			}
+		case "'", "\"":
+			w.newline()
+			if len(operands) > 0 {
+				w.show(font.decode(operands[len(operands)-1]))
+			}
+		case "TJ":
+			arr, _ := firstOperand(operands).([]any)
+			for _, x := range arr {
+				if n, ok := x.(float64); ok {
+					if n < -200 {
+						w.space() // a gap wider than a fifth of an em separates words
+					}
+					continue
+				}
+				w.show(font.decode(x))
+			}
+		case "Td", "TD":
+			if ty := num(1); ty != 0 {
+				w.newline()
+				y += ty
+			} else if num(0) != 0 {
+				w.space()
+			}
+		case "T*":
+			w.newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				if ny := num(5); haveY && math.Abs(ny-y) > 0.5 {
+					w.newline()
+				} else if haveY {
+					w.space()
+				}
+				y, haveY = num(5), true
+			}
+		case "ET":
+			w.space()
+		case "Do":
+			name, _ := firstOperand(operands).(pdfName)
+			xobj, ok := p.resolve(p.dict(resources["XObject"])[name]).(*pdfStream)
+			if ok && xobj.dict["Subtype"] == pdfName("Form") {
+				if b, err := p.decode(xobj); err == nil {
+					res := p.dict(xobj.dict["Resources"])
+					if res == nil {
+						res = resources
+					}
+					p.runContent(b, res, w, depth+1)
+				}
+			}
+		case "ID":
+			lx.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+func firstOperand(operands []any) any {
+	if len(operands) == 0 {
+		return nil
+	}
+	return operands[0]
+}
+
+// decode returns the data of a stream with its filters undone.
+func (p *pdfDoc) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := p.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	data := s.raw
+	for _, f := range filters {
+		var err error
+		switch p.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			var zr io.ReadCloser
+			if zr, err = zlib.NewReader(bytes.NewReader(data)); err == nil {
+				var out []byte
+				out, err = readLimited(zr, &p.budget)
+				if len(out) > 0 {
+					err = nil // keep what a truncated stream yields
+				}
+				data = out
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			h := bytes.Map(
+				func(r rune) rune {
+					if r == '>' || r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+						return -1
+					}
+					return r
+				}, data,
+			)
+			if len(h)%2 == 1 {
+				h = append(h, '0')
+			}
+			data, err = hex.DecodeString(string(h))
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			src := bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+			if i := bytes.Index(src, []byte("~>")); i >= 0 {
+				src = src[:i]
+			}
+			out := make([]byte, len(src)*4/5+4)
+			var n int
+			n, _, err = ascii85.Decode(out, src, true)
+			data = out[:n]
+		default:
+			err = fmt.Errorf("unsupported filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	toUnicode map[string]string // code bytes to text, from the ToUnicode CMap
+	codeLen   int               // bytes per code in the ToUnicode CMap
+	composite bool              // a Type0 font, whose codes are meaningless without ToUnicode
+	encoding  map[byte]rune     // Differences of a simple font
+}
+
+func (p *pdfDoc) font(d pdfDict) *pdfFont {
+	if d == nil {
+		return nil
+	}
+	key := fmt.Sprintf("%p", d)
+	if f, ok := p.fonts[key]; ok {
+		return f
+	}
+	f := &pdfFont{codeLen: 1, composite: d["Subtype"] == pdfName("Type0")}
+	if f.composite {
+		f.codeLen = 2
+	}
+	if s, ok := p.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if b, err := p.decode(s); err == nil {
+			f.parseCMap(b)
+		}
+	}
+	if enc := p.dict(d["Encoding"]); enc != nil {
+		if diffs, ok := p.resolve(enc["Differences"]).([]any); ok {
+			f.encoding = map[byte]rune{}
+			code := 0
+			for _, x := range diffs {
+				switch x := p.resolve(x).(type) {
+				case float64:
+					code = int(x)
+				case pdfName:
+					if r, ok := glyphRune(string(x)); ok && code < 256 {
+						f.encoding[byte(code)] = r
+					}
+					code++
+				}
+			}
+		}
+	}
+	p.fonts[key] = f
+	return f
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap.
+func (f *pdfFont) parseCMap(b []byte) {
+	f.toUnicode = map[string]string{}
+	lx := &pdfLexer{data: b}
+	var operands []any
+	for {
+		v, err := lx.object(false)
+		if err != nil {
+			return
+		}
+		op, ok := v.(pdfKeyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+		switch op {
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if s, ok := operands[0].(pdfString); ok && len(s) > 0 {
+					f.codeLen = len(s)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, _ := operands[i].(pdfString)
+				dst, _ := operands[i+1].(pdfString)
+				f.toUnicode[string(src)] = utf16BE(dst)
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, _ := operands[i].(pdfString)
+				hi, _ := operands[i+1].(pdfString)
+				if len(lo) == 0 || len(lo) != len(hi) || len(lo) > 4 {
+					continue
+				}
+				start, end := codeValue(lo), codeValue(hi)
+				for c := start; c <= end && c-start < 65536; c++ {
+					code := codeBytes(c, len(lo))
+					switch dst := operands[i+2].(type) {
+					case pdfString:
+						if len(dst) >= 2 {
+							// The last byte pair of the destination increases with the code
+							d := []byte(dst)
+							last := uint32(d[len(d)-2])<<8 | uint32(d[len(d)-1])
+							last += c - start
+							d[len(d)-2], d[len(d)-1] = byte(last>>8), byte(last)
+							f.toUnicode[code] = utf16BE(pdfString(d))
+						}
+					case []any:
+						if int(c-start) < len(dst) {
+							if s, ok := dst[c-start].(pdfString); ok {
+								f.toUnicode[code] = utf16BE(s)
+							}
+						}
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(string(op), "end") || strings.HasPrefix(string(op), "begin") {
+			operands = operands[:0]
+		}
+	}
+}
+
+func codeValue(s pdfString) uint32 {
+	var v uint32
+	for i := 0; i < len(s); i++ {
+		v = v<<8 | uint32(s[i])
+	}
+	return v
+}
+
+func codeBytes(v uint32, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+func utf16BE(s pdfString) string {
+	u := make([]uint16, len(s)/2)
+	for i := range u {
+		u[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(u))
+}
+
+// decode returns the text of a string shown with the font.
+func (f *pdfFont) decode(v any) string {
+	s, ok := v.(pdfString)
+	if !ok {
+		return ""
+	}
+	if f == nil {
+		return winAnsi(string(s), nil)
+	}
+	if f.toUnicode != nil {
+		var b strings.Builder
+		for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+			code := string(s[i : i+f.codeLen])
+			if t, ok := f.toUnicode[code]; ok {
+				b.WriteString(t)
+			} else if f.codeLen == 1 && !f.composite {
+				b.WriteString(winAnsi(code, f.encoding))
+			}
+		}
+		return b.String()
+	}
+	if f.composite {
+		return ""
+	}
+	return winAnsi(string(s), f.encoding)
+}
+
+// winAnsi decodes the codes of a simple font, using its Differences and otherwise the
+// Windows-1252 encoding most PDF producers use.
+func winAnsi(s string, diffs map[byte]rune) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if r, ok := diffs[c]; ok {
+			b.WriteRune(r)
+			continue
+		}
+		switch {
+		case c >= 0x80 && c < 0xa0:
+			if r := cp1252[c-0x80]; r != 0 {
+				b.WriteRune(r)
+			}
+		case c < 0x20 && c != '\t' && c != '\n':
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+var cp1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// glyphNames maps the common glyph names of Differences arrays that are not a single
+// letter or digit.
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')', "asterisk": '*',
+	"plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/', "colon": ':',
+	"semicolon": ';', "less": '<', "equal": '=', "greater": '>', "question": '?', "at": '@',
+	"bracketleft": '[', "backslash": '\\', "bracketright": ']', "underscore": '_', "braceleft": '{',
+	"bar": '|', "braceright": '}', "asciitilde": '~', "quoteleft": '‘', "quoteright": '’',
+	"quotedblleft": '“', "quotedblright": '”', "bullet": '•', "endash": '–', "emdash": '—',
+	"ellipsis": '…', "copyright": '©', "registered": '®', "trademark": '™', "degree": '°',
+	"fi": 'ﬁ', "fl": 'ﬂ', "zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9', "minus": '−',
+}
+
+func glyphRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if hexCode, ok := strings.CutPrefix(name, prefix); ok && len(hexCode) >= 4 && len(hexCode) <= 6 {
+			if n, err := strconv.ParseUint(hexCode[:4], 16, 32); err == nil {
+				return rune(n), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// pdfTextWriter collects shown text, turning moves to a new line into newlines and gaps
+// into spaces.
+type pdfTextWriter struct {
+	b            strings.Builder
+	pendingSpace bool
+	pendingLine  bool
+}
+
+func (w *pdfTextWriter) show(s string) {
+	if s == "" {
+		return
+	}
+	if w.b.Len() > 0 {
+		switch {
+		case w.pendingLine:
+			w.b.WriteString("\n")
+		case w.pendingSpace && !strings.HasSuffix(w.b.String(), " ") && !strings.HasPrefix(s, " "):
+			w.b.WriteString(" ")
+		}
+	}
+	w.pendingSpace, w.pendingLine = false, false
+	w.b.WriteString(s)
+}
+
+func (w *pdfTextWriter) space()   { w.pendingSpace = true }
+func (w *pdfTextWriter) newline() { w.pendingLine = true }
+
+// pdfLexer reads PDF objects and, in content streams, operators.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+var errPDFEnd = errors.New("end of data")
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (lx *pdfLexer) skipSpace() {
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		switch {
+		case isPDFSpace(c):
+			lx.pos++
+		case c == '%':
+			for lx.pos < len(lx.data) && lx.data[lx.pos] != '\n' && lx.data[lx.pos] != '\r' {
+				lx.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object reads the next value. At the top level of an indirect object (top), a
+// dictionary followed by "stream" is read as a stream.
+func (lx *pdfLexer) object(top bool) (any, error) {
+	lx.skipSpace()
+	if lx.pos >= len(lx.data) {
+		return nil, errPDFEnd
+	}
+	c := lx.data[lx.pos]
+	switch {
+	case c == '/':
+		lx.pos++
+		return pdfName(lx.name()), nil
+	case c == '(':
+		lx.pos++
+		return lx.literalString(), nil
+	case c == '<' && lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '<':
+		lx.pos += 2
+		d := pdfDict{}
+		for {
+			lx.skipSpace()
+			if lx.pos >= len(lx.data) {
+				return nil, errPDFEnd
+			}
+			if bytes.HasPrefix(lx.data[lx.pos:], []byte(">>")) {
+				lx.pos += 2
+				break
+			}
+			k, err := lx.object(false)
+			if err != nil {
+				return nil, err
+			}
+			v, err := lx.object(false)
+			if err != nil {
+				return nil, err
+			}
+			if name, ok := k.(pdfName); ok {
+				d[name] = v
+			}
+		}
+		if top {
+			return lx.maybeStream(d), nil
+		}
+		return d, nil
+	case c == '<':
+		lx.pos++
+		end := bytes.IndexByte(lx.data[lx.pos:], '>')
+		if end < 0 {
+			return nil, errPDFEnd
+		}
+		h := bytes.Map(
+			func(r rune) rune {
+				if isPDFSpace(byte(r)) {
+					return -1
+				}
+				return r
+			}, lx.data[lx.pos:lx.pos+end],
+		)
+		lx.pos += end + 1
+		if len(h)%2 == 1 {
+			h = append(h, '0')
+		}
+		b, _ := hex.DecodeString(string(h))
+		return pdfString(b), nil
+	case c == '[':
+		lx.pos++
+		var arr []any
+		for {
+			lx.skipSpace()
+			if lx.pos >= len(lx.data) {
+				return nil, errPDFEnd
+			}
+			if lx.data[lx.pos] == ']' {
+				lx.pos++
+				return arr, nil
+			}
+			v, err := lx.object(false)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		lx.pos++
+		return pdfKeyword([]byte{c}), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		n := lx.number()
+		// "n g R" is a reference
+		save := lx.pos
+		if n == math.Trunc(n) && n >= 0 {
+			lx.skipSpace()
+			if lx.pos < len(lx.data) && lx.data[lx.pos] >= '0' && lx.data[lx.pos] <= '9' {
+				lx.number()
+				lx.skipSpace()
+				if lx.pos < len(lx.data) && lx.data[lx.pos] == 'R' &&
+					(lx.pos+1 == len(lx.data) || isPDFSpace(lx.data[lx.pos+1]) || isPDFDelim(lx.data[lx.pos+1])) {
+					lx.pos++
+					return pdfRef(int(n)), nil
+				}
+			}
+		}
+		lx.pos = save
+		return n, nil
+	}
+	start := lx.pos
+	for lx.pos < len(lx.data) && !isPDFSpace(lx.data[lx.pos]) && !isPDFDelim(lx.data[lx.pos]) {
+		lx.pos++
+	}
+	switch kw := string(lx.data[start:lx.pos]); kw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return pdfKeyword(kw), nil
+	}
+}
+
+func (lx *pdfLexer) name() string {
+	var b strings.Builder
+	for lx.pos < len(lx.data) && !isPDFSpace(lx.data[lx.pos]) && !isPDFDelim(lx.data[lx.pos]) {
+		c := lx.data[lx.pos]
+		if c == '#' && lx.pos+2 < len(lx.data) {
+			if v, err := strconv.ParseUint(string(lx.data[lx.pos+1:lx.pos+3]), 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				lx.pos += 3
+				continue
+			}
+		}
+		b.WriteByte(c)
+		lx.pos++
+	}
+	return b.String()
+}
+
+func (lx *pdfLexer) number() float64 {
+	start := lx.pos
+	for lx.pos < len(lx.data) && strings.IndexByte("+-.0123456789", lx.data[lx.pos]) >= 0 {
+		lx.pos++
+	}
+	n, _ := strconv.ParseFloat(string(lx.data[start:lx.pos]), 64)
+	return n
+}
+
+func (lx *pdfLexer) literalString() pdfString {
+	var b []byte
+	depth := 1
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		lx.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if lx.pos >= len(lx.data) {
+				return pdfString(b)
+			}
+			e := lx.data[lx.pos]
+			lx.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if lx.pos < len(lx.data) && lx.data[lx.pos] == '\n' {
+					lx.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && lx.pos < len(lx.data) && lx.data[lx.pos] >= '0' && lx.data[lx.pos] <= '7'; i++ {
+						v = v*8 + int(lx.data[lx.pos]-'0')
+						lx.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+// maybeStream reads the stream following a dictionary, if any. The data ends at
+// "endstream" rather than after /Length, which may be an unresolved reference.
+func (lx *pdfLexer) maybeStream(d pdfDict) any {
+	save := lx.pos
+	lx.skipSpace()
+	if !bytes.HasPrefix(lx.data[lx.pos:], []byte("stream")) {
+		lx.pos = save
+		return d
+	}
+	lx.pos += len("stream")
+	if bytes.HasPrefix(lx.data[lx.pos:], []byte("\r\n")) {
+		lx.pos += 2
+	} else if lx.pos < len(lx.data) && (lx.data[lx.pos] == '\n' || lx.data[lx.pos] == '\r') {
+		lx.pos++
+	}
+	start := lx.pos
+	if n, ok := d["Length"].(float64); ok && start+int(n) <= len(lx.data) {
+		end := start + int(n)
+		rest := bytes.TrimLeft(lx.data[end:min(end+32, len(lx.data))], " \r\n")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			lx.pos = end
+			return &pdfStream{dict: d, raw: lx.data[start:end]}
+		}
+	}
+	end := bytes.Index(lx.data[start:], []byte("endstream"))
+	if end < 0 {
+		end = len(lx.data) - start
+	}
+	raw := bytes.TrimSuffix(bytes.TrimSuffix(lx.data[start:start+end], []byte("\n")), []byte("\r"))
+	lx.pos = start + end
+	return &pdfStream{dict: d, raw: raw}
+}
+
+// skipInlineImage skips the data of an inline image, after its ID operator, up to EI.
+func (lx *pdfLexer) skipInlineImage() {
+	lx.pos++ // the single space after ID
+	for lx.pos < len(lx.data) {
+		i := bytes.Index(lx.data[lx.pos:], []byte("EI"))
+		if i < 0 {
+			lx.pos = len(lx.data)
+			return
+		}
+		at := lx.pos + i
+		lx.pos = at + 2
+		if at > 0 && isPDFSpace(lx.data[at-1]) && (lx.pos == len(lx.data) || isPDFSpace(lx.data[lx.pos])) {
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+)
+
+type tableExtractor struct{}
+
+func (tableExtractor) Kind() string { return "csv" }
+
+func (tableExtractor) Detect(name string, head []byte) bool {
+	return hasExt(name, ".csv", ".tsv", ".tab")
+}
+
+// Extract renders CSV, or TSV for .tsv and .tab files, as a markdown table whose first
+// row is the header.
+func (tableExtractor) Extract(name string, data []byte) ([]Doc, error) {
+	text, err := Text(data)
+	if err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(text, "\ufeff")))
+	if hasExt(name, ".tsv", ".tab") {
+		r.Comma = '\t'
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return []Doc{{Name: name, Kind: "csv", Text: markdownTable(rows)}}, nil
+}
+
+// markdownTable formats rows as a markdown table, the first row being the header. Short
+// rows are padded; pipes and line breaks in cells are escaped.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return ""
+	}
+	var b bytes.Buffer
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = strings.TrimSpace(row[i])
+				cell = strings.ReplaceAll(cell, "|", `\|`)
+				cell = strings.ReplaceAll(strings.ReplaceAll(cell, "\r\n", "\n"), "\n", "<br>")
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/resolver"
+)
+
+// validateFallbackOptions checks --fallback-on and --fallback-timeout (or the profile
+// values copied into them).
+func validateFallbackOptions(f *cliFlags) error {
+	for _, class := range f.fallbackOn {
+		if !slices.Contains(config.FallbackErrorClasses, class) {
+			return fmt.Errorf(
+				"--fallback-on must list error classes from %s, got %q",
+				strings.Join(config.FallbackErrorClasses, ", "), class,
+			)
+		}
+	}
+	if f.fallbackTimeout != "" {
+		if d, err := time.ParseDuration(f.fallbackTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("--fallback-timeout must be a duration like 20s, got %q", f.fallbackTimeout)
+		}
+	}
+	return nil
+}
+
+// newFallbackProvider chains prov, asked for model, with the fallback targets from
+// --fallback or the profile, resolved with s. It returns nil when no fallback is configured. Targets
+// that cannot be built (e.g. a provider nuro does not implement yet) or that a policy
+// forbids are skipped with a warning rather than failing a request the primary may
+// well answer.
+func newFallbackProvider(
+	s resolver.Settings, prov provider.Provider, model string, f *cliFlags,
+) (*provider.Fallback, error) {
+	if len(f.fallback) == 0 {
+		return nil, nil
+	}
+	fb := &provider.Fallback{
+		Links: []provider.FallbackLink{{Provider: prov, Model: model}},
+		On:    f.fallbackOn,
+	}
+	if f.fallbackTimeout != "" {
+		fb.AttemptTimeout, _ = time.ParseDuration(f.fallbackTimeout) // validated with the flags
+	}
+	for _, t := range f.fallback {
+		res, err := resolver.ResolveFallback(s, t.Provider, t.Model, t.APIKey, t.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTargetPolicy(res); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "nuro: WARNING: skipping fallback %s: %v\n", t, err)
+			continue
+		}
+		p, err := provider.BuildProvider(res)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "nuro: WARNING: skipping fallback %s: %v\n", t, err)
+			continue
+		}
+		fb.Links = append(fb.Links, provider.FallbackLink{Provider: p, Model: res.Model})
+	}
+	if len(fb.Links) == 1 {
+		return nil, nil
+	}
+
+	if f.verbose {
+		chain := make([]string, len(fb.Links))
+		for i, l := range fb.Links {
+			chain[i] = l.String()
+		}
+		on := "5xx,429,timeout,network"
+		if len(fb.On) > 0 {
+			on = strings.Join(fb.On, ",")
+		}
+		_, _ = fmt.Fprintf(
+			os.Stderr, "nuro: fallback chain %s on=%s\n", strings.Join(chain, " -> "), on,
+		)
+		fb.OnFallback = func(from, to provider.FallbackLink, err error) {
+			_, _ = fmt.Fprintf(
+				os.Stderr, "nuro: %s failed (%s): %v; falling back to %s\n", from,
+				provider.ErrorClass(err), err, to,
+			)
+		}
+	}
+	return fb, nil
+}
+
+// answeredBy returns the provider and model that served the request: the resolved
+// ones, or the fallback chain's answering link.
+func answeredBy(prov provider.Provider, model string, fb *provider.Fallback) provider.FallbackLink {
+	if fb == nil {
+		return provider.FallbackLink{Provider: prov, Model: model}
+	}
+	return fb.Answered()
+}
+
+// reportFallback reports on stderr which provider answered after a fallback.
+func reportFallback(fb *provider.Fallback) {
+	if fb == nil || len(fb.Skipped()) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(
+		os.Stderr, "nuro: answered by %s after fallback from %s\n", fb.Answered(),
+		strings.Join(fb.Skipped(), ", "),
+	)
+}
+
+// fallbackSkipped lists the providers that failed over before one answered.
+func fallbackSkipped(fb *provider.Fallback) []string {
+	if fb == nil {
+		return nil
+	}
+	return fb.Skipped()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+// Exit codes for responses that did not finish normally. The output is still written
+// before exiting so pipelines can decide what to do with partial answers.
+const (
+	exitTruncated     = 5 // finish_reason=length
+	exitContentFilter = 6 // finish_reason=content_filter
+	exitToolCalls     = 7 // finish_reason=tool_calls
+)
+
+// finishExitCode maps a finish reason to the process exit code.
+func finishExitCode(reason string) int {
+	switch reason {
+	case provider.FinishLength:
+		return exitTruncated
+	case provider.FinishContentFilter:
+		return exitContentFilter
+	case provider.FinishToolCalls:
+		return exitToolCalls
+	default:
+		return 0
+	}
+}
+
+// complete runs a single Complete or Stream call (Stream when onDelta is non-nil).
+// When maxContinuations > 0 and the answer was cut off by the token limit, it issues
+// up to that many continuation requests and stitches the parts together.
+func complete(
+	ctx context.Context, prov provider.Provider, args provider.CompletionArgs,
+	onDelta func(string), maxContinuations int, verbose bool,
+) (string, provider.Usage, string, error) {
+	var text string
+	var usage provider.Usage
+	for attempt := 0; ; attempt++ {
+		var part, finish string
+		var u provider.Usage
+		var err error
+		if onDelta != nil {
+			part, u, finish, err = prov.Stream(ctx, args, onDelta)
+		} else {
+			part, u, finish, err = prov.Complete(ctx, args)
+		}
+		text += part
+		usage = usage.Add(u)
+		if err != nil {
+			return text, usage, finish, err
+		}
+		if finish != provider.FinishLength || attempt >= maxContinuations {
+			return text, usage, finish, nil
+		}
+		if part == "" {
+			// No progress; continuing again would loop on the same truncated answer
+			return text, usage, finish, nil
+		}
+
+		if verbose {
+			_, _ = fmt.Fprintf(
+				os.Stderr, "nuro: output truncated at %d chars, continuing (%d/%d)\n", len(text),
+				attempt+1, maxContinuations,
+			)
+		}
+		args.Continuation = text
+	}
+}
+
+// reportFinish warns on stderr about abnormal finish reasons.
+func reportFinish(reason string) {
+	switch reason {
+	case provider.FinishLength:
+		_, _ = fmt.Fprintln(
+			os.Stderr,
+			"nuro: WARNING: output truncated by max tokens (finish_reason=length); "+
+				"raise --max-tokens or use --continue-on-length",
+		)
+	case provider.FinishContentFilter:
+		_, _ = fmt.Fprintln(
+			os.Stderr, "nuro: WARNING: output stopped by the provider's content filter",
+		)
+	case provider.FinishToolCalls:
+		_, _ = fmt.Fprintln(os.Stderr, "nuro: WARNING: model stopped to request a tool call")
+	}
+}
+
+// exitOnFinish reports an abnormal finish reason and exits with its dedicated code.
+func exitOnFinish(finish string) {
+	if code := finishExitCode(finish); code != 0 {
+		reportFinish(finish)
+		os.Exit(code)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+// scriptedProvider returns one scripted part per call and records the args it received.
+type scriptedProvider struct {
+	parts    []string
+	finishes []string
+	calls    []provider.CompletionArgs
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) next(args provider.CompletionArgs) (string, provider.Usage, string) {
+	i := len(p.calls)
+	p.calls = append(p.calls, args)
+	return p.parts[i], provider.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3}, p.finishes[i]
+}
+
+func (p *scriptedProvider) Complete(_ context.Context, args provider.CompletionArgs) (
+	string, provider.Usage, string, error,
+) {
+	text, usage, finish := p.next(args)
+	return text, usage, finish, nil
+}
+
+func (p *scriptedProvider) Stream(
+	_ context.Context, args provider.CompletionArgs, onDelta func(string),
+) (string, provider.Usage, string, error) {
+	text, usage, finish := p.next(args)
+	onDelta(text)
+	return text, usage, finish, nil
+}
+
+func TestCompleteStitchesContinuations(t *testing.T) {
+	prov := &scriptedProvider{
+		parts:    []string{"one two ", "three four ", "five"},
+		finishes: []string{"length", "length", "stop"},
+	}
+	var streamed strings.Builder
+	text, usage, finish, err := complete(
+		context.Background(), prov, provider.CompletionArgs{Prompt: "count"},
+		func(d string) { streamed.WriteString(d) }, 5, false,
+	)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if text != "one two three four five" || streamed.String() != text {
+		t.Errorf("unexpected stitched text %q (streamed %q)", text, streamed.String())
+	}
+	if finish != provider.FinishStop {
+		t.Errorf("expected final finish reason stop, got %q", finish)
+	}
+	if usage.TotalTokens != 9 {
+		t.Errorf("expected usage summed over 3 calls, got %+v", usage)
+	}
+	if len(prov.calls) != 3 || prov.calls[0].Continuation != "" ||
+		prov.calls[2].Continuation != "one two three four " {
+		t.Errorf("unexpected continuation args: %+v", prov.calls)
+	}
+}
+
+func TestCompleteStopsAtMaxContinuations(t *testing.T) {
+	prov := &scriptedProvider{
+		parts:    []string{"a", "b", "c"},
+		finishes: []string{"length", "length", "length"},
+	}
+	text, _, finish, err := complete(
+		context.Background(), prov, provider.CompletionArgs{}, nil, 1, false,
+	)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if text != "ab" || finish != provider.FinishLength || len(prov.calls) != 2 {
+		t.Errorf("expected 2 calls ending truncated, got %q %q after %d calls", text, finish, len(prov.calls))
+	}
+}
+
+func TestFinishExitCode(t *testing.T) {
+	tests := map[string]int{
+		"":               0,
+		"stop":           0,
+		"length":         exitTruncated,
+		"content_filter": exitContentFilter,
+		"tool_calls":     exitToolCalls,
+	}
+	for reason, expected := range tests {
+		if got := finishExitCode(reason); got != expected {
+			t.Errorf("finishExitCode(%q) = %d, expected %d", reason, got, expected)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/heather7532/nuro/extract"
+)
+
+// includeRe matches @path references in a prompt: an @ at the start of the text or
+// after whitespace, followed by a file, directory or glob. "@@" escapes a literal "@".
+var includeRe = regexp.MustCompile(`(^|\s)@(@?)(\S+)`)
+
+// expandIncludes replaces each @path reference in prompt with the contents of the files
+// it names, each in a code fence headed by the file name, and returns the number of
+// bytes inlined. References that name no file are left as they are. Files outside the
+// working directory and hidden files are refused unless they are within an allow path.
+func expandIncludes(prompt string, allow []string, verbose bool) (string, int, error) {
+	var firstErr error
+	included := 0
+	out := includeRe.ReplaceAllStringFunc(
+		prompt, func(m string) string {
+			sub := includeRe.FindStringSubmatch(m)
+			lead, escaped, ref := sub[1], sub[2], sub[3]
+			if escaped != "" || firstErr != nil {
+				return lead + "@" + ref
+			}
+
+			// Punctuation right after a reference ("see @notes.md.") is not part of it
+			trail := ""
+			paths, err := expandPaths(ref)
+			if err == nil && len(paths) == 0 {
+				if trimmed := strings.TrimRight(ref, ".,;:!?)\"'"); trimmed != ref && trimmed != "" {
+					trail = ref[len(trimmed):]
+					paths, err = expandPaths(trimmed)
+					ref = trimmed
+				}
+			}
+			if err != nil {
+				firstErr = fmt.Errorf("@%s: %w", ref, err)
+				return m
+			}
+			if len(paths) == 0 {
+				if strings.ContainsAny(ref, "/*?[") {
+					_, _ = fmt.Fprintf(os.Stderr, "nuro: @%s matches no files; left as text\n", ref)
+				}
+				return m
+			}
+
+			// The fences go on lines of their own
+			var sb strings.Builder
+			if lead != "" {
+				sb.WriteString("\n")
+			}
+			for i, path := range paths {
+				if err := checkInclude(path, allow); err != nil {
+					firstErr = fmt.Errorf("@%s: %w", ref, err)
+					return m
+				}
+				b, err := os.ReadFile(path)
+				if err != nil {
+					firstErr = fmt.Errorf("@%s: %w", ref, err)
+					return m
+				}
+				if i > 0 {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(fencedFile(filepath.ToSlash(path), string(b)))
+				included += len(b)
+				if verbose {
+					_, _ = fmt.Fprintf(os.Stderr, "nuro: included %s (%s)\n", path, formatBytes(len(b)))
+				}
+			}
+			sb.WriteString("\n")
+			sb.WriteString(trail)
+			return sb.String()
+		},
+	)
+	if firstErr != nil {
+		return "", 0, firstErr
+	}
+	return out, included, nil
+}
+
+// checkInclude refuses a file an @path reference may not read: one outside the working
+// directory or below a hidden file or directory, unless it is within one of the allow
+// paths given with --allow-include. Symlinks are resolved first.
+func checkInclude(path string, allow []string) error {
+	real, err := realPath(path)
+	if err != nil {
+		return err
+	}
+	for _, a := range allow {
+		if root, err := realPath(a); err == nil && withinDir(root, real) {
+			return nil
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if wd, err = realPath(wd); err != nil {
+		return err
+	}
+	if !withinDir(wd, real) {
+		return fmt.Errorf("%s is outside the working directory; allow it with --allow-include", path)
+	}
+	rel, _ := filepath.Rel(wd, real)
+	for _, seg := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(seg, ".") {
+			return fmt.Errorf("%s is hidden; allow it with --allow-include", path)
+		}
+	}
+	return nil
+}
+
+// realPath returns the absolute path with symlinks resolved.
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// withinDir reports whether path is root or below it.
+func withinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// fencedFile formats a file as a code fence whose info string is the file name, using a
+// fence longer than any backtick run in the content.
+func fencedFile(name, content string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence + name + "\n" + strings.TrimRight(content, "\n") + "\n" + fence
+}
+
+// expandPaths returns the regular files named by pattern, sorted: the file itself, every
+// file below a directory, or the matches of a glob in which "**" matches any number of
+// directories. Hidden files and directories are skipped unless named explicitly. A
+// pattern that names nothing yields no paths and no error.
+func expandPaths(pattern string) ([]string, error) {
+	if !hasGlobMeta(pattern) {
+		fi, err := os.Stat(pattern)
+		if err != nil {
+			return nil, nil
+		}
+		if !fi.IsDir() {
+			return []string{pattern}, nil
+		}
+		pattern = filepath.Join(pattern, "**")
+	}
+
+	// Walk from the longest leading directory without glob characters
+	segs := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	i := 0
+	for i < len(segs)-1 && !hasGlobMeta(segs[i]) {
+		i++
+	}
+	root := strings.Join(segs[:i], "/")
+	switch {
+	case root == "" && strings.HasPrefix(pattern, "/"):
+		root = "/"
+	case root == "":
+		root = "."
+	}
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		return nil, nil
+	}
+	rest := segs[i:]
+	recursive := false
+	for _, s := range rest {
+		recursive = recursive || s == "**"
+	}
+
+	var paths []string
+	err := filepath.WalkDir(
+		root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == root {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if d.IsDir() {
+				if !recursive && len(parts) >= len(rest) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() && matchSegments(rest, parts) {
+				paths = append(paths, path)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// matchSegments matches path segments against pattern segments, where "**" matches zero
+// or more segments and any other segment is a filepath.Match pattern.
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for k := 0; k <= len(parts); k++ {
+			if matchSegments(pattern[1:], parts[k:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], parts[0])
+	return ok && matchSegments(pattern[1:], parts[1:])
+}
+
+// dataSource is one piece of the data sent with the prompt: --data, a --data-file match
+// or stdin.
+type dataSource struct {
+	name    string
+	kind    string // extractor that produced the content, e.g. "pdf"; empty for --data
+	content string
+}
+
+// readDataFiles reads the files matched by each --data-file pattern, in order. A pattern
+// that matches no file is an error.
+func readDataFiles(patterns []string, raw bool) ([]dataSource, error) {
+	var sources []dataSource
+	for _, pattern := range patterns {
+		paths, err := expandPaths(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --data-file: %w", err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("failed to read --data-file: %s matches no files", pattern)
+		}
+		for _, path := range paths {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read --data-file: %w", err)
+			}
+			found, err := extractSources(filepath.ToSlash(path), b, raw)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, found...)
+		}
+	}
+	return sources, nil
+}
+
+// extractSources turns data read from a file or stdin into sources: the text of HTML,
+// PDF and office documents, or the files of an archive, each its own source. With raw,
+// the data is sent as it is. Binary data is refused either way; binary files inside an
+// archive are skipped with a warning.
+func extractSources(name string, b []byte, raw bool) ([]dataSource, error) {
+	if raw {
+		text, err := extract.Text(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w (use a text file or a supported format)", name, err)
+		}
+		return []dataSource{{name: name, kind: "text", content: text}}, nil
+	}
+	docs, err := extract.Extract(name, b)
+	if err != nil {
+		return nil, fmt.Errorf("%w (supported formats: %s)", err, strings.Join(extract.Kinds(), ", "))
+	}
+	sources := make([]dataSource, 0, len(docs))
+	for _, d := range docs {
+		if d.Skipped != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "nuro: skipped %s: %s\n", d.Name, d.Skipped)
+			continue
+		}
+		sources = append(sources, dataSource{name: d.Name, kind: d.Kind, content: d.Text})
+	}
+	return sources, nil
+}
+
+// assembleData joins the data sources. A single source is sent as it is; several are
+// each put in a code fence labeled with the source's name, so the model can tell them
+// apart. The fences start on a line of their own since the data follows the prompt on
+// the same line.
+func assembleData(sources []dataSource, verbose bool) string {
+	if verbose {
+		for _, src := range sources {
+			kind := ""
+			if src.kind != "" {
+				kind = src.kind + ", "
+			}
+			_, _ = fmt.Fprintf(
+				os.Stderr, "nuro: data source %s (%s%s)\n", src.name, kind, formatBytes(len(src.content)),
+			)
+		}
+	}
+	switch len(sources) {
+	case 0:
+		return ""
+	case 1:
+		return sources[0].content
+	}
+	sections := make([]string, 0, len(sources))
+	for _, src := range sources {
+		sections = append(sections, fencedFile(src.name, src.content))
+	}
+	return fmt.Sprintf("%d sources:\n\n%s", len(sources), strings.Join(sections, "\n\n"))
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// chdir changes to dir for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestExpandPaths(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "src/main.go", "package main")
+	writeTestFile(t, "src/util/util.go", "package util")
+	writeTestFile(t, "src/util/util_test.go", "package util")
+	writeTestFile(t, "src/README.md", "# src")
+	writeTestFile(t, "src/.hidden/x.go", "package hidden")
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"src/main.go", []string{"src/main.go"}},
+		{"src/**/*.go", []string{"src/main.go", "src/util/util.go", "src/util/util_test.go"}},
+		{"src/*.go", []string{"src/main.go"}},
+		{"src/util", []string{"src/util/util.go", "src/util/util_test.go"}},
+		{"src/**/*_test.go", []string{"src/util/util_test.go"}},
+		{"missing.txt", nil},
+		{"src/**/*.rs", nil},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.pattern, func(t *testing.T) {
+				got, err := expandPaths(tt.pattern)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range got {
+					got[i] = filepath.ToSlash(got[i])
+				}
+				if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+					t.Errorf("expandPaths(%q) = %v, want %v", tt.pattern, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestExpandIncludes(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "notes.md", "remember the milk\n")
+	writeTestFile(t, "code.md", "```go\nx := 1\n```\n")
+
+	tests := []struct {
+		name     string
+		prompt   string
+		want     string
+		included int
+	}{
+		{
+			name:     "file",
+			prompt:   "Summarize @notes.md please",
+			want:     "Summarize\n```notes.md\nremember the milk\n```\n please",
+			included: 18,
+		},
+		{
+			name:     "trailing punctuation",
+			prompt:   "Read @notes.md.",
+			want:     "Read\n```notes.md\nremember the milk\n```\n.",
+			included: 18,
+		},
+		{
+			name:     "longer fence",
+			prompt:   "@code.md",
+			want:     "````code.md\n```go\nx := 1\n```\n````\n",
+			included: 17,
+		},
+		{name: "escaped", prompt: "mail me @@notes.md", want: "mail me @notes.md"},
+		{name: "no such file", prompt: "ping @someone and a@notes.md", want: "ping @someone and a@notes.md"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, n, err := expandIncludes(tt.prompt, nil, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want || n != tt.included {
+					t.Errorf("expandIncludes(%q) = %q, %d; want %q, %d", tt.prompt, got, n, tt.want, tt.included)
+				}
+			},
+		)
+	}
+}
+
+func TestExpandIncludesRefusesOutsideAndHidden(t *testing.T) {
+	outside := t.TempDir()
+	writeTestFile(t, filepath.Join(outside, "secret.txt"), "outside\n")
+	chdir(t, t.TempDir())
+	writeTestFile(t, ".env", "TOKEN=x\n")
+	writeTestFile(t, ".ssh/id_rsa", "key\n")
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), "link.txt"); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.ToSlash(filepath.Join(outside, "secret.txt"))
+
+	tests := []struct {
+		name         string
+		prompt       string
+		allow        []string
+		wantErrorMsg string
+	}{
+		{name: "outside", prompt: "@" + secret, wantErrorMsg: "outside the working directory"},
+		{name: "parent", prompt: "@../" + filepath.Base(outside) + "/secret.txt", wantErrorMsg: "outside the working directory"},
+		{name: "symlink out", prompt: "@link.txt", wantErrorMsg: "outside the working directory"},
+		{name: "hidden file", prompt: "@.env", wantErrorMsg: ".env is hidden"},
+		{name: "hidden directory", prompt: "@.ssh", wantErrorMsg: "is hidden"},
+		{name: "allowed file", prompt: "@" + secret, allow: []string{secret}},
+		{name: "allowed directory", prompt: "@" + secret, allow: []string{outside}},
+		{name: "allowed hidden", prompt: "@.env", allow: []string{".env"}},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, _, err := expandIncludes(tt.prompt, tt.allow, false)
+				if tt.wantErrorMsg == "" {
+					if err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrorMsg) {
+					t.Errorf("expected error %q, got %v", tt.wantErrorMsg, err)
+				}
+			},
+		)
+	}
+}
+
+func TestNamedPromptIsNotExpanded(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "notes.md", "remember the milk\n")
+
+	f := cliFlags{promptFlag: "Summarize @notes.md", namedPrompt: true}
+	prompt, _, err := resolvePromptAndData(&f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt != "Summarize @notes.md" || f.includedBytes != 0 {
+		t.Errorf("expected the library prompt as written, got %q (%d bytes included)", prompt, f.includedBytes)
+	}
+}
+
+func TestValidateDataSizeCountsIncludedBytes(t *testing.T) {
+	err := validateDataSize("", 600*1024, false, false)
+	if err == nil || !strings.Contains(err.Error(), "exceeds safe limit") {
+		t.Errorf("expected included bytes to count toward the limit, got: %v", err)
+	}
+}
+
+func TestResolveMultipleDataSources(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "a.yaml", "replicas: 1\n")
+	writeTestFile(t, "b.yaml", "replicas: 3\n")
+	writeTestFile(t, "conf/c.yaml", "replicas: 5\n")
+	writeTestFile(t, "page.html", "<html><body><p>Hello <b>there</b></p></body></html>")
+	writeTestFile(t, "logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name         string
+		flags        cliFlags
+		want         string
+		wantErrorMsg string
+	}{
+		{
+			name:  "no data",
+			flags: cliFlags{promptFlag: "hi"},
+			want:  "",
+		},
+		{
+			name:  "single file is sent as is",
+			flags: cliFlags{dataFiles: []string{"a.yaml"}},
+			want:  "replicas: 1\n",
+		},
+		{
+			name:  "files are labeled",
+			flags: cliFlags{dataFiles: []string{"a.yaml", "b.yaml"}},
+			want:  "2 sources:\n\n```a.yaml\nreplicas: 1\n```\n\n```b.yaml\nreplicas: 3\n```",
+		},
+		{
+			name:  "inline data and a glob",
+			flags: cliFlags{dataInline: "env: prod", dataFiles: []string{"conf/*.yaml"}},
+			want:  "2 sources:\n\n```data\nenv: prod\n```\n\n```conf/c.yaml\nreplicas: 5\n```",
+		},
+		{
+			name:  "html is extracted",
+			flags: cliFlags{dataFiles: []string{"page.html"}},
+			want:  "Hello there\n",
+		},
+		{
+			name:  "no extraction",
+			flags: cliFlags{dataFiles: []string{"page.html"}, noExtract: true},
+			want:  "<html><body><p>Hello <b>there</b></p></body></html>",
+		},
+		{
+			name:         "binary is refused",
+			flags:        cliFlags{dataFiles: []string{"logo.png"}},
+			wantErrorMsg: "logo.png: binary data (image/png) cannot be sent as text",
+		},
+		{
+			name:         "no match",
+			flags:        cliFlags{dataFiles: []string{"*.json"}},
+			wantErrorMsg: "*.json matches no files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, data, err := resolvePromptAndData(&tt.flags)
+				if tt.wantErrorMsg != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErrorMsg) {
+						t.Fatalf("expected error %q, got %v", tt.wantErrorMsg, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data != tt.want {
+					t.Errorf("data = %q, want %q", data, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/rag"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+const indexUsage = `usage: nuro index build <dir> [--embed-model model] [--chunk-size n] [--out file]`
+
+// runIndex implements "nuro index build <dir>".
+func runIndex(args []string) {
+	if len(args) > 0 {
+		exitOnHelp(args[0], indexUsage)
+	}
+	if len(args) == 0 || args[0] != "build" {
+		exitWithErr(usageError(indexUsage), 2)
+	}
+
+	fs := pflag.NewFlagSet("index build", pflag.ContinueOnError)
+	embedModel := fs.String("embed-model", "", "Embedding model (default depends on provider).")
+	chunkSize := fs.Int("chunk-size", rag.DefaultChunkSize, "Approximate characters per chunk.")
+	out := fs.String("out", "", "Index file path (default: <dir>/"+rag.IndexFileName+").")
+	configName := fs.StringP("cfg", "c", "", "Use a named configuration profile from .nuro file")
+	timeoutSec := fs.Int("timeout", 300, "Timeout in seconds for the whole build.")
+	verbose := fs.Bool("verbose", false, "Verbose diagnostics to stderr.")
+	parseSubcommand(fs, args[1:])
+	if fs.NArg() != 1 {
+		exitWithErr(usageError(indexUsage), 2)
+	}
+	root := fs.Arg(0)
+
+	_, settings, err := loadSettings(*configName)
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	res, err := resolver.ResolveProviderAndModel(settings, "")
+	if err != nil {
+		exitWithErr(err, 3)
+	}
+	model := *embedModel
+	if model == "" {
+		model = provider.DefaultEmbeddingModel(res.ProviderName)
+	}
+	// The corpus goes to the embedding model as it is, so the policies apply to that model
+	// and to the chunks before anything is sent
+	enforceEmbeddingPolicy(res.ProviderName, model)
+	enforceNoRedactionPolicy("index")
+	prov, err := provider.BuildProvider(res)
+	if err != nil {
+		exitWithErr(err, 3)
+	}
+	emb, ok := prov.(provider.Embedder)
+	if !ok {
+		exitWithErr(fmt.Errorf("provider '%s' does not support embeddings", prov.Name()), 3)
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Duration(*timeoutSec)*time.Second,
+	)
+	defer cancel()
+
+	var policyErr error
+	opts := rag.BuildOptions{
+		ChunkSize: *chunkSize,
+		Check: func(chunks []rag.Chunk) error {
+			size := 0
+			texts := make([]string, len(chunks))
+			for i, c := range chunks {
+				size += len(c.Text)
+				texts[i] = c.Text
+			}
+			policyErr = checkDataPolicy(size, texts...)
+			return policyErr
+		},
+	}
+	if *verbose {
+		_, _ = fmt.Fprintf(
+			os.Stderr, "nuro: index provider=%s embed_model=%s root=%s\n", prov.Name(), model, root,
+		)
+		opts.Progress = func(done, total int) {
+			_, _ = fmt.Fprintf(os.Stderr, "nuro: embedded %d/%d chunks\n", done, total)
+		}
+	}
+
+	ix, err := rag.Build(ctx, emb, prov.Name(), model, root, opts)
+	if policyErr != nil {
+		exitWithErr(policyErr, exitPolicy)
+	}
+	if err != nil {
+		exitWithErr(err, 4)
+	}
+
+	path := *out
+	if path == "" {
+		info, err := os.Stat(root)
+		if err == nil && !info.IsDir() {
+			exitWithErr(usageError("--out is required when indexing a single file"), 2)
+		}
+		path = rag.IndexPath(root)
+	}
+	if err := ix.Save(path); err != nil {
+		exitWithErr(fmt.Errorf("failed to write index: %w", err), 2)
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "nuro: indexed %d chunks into %s\n", len(ix.Chunks), path)
+}
+
+// retrieveContext loads the --rag index and returns the chunks most relevant to the prompt.
+func retrieveContext(
+	ctx context.Context, prov provider.Provider, f *cliFlags, prompt string,
+) ([]rag.Result, error) {
+	ix, err := rag.Load(f.ragPath)
+	if err != nil {
+		return nil, err
+	}
+	if ix.Provider != prov.Name() {
+		return nil, fmt.Errorf(
+			"index %s was built with provider '%s' but the current provider is '%s'",
+			f.ragPath, ix.Provider, prov.Name(),
+		)
+	}
+	emb, ok := prov.(provider.Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' does not support embeddings", prov.Name())
+	}
+	enforceEmbeddingPolicy(prov.Name(), ix.Model)
+	return ix.Query(ctx, emb, prompt, f.ragTopK)
+}
+
+// joinData appends an extra section to existing data, separated by a blank line.
+func joinData(data, extra string) string {
+	if extra == "" {
+		return data
+	}
+	if data == "" {
+		return extra
+	}
+	return data + "\n\n" + extra
+}
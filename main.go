@@ -3,36 +3,98 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strconv"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/heather7532/nuro/cache"
+	"github.com/heather7532/nuro/cassette"
 	"github.com/heather7532/nuro/config"
 	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/rag"
+	"github.com/heather7532/nuro/redact"
 	"github.com/heather7532/nuro/resolver"
 	"github.com/spf13/pflag"
 )
 
 type cliFlags struct {
-	promptFlag     string // value when provided as --prompt "..."
-	promptUseStdin bool   // true when --prompt-stdin is present
-	dataInline     string // --data "..."
-	dataFile       string // --data-file path
-	modelArg       string // -m / --model
-	maxTokens      int
-	temperature    float64
-	topP           float64
-	timeoutSec     int
-	stream         bool
-	jsonOut        bool
-	verbose        bool
-	showVersion    bool
-	force          bool   // -f / --force to override data size warnings
-	configName     string // --cfg to select a named configuration profile
+	promptFlag       string   // value when provided as --prompt "..."
+	promptUseStdin   bool     // true when --prompt-stdin is present
+	promptFile       string   // --prompt-file path
+	includedBytes    int      // bytes inlined into the prompt by @path references
+	allowIncludes    []string // --allow-include paths outside the working directory or hidden that @path may read
+	namedPrompt      bool     // the prompt comes from the library ("nuro run"); @path is not expanded
+	dataInline       string   // --data "..."
+	dataFiles        []string // --data-file paths, directories or globs (repeatable)
+	modelArg         string   // -m / --model
+	maxTokens        int
+	temperature      float64
+	topP             float64
+	timeoutSec       int
+	stream           bool
+	jsonOut          bool
+	verbose          bool
+	showVersion      bool
+	force            bool          // -f / --force to override data size warnings
+	noExtract        bool          // --no-extract sends data files and stdin without extracting text
+	redact           bool          // --redact replaces sensitive values with placeholders
+	redactRestore    bool          // --redact-restore puts the original values back into the answer
+	redactCheck      bool          // --redact-check shows what would be sent, without sending it
+	configName       string        // --cfg to select a named configuration profile
+	ragPath          string        // --rag directory (or index file) to retrieve context from
+	ragTopK          int           // --rag-top-k number of chunks to retrieve
+	checkModel       bool          // --check-model validates the model against the provider's list
+	keepAlive        string        // --keep-alive (Ollama) how long the model stays loaded
+	autoPull         bool          // --auto-pull (Ollama) pulls a missing model before the request
+	pullTimeout      time.Duration // --pull-timeout caps the --auto-pull download
+	system           string        // --system prompt
+	seed             int           // --seed (only sent when the flag or profile sets it)
+	stop             []string      // --stop sequences (repeatable)
+	numCtx           int           // --num-ctx (Ollama)
+	repeatPenalty    float64       // --repeat-penalty (Ollama)
+	topK             int           // --top-k (Ollama)
+	minP             float64       // --min-p (Ollama)
+	mirostat         int           // --mirostat (Ollama)
+	ollamaAPI        string        // --ollama-api generate|chat
+	reasoning        string        // --reasoning-effort (OpenAI reasoning models)
+	verbosity        string        // --verbosity (OpenAI gpt-5)
+	continueOnLength bool          // --continue-on-length stitches continuations of truncated output
+	maxContinuations int           // --max-continuations caps the continuation requests
+	output           string        // --output text|json|ndjson|yaml|markdown|raw
+	outFile          string        // -o / --out file written atomically
+	tee              bool          // --tee also writes the --out output to stdout
+	extract          string        // --extract code keeps only the fenced code blocks
+	render           string        // --render auto|always|never for terminal Markdown rendering
+	cache            bool          // --cache answers repeated requests from the response cache
+	noCache          bool          // --no-cache disables the cache even if the profile enables it
+	cacheOnly        bool          // --cache-only never calls the provider
+	cacheTTL         string        // --cache-ttl maximum age of cached responses
+	record           string        // --record dir saves provider HTTP traffic as a cassette
+	replay           string        // --replay dir answers provider requests from a cassette
+
+	fallbackSpecs   []string                // --fallback provider:model (repeatable)
+	fallback        []config.FallbackTarget // fallback chain from --fallback or the profile
+	fallbackOn      []string                // --fallback-on error classes
+	fallbackTimeout string                  // --fallback-timeout per-provider time limit
+
+	vars []string // --var name=value for the prompt of "nuro run" (repeatable)
+}
+
+// subcommands maps the first CLI argument to a handler that receives the remaining args.
+var subcommands = map[string]func(args []string){
+	"index":   runIndex,
+	"cache":   runCache,
+	"compare": runCompare,
+	"eval":    runEval,
+	"models":  runModels,
+	"ollama":  runOllama,
+	"prompts": runPrompts,
+	"run":     runNamedPrompt,
 }
 
 func parseFlags() (*cliFlags, error) {
@@ -46,8 +108,16 @@ func parseFlags() (*cliFlags, error) {
 		&f.promptUseStdin, "prompt-stdin", "s", false,
 		"Read prompt from stdin instead of using --prompt",
 	)
+	pflag.StringVar(&f.promptFile, "prompt-file", "", "Read the prompt from a file.")
+	pflag.StringArrayVar(
+		&f.allowIncludes, "allow-include", nil,
+		"File or directory outside the working directory, or hidden, that @path references may read (repeatable).",
+	)
 	pflag.StringVar(&f.dataInline, "data", "", "Inline data/payload string.")
-	pflag.StringVar(&f.dataFile, "data-file", "", "Path to file containing data/payload.")
+	pflag.StringArrayVar(
+		&f.dataFiles, "data-file", nil,
+		"File, directory or glob containing data/payload (repeatable; sources are labeled).",
+	)
 	pflag.StringVarP(
 		&f.modelArg, "model", "m", "", "Model id (or $ENV to read model id from env var).",
 	)
@@ -57,12 +127,115 @@ func parseFlags() (*cliFlags, error) {
 	pflag.Float64Var(&f.topP, "top-p", 1.0, "Top-p (nucleus sampling).")
 	pflag.IntVar(&f.timeoutSec, "timeout", 60, "Request timeout in seconds.")
 	pflag.BoolVar(&f.stream, "stream", false, "Stream tokens to stdout.")
-	pflag.BoolVar(&f.jsonOut, "json", false, "Emit structured JSON result.")
+	pflag.BoolVar(&f.jsonOut, "json", false, "Emit structured JSON result (same as --output json).")
+	pflag.StringVar(
+		&f.output, "output", outputText,
+		"Output format: text, json, ndjson (streamed delta/done/error events), yaml, markdown, or raw.",
+	)
+	pflag.StringVarP(
+		&f.outFile, "out", "o", "", "Write the output to this file (replaced atomically when complete).",
+	)
+	pflag.BoolVar(&f.tee, "tee", false, "With --out, also write the output to stdout.")
+	pflag.StringVar(
+		&f.render, "render", renderAuto,
+		"Render Markdown answers for the terminal: auto (when stdout is a TTY), always, or never.",
+	)
+	pflag.StringVar(
+		&f.extract, "extract", "", "Extract parts of the answer: 'code' keeps only fenced code blocks.",
+	)
 	pflag.BoolVar(&f.verbose, "verbose", false, "Verbose diagnostics to stderr.")
 	pflag.BoolVarP(&f.force, "force", "f", false, "Force sending large data without warnings.")
+	pflag.BoolVar(
+		&f.redact, "redact", false,
+		"Replace emails, IPs, JWTs, keys and .nuro redact patterns with placeholders before sending.",
+	)
+	pflag.BoolVar(
+		&f.redactRestore, "redact-restore", false, "Restore the redacted values in the answer (with --redact).",
+	)
+	pflag.BoolVar(
+		&f.redactCheck, "redact-check", false, "Show what would be sent with redaction applied, without sending.",
+	)
+	pflag.BoolVar(
+		&f.noExtract, "no-extract", false,
+		"Send --data-file and stdin as-is instead of extracting text from HTML, PDF, DOCX, archives, ...",
+	)
 	pflag.StringVarP(
 		&f.configName, "cfg", "c", "", "Use a named configuration profile from .nuro file",
 	)
+	pflag.StringVar(
+		&f.ragPath, "rag", "",
+		"Retrieve context from a directory indexed with 'nuro index build' and add it as data.",
+	)
+	pflag.IntVar(&f.ragTopK, "rag-top-k", rag.DefaultTopK, "Number of chunks to retrieve for --rag.")
+	pflag.BoolVar(
+		&f.checkModel, "check-model", false,
+		"Verify the model exists (via the provider's model list) before sending the request.",
+	)
+	pflag.StringVar(
+		&f.keepAlive, "keep-alive", "",
+		"Ollama: keep the model loaded for this long after the request (e.g. 10m, -1 for forever).",
+	)
+	pflag.BoolVar(
+		&f.autoPull, "auto-pull", false, "Ollama: pull the model first if it is not available locally.",
+	)
+	pflag.DurationVar(
+		&f.pullTimeout, "pull-timeout", 30*time.Minute, "Ollama: the longest an --auto-pull download may take.",
+	)
+	pflag.StringVar(&f.system, "system", "", "System prompt sent ahead of the user message.")
+	pflag.IntVar(&f.seed, "seed", 0, "Sampling seed for reproducible output.")
+	pflag.StringArrayVar(&f.stop, "stop", nil, "Stop sequence (repeatable).")
+	pflag.IntVar(&f.numCtx, "num-ctx", 0, "Ollama: context window size in tokens.")
+	pflag.Float64Var(&f.repeatPenalty, "repeat-penalty", 0, "Ollama: penalty for repeated tokens.")
+	pflag.IntVar(&f.topK, "top-k", 0, "Ollama: sample from the k most likely tokens.")
+	pflag.Float64Var(&f.minP, "min-p", 0, "Ollama: minimum token probability relative to the top token.")
+	pflag.IntVar(&f.mirostat, "mirostat", 0, "Ollama: Mirostat sampling (0=off, 1, 2).")
+	pflag.StringVar(
+		&f.ollamaAPI, "ollama-api", "generate", "Ollama: endpoint to use, generate or chat.",
+	)
+	pflag.StringVar(
+		&f.reasoning, "reasoning-effort", "",
+		"OpenAI gpt-5/o-series: reasoning effort (minimal, low, medium, high).",
+	)
+	pflag.StringVar(
+		&f.verbosity, "verbosity", "", "OpenAI gpt-5: answer verbosity (low, medium, high).",
+	)
+	pflag.BoolVar(
+		&f.continueOnLength, "continue-on-length", false,
+		"When output is truncated by max tokens, request continuations and stitch them together.",
+	)
+	pflag.IntVar(
+		&f.maxContinuations, "max-continuations", 5, "Maximum continuation requests for --continue-on-length.",
+	)
+	pflag.BoolVar(&f.cache, "cache", false, "Answer repeated requests from the on-disk response cache.")
+	pflag.BoolVar(&f.noCache, "no-cache", false, "Disable the response cache (overrides the profile).")
+	pflag.BoolVar(
+		&f.cacheOnly, "cache-only", false, "Only answer from the response cache; fail on a cache miss.",
+	)
+	pflag.StringVar(
+		&f.cacheTTL, "cache-ttl", "24h",
+		"Maximum age of cached responses (0 for no expiry).",
+	)
+	pflag.StringVar(
+		&f.record, "record", "", "Record provider HTTP traffic to this directory (API keys redacted).",
+	)
+	pflag.StringVar(
+		&f.replay, "replay", "", "Replay provider HTTP traffic recorded with --record; no network access.",
+	)
+	pflag.StringArrayVar(
+		&f.fallbackSpecs, "fallback", nil,
+		"Fallback provider:model tried when the provider fails (repeatable, in order).",
+	)
+	pflag.StringSliceVar(
+		&f.fallbackOn, "fallback-on", nil,
+		"Error classes that trigger a fallback: 5xx, 429, timeout, network (default all).",
+	)
+	pflag.StringVar(
+		&f.fallbackTimeout, "fallback-timeout", "",
+		"Time limit for each provider in the fallback chain (e.g. 20s), so timeouts can fall back.",
+	)
+	pflag.StringArrayVar(
+		&f.vars, "var", nil, "Set a variable of the prompt run with 'nuro run' (name=value, repeatable).",
+	)
 	// --help is auto-provided
 
 	pflag.Parse()
@@ -71,6 +244,73 @@ func parseFlags() (*cliFlags, error) {
 	if f.promptFlag != "" && f.promptUseStdin {
 		return nil, usageError("cannot use both --prompt and --prompt-stdin")
 	}
+	if f.promptFile != "" && (f.promptFlag != "" || f.promptUseStdin) {
+		return nil, usageError("cannot use --prompt-file with --prompt or --prompt-stdin")
+	}
+
+	if f.ollamaAPI != "generate" && f.ollamaAPI != "chat" {
+		return nil, usageError("--ollama-api must be 'generate' or 'chat'")
+	}
+
+	switch f.output {
+	case outputText:
+		if f.jsonOut {
+			f.output = outputJSON
+		}
+	case outputJSON:
+		f.jsonOut = true
+	case outputNDJSON:
+		if f.jsonOut {
+			return nil, usageError("cannot use --json with --output ndjson")
+		}
+		// NDJSON is a streaming format: deltas are emitted as they arrive
+		f.stream = true
+	case outputYAML, outputMarkdown, outputRaw:
+		if f.jsonOut {
+			return nil, usageError("cannot use --json with --output " + f.output)
+		}
+	default:
+		return nil, usageError("--output must be one of text, json, ndjson, yaml, markdown, raw")
+	}
+	if f.tee && (f.outFile == "" || f.outFile == "-") {
+		return nil, usageError("--tee requires --out")
+	}
+	if f.record != "" && f.replay != "" {
+		return nil, usageError("cannot use both --record and --replay")
+	}
+
+	if f.noCache && (f.cache || f.cacheOnly) {
+		return nil, usageError("cannot use --no-cache with --cache or --cache-only")
+	}
+
+	for _, spec := range f.fallbackSpecs {
+		t, err := config.ParseFallbackTarget(spec)
+		if err != nil {
+			return nil, usageError(err.Error())
+		}
+		f.fallback = append(f.fallback, t)
+	}
+	if err := validateFallbackOptions(&f); err != nil {
+		return nil, usageError(err.Error())
+	}
+
+	if f.render != renderAuto && f.render != renderAlways && f.render != renderNever {
+		return nil, usageError("--render must be auto, always, or never")
+	}
+	if f.extract != "" && f.extract != extractCode {
+		return nil, usageError("--extract must be 'code'")
+	}
+
+	switch f.reasoning {
+	case "", "minimal", "low", "medium", "high":
+	default:
+		return nil, usageError("--reasoning-effort must be one of minimal, low, medium, high")
+	}
+	switch f.verbosity {
+	case "", "low", "medium", "high":
+	default:
+		return nil, usageError("--verbosity must be one of low, medium, high")
+	}
 
 	// Disallow --data with no value (must be explicitly provided)
 	// pflag already errors when a string flag is used without a value,
@@ -83,6 +323,26 @@ func parseFlags() (*cliFlags, error) {
 }
 
 func main() {
+	// NURO_CASSETTE records provider traffic to a directory, or replays it once recorded
+	if dir := cliEnv.Env["NURO_CASSETTE"]; dir != "" {
+		if err := useCassette(dir, "", false); err != nil {
+			exitWithErr(err, 2)
+		}
+	}
+
+	// Subcommands (e.g. "nuro index build ./docs") are dispatched before flag parsing
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			run(os.Args[2:])
+			return
+		}
+	}
+	runCompletion()
+}
+
+// runCompletion sends the prompt and data given by the command line flags (or by the
+// library prompt of "nuro run") and writes the answer.
+func runCompletion() {
 	flags, err := parseFlags()
 	if err != nil {
 		exitWithErr(err, 2)
@@ -93,44 +353,35 @@ func main() {
 		fmt.Println(version)
 		return
 	}
-	// Load .nuro config file if present
-	cfg, err := config.LoadConfig()
+
+	switch {
+	case flags.record != "":
+		err = useCassette(flags.record, cassette.ModeRecord, flags.verbose)
+	case flags.replay != "":
+		err = useCassette(flags.replay, cassette.ModeReplay, flags.verbose)
+	}
 	if err != nil {
-		exitWithErr(err, 2) // Exit code 2 for config loading error
+		exitWithErr(err, 2)
 	}
 
-	// If the user explicitly requested a named profile but no .nuro was found,
-	// fail early instead of falling back to environment variable discovery.
-	if flags.configName != "" && cfg == nil {
-		exitWithErr(
-			fmt.Errorf(
-				".nuro config not found but --cfg '%s' was specified", flags.configName,
-			), 2,
-		)
+	sink, err := newOutputSink(flags)
+	if err != nil {
+		exitWithErr(err, 2)
 	}
+	activeSink = sink
 
-	// Apply the appropriate profile based on CLI flag or config default
-	if cfg != nil {
-		if err := cfg.Validate(); err != nil {
-			exitWithErr(fmt.Errorf("invalid .nuro config: %w", err), 2)
+	if namedPrompt != nil {
+		if err := applyNamedPrompt(flags, namedPrompt); err != nil {
+			exitWithErr(err, 2)
 		}
-		if flags.configName != "" {
-			// Use the profile specified by the --cfg flag
-			if err := cfg.ApplyProfile(flags.configName); err != nil {
-				exitWithErr(
-					fmt.Errorf(
-						"failed to apply .nuro config profile '%s': %w", flags.configName, err,
-					), 2,
-				)
-			}
-		} else {
-			// Use the default profile (or first profile)
-			if err := cfg.Apply(); err != nil {
-				exitWithErr(fmt.Errorf("failed to apply .nuro config: %w", err), 2)
-			}
-		}
-	} else {
-		// No config file
+	} else if len(flags.vars) > 0 {
+		exitWithErr(usageError("--var is only used with 'nuro run'"), 2)
+	}
+
+	// Load .nuro config file if present and apply the selected profile
+	profile, settings, err := loadSettings(flags.configName)
+	if err != nil {
+		exitWithErr(err, 2)
 	}
 
 	// Resolve prompt & data per rules
@@ -139,44 +390,41 @@ func main() {
 		exitWithErr(err, 2)
 	}
 
+	if flags.ragPath != "" && strings.TrimSpace(prompt) == "" {
+		exitWithErr(usageError("--rag requires a prompt to search the index with"), 2)
+	}
+
 	// Validate data size and warn about potential costs
-	if err := validateDataSize(data, flags.force, flags.verbose); err != nil {
+	if err := validateDataSize(data, flags.includedBytes, flags.force, flags.verbose); err != nil {
 		exitWithErr(err, 2)
 	}
-
-	// Build the combined message content for verbose output
-	combinedContent := buildCombinedContent(prompt, data)
+	dataSize := len(data) + flags.includedBytes
 
 	// Discover provider/model from env/args (no MCP in v1)
-	res, err := resolver.ResolveProviderAndModel(flags.modelArg)
+	res, err := resolver.ResolveProviderAndModel(settings, flags.modelArg)
 	if err != nil {
 		exitWithErr(err, 3)
 	}
 
-	// If a .nuro profile was applied it sets NURO_* env vars. Follow your requested
-	// precedence: profile values should take precedence over flags for numeric
-	// parameters (max_tokens, temperature, top_p). If NURO_* vars are present,
-	// override the parsed flag values.
-	argsSource := "flags"
-	if v := os.Getenv("NURO_MAX_TOKENS"); v != "" {
-		if i, e := strconv.Atoi(v); e == nil {
-			flags.maxTokens = i
-			argsSource = "NURO_PROFILE"
-		}
+	// Fill the parameters not given as flags from the profile and the environment
+	params, err := resolveParams(flags, pflag.CommandLine, profile, cliEnv.Env)
+	if err != nil {
+		exitWithErr(err, 2)
 	}
-	if v := os.Getenv("NURO_TEMPERATURE"); v != "" {
-		if f, e := strconv.ParseFloat(v, 64); e == nil {
-			flags.temperature = f
-			argsSource = "NURO_PROFILE"
-		}
+
+	// Replace sensitive values before anything leaves the machine
+	redactor, err := newRedactor(flags)
+	if err != nil {
+		exitWithErr(err, 2)
 	}
-	if v := os.Getenv("NURO_TOP_P"); v != "" {
-		if f, e := strconv.ParseFloat(v, 64); e == nil {
-			flags.topP = f
-			argsSource = "NURO_PROFILE"
-		}
+	if redactor != nil {
+		prompt, data = redactor.Redact(prompt), redactor.Redact(data)
+		flags.system = redactor.Redact(flags.system)
 	}
 
+	// Build the combined message content for verbose output
+	combinedContent := buildCombinedContent(prompt, data)
+
 	if flags.verbose || (pflag.CommandLine.Changed("model") && !flags.jsonOut) {
 		keyDisplay := redactKey(res.APIKey)
 		_, _ = fmt.Fprintf(
@@ -187,9 +435,12 @@ func main() {
 		if flags.verbose {
 			_, _ = fmt.Fprintf(
 				os.Stderr,
-				"nuro: args max_tokens=%d temp=%.2f top_p=%.2f timeout=%ds stream=%t json=%t source=%s\n",
+				"nuro: args max_tokens=%d temp=%.2f top_p=%.2f timeout=%ds stream=%t output=%s\n",
 				flags.maxTokens, flags.temperature, flags.topP, flags.timeoutSec, flags.stream,
-				flags.jsonOut, argsSource,
+				flags.output,
+			)
+			printProvenance(
+				os.Stderr, append(targetProvenance(flags, pflag.CommandLine, profile, cliEnv, res), params...),
 			)
 			_, _ = fmt.Fprintf(
 				os.Stderr, "nuro: prompt_len=%d data_len=%d\n", len(prompt), len(data),
@@ -201,16 +452,63 @@ func main() {
 	}
 
 	// Build request
-	args := provider.CompletionArgs{
-		Model:       res.Model,
-		Prompt:      prompt,
-		Data:        data,
-		MaxTokens:   flags.maxTokens,
-		Temperature: flags.temperature,
-		TopP:        flags.topP,
-		JSONOut:     flags.jsonOut,
-		Stream:      flags.stream,
-		Timeout:     time.Duration(flags.timeoutSec) * time.Second,
+	args := completionArgs(flags, params)
+	args.Model, args.Prompt, args.Data = res.Model, prompt, data
+	args.JSONOut, args.Stream = flags.jsonOut, flags.stream
+
+	if flags.redactCheck {
+		// Nothing is sent, so an --out file is left as it was
+		sink.abort()
+		printRedactCheck(redactor, flags.system, combinedContent, flags.ragPath != "")
+		return
+	}
+
+	// Enforce the .nuro policies on where the request goes and what it carries
+	enforceTargetPolicy(res)
+	if src := redactionRequiredBy(); src != "" && redactor == nil {
+		exitWithErr(fmt.Errorf("redaction is required by the policy in %s", src), exitPolicy)
+	}
+	enforceDataPolicy(dataSize, flags.system, combinedContent)
+
+	// Build provider instance
+	prov, err := provider.BuildProvider(res)
+	if err != nil {
+		exitWithErr(err, 3)
+	}
+
+	// Requests are sent through llm, which falls back to other providers and answers
+	// repeated requests from the response cache when configured; prov stays the bare
+	// provider for model lookups.
+	llm := prov
+	fallback, err := newFallbackProvider(settings, prov, res.Model, flags)
+	if err != nil {
+		exitWithErr(err, 3)
+	}
+	if fallback != nil {
+		llm = fallback
+	}
+	var cached *cache.Provider
+	if flags.cache || flags.cacheOnly {
+		cached, err = newCacheProvider(llm, res.BaseURL, flags.cacheTTL, flags.cacheOnly)
+		if err != nil {
+			exitWithErr(err, 2)
+		}
+		llm = cached
+	}
+
+	// A pull can take far longer than a request, so it runs before the request timeout
+	// starts, under its own limit; Ctrl-C cancels it. An answer from the cache needs no
+	// model (with --rag the data, and so the cache key, is only known after retrieval).
+	fromCache := flags.cacheOnly || (cached != nil && flags.ragPath == "" && cached.Has(args))
+	if flags.autoPull && !fromCache {
+		pullCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		pullCtx, cancelPull := context.WithTimeout(pullCtx, flags.pullTimeout)
+		err := autoPull(pullCtx, prov, res.Model, os.Stderr)
+		cancelPull()
+		stop()
+		if err != nil {
+			exitWithErr(err, 4)
+		}
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), args.Timeout)
@@ -220,80 +518,241 @@ func main() {
 	// emit endpoint-level diagnostics (e.g., which OpenAI endpoint was used).
 	ctx = context.WithValue(ctx, "nuro_verbose", flags.verbose)
 
-	// Build provider instance
-	prov, err := provider.BuildProvider(res)
-	if err != nil {
-		exitWithErr(err, 3)
+	if flags.checkModel {
+		notFound, err := checkModel(ctx, prov, res.Model)
+		if err != nil {
+			exitWithErr(err, 4)
+		}
+		if notFound != nil {
+			exitWithErr(notFound, 3)
+		}
+	}
+
+	// Retrieve context chunks from a local index and append them to the data
+	var sources []string
+	if flags.ragPath != "" {
+		results, err := retrieveContext(ctx, prov, flags, prompt)
+		if err != nil {
+			exitWithErr(err, 4)
+		}
+		for _, r := range results {
+			sources = append(sources, r.Citation())
+		}
+		if flags.verbose {
+			_, _ = fmt.Fprintf(
+				os.Stderr, "nuro: rag retrieved %d chunks: %s\n", len(results),
+				strings.Join(sources, ", "),
+			)
+		}
+		retrieved := rag.FormatContext(results)
+		if redactor != nil {
+			retrieved = redactor.Redact(retrieved)
+		}
+		enforceDataPolicy(0, retrieved)
+		args.Data = joinData(args.Data, retrieved)
+	}
+	if redactor != nil && flags.verbose {
+		_, _ = fmt.Fprintf(os.Stderr, "nuro: redacted %d values: %s\n", redactor.Total(), redactor.Summary())
+	}
+
+	maxContinuations := 0
+	if flags.continueOnLength {
+		maxContinuations = flags.maxContinuations
 	}
 
 	if flags.stream {
 		// Streaming path
-		total, usage, err := prov.Stream(
-			ctx, args, func(delta string) {
-				_, _ = fmt.Fprint(os.Stdout, delta)
-			},
+		delta := sink.delta
+		var restorer *redact.Restorer
+		if redactor != nil && flags.redactRestore {
+			restorer = redactor.NewRestorer(sink.delta)
+			delta = restorer.Write
+		}
+		total, usage, finish, err := complete(
+			ctx, llm, args, delta, maxContinuations, flags.verbose,
 		)
+		if restorer != nil {
+			restorer.Flush()
+			total = redactor.Restore(total)
+		}
+		reportCache(cached, flags.verbose)
+		answer := answeredBy(prov, res.Model, fallback)
 		if err != nil {
+			if errors.Is(err, cache.ErrMiss) {
+				exitWithErr(err, 3)
+			}
+			if e := explainNotFound(answer.Provider, answer.Model, err); e != nil {
+				exitWithErr(e, 3)
+			}
 			exitWithErr(err, 4)
 		}
 
 		if flags.verbose {
 			_, _ = fmt.Fprintf(
 				os.Stderr,
-				"nuro: stream response total_len=%d prompt_tokens=%d completion_tokens=%d total_tokens=%d\n",
-				len(total), usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens,
+				"nuro: stream response total_len=%d prompt_tokens=%d completion_tokens=%d total_tokens=%d finish_reason=%s\n",
+				len(total), usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens, finish,
 			)
+			printTiming(usage)
+			reportFallback(fallback)
 		}
-		if flags.jsonOut {
-			out := provider.JSONResult{
-				Provider: prov.Name(),
-				Model:    res.Model,
-				Usage:    usage,
-				Text:     total,
-			}
-			_, _ = fmt.Fprintln(os.Stdout)
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			_ = enc.Encode(out)
+		out := provider.JSONResult{
+			Provider:     answer.Provider.Name(),
+			Model:        answer.Model,
+			Usage:        usage,
+			Text:         total,
+			FinishReason: finish,
+			Sources:      sources,
+			Cached:       cached != nil && cached.Cached(),
+			Fallback:     fallbackSkipped(fallback),
+		}
+		if err := sink.finish(out, true); err != nil {
+			exitWithErr(err, 2)
 		}
+		exitOnFinish(finish)
+		checkPromptSchema(total)
 		return
 	}
 
 	// Non-streaming
-	text, usage, err := prov.Complete(ctx, args)
+	text, usage, finish, err := complete(ctx, llm, args, nil, maxContinuations, flags.verbose)
+	if redactor != nil && flags.redactRestore {
+		text = redactor.Restore(text)
+	}
+	reportCache(cached, flags.verbose)
+	answer := answeredBy(prov, res.Model, fallback)
 	if err != nil {
+		if errors.Is(err, cache.ErrMiss) {
+			exitWithErr(err, 3)
+		}
+		if e := explainNotFound(answer.Provider, answer.Model, err); e != nil {
+			exitWithErr(e, 3)
+		}
 		exitWithErr(err, 4)
 	}
 
 	if flags.verbose {
 		_, _ = fmt.Fprintf(
 			os.Stderr,
-			"nuro: response text_len=%d prompt_tokens=%d completion_tokens=%d total_tokens=%d\n",
-			len(text), usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens,
+			"nuro: response text_len=%d prompt_tokens=%d completion_tokens=%d total_tokens=%d finish_reason=%s\n",
+			len(text), usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens, finish,
 		)
+		printTiming(usage)
+		reportFallback(fallback)
+	}
+	out := provider.JSONResult{
+		Provider:     answer.Provider.Name(),
+		Model:        answer.Model,
+		Usage:        usage,
+		Text:         text,
+		FinishReason: finish,
+		Sources:      sources,
+		Cached:       cached != nil && cached.Cached(),
+		Fallback:     fallbackSkipped(fallback),
+	}
+	if err := sink.finish(out, false); err != nil {
+		exitWithErr(err, 2)
 	}
-	if flags.jsonOut {
-		out := provider.JSONResult{
-			Provider: prov.Name(),
-			Model:    res.Model,
-			Usage:    usage,
-			Text:     text,
+	exitOnFinish(finish)
+	checkPromptSchema(text)
+}
+
+// loadSettings loads the .nuro config (if any) and returns the named profile, or the
+// default profile when name is empty, with the resolver settings it gives over the
+// environment.
+func loadSettings(name string) (*appliedProfile, resolver.Settings, error) {
+	prof, err := loadProfile(name)
+	if err != nil {
+		return nil, resolver.Settings{}, err
+	}
+	return prof, resolverSettings(prof, cliEnv), nil
+}
+
+// loadProfile loads the .nuro config (if any) and returns the named profile, or the
+// default profile when name is empty; it returns nil when there is no profile.
+func loadProfile(name string) (*appliedProfile, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// If the user explicitly requested a named profile but no .nuro was found,
+	// fail early instead of falling back to environment variable discovery.
+	if name != "" && cfg == nil {
+		return nil, fmt.Errorf(".nuro config not found but --cfg '%s' was specified", name)
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid .nuro config: %w", err)
+	}
+	if name != "" {
+		// Use the profile specified by the --cfg flag
+		p, err := cfg.GetProfile(name, cliEnv.Env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply .nuro config profile '%s': %w", name, err)
 		}
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		_ = enc.Encode(out)
-	} else {
-		_, _ = fmt.Fprintln(os.Stdout, text)
+		return &appliedProfile{Profile: p, name: name, selected: true}, nil
+	}
+	if cfg.Profiles == nil {
+		return nil, nil
 	}
+	// Use the default profile (or first profile)
+	name = cfg.DefaultProfileName()
+	p, err := cfg.GetProfile(name, cliEnv.Env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply .nuro config: %w", err)
+	}
+	return &appliedProfile{Profile: p, name: name}, nil
+}
+
+// printTiming reports provider-side timings (Ollama) when available
+func printTiming(u provider.Usage) {
+	if u.TotalDuration == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(
+		os.Stderr,
+		"nuro: timing total=%s load=%s prompt_eval=%s eval=%s tokens_per_sec=%.1f\n",
+		u.TotalDuration.Round(time.Millisecond), u.LoadDuration.Round(time.Millisecond),
+		u.PromptEvalDuration.Round(time.Millisecond), u.EvalDuration.Round(time.Millisecond),
+		u.TokensPerSecond(),
+	)
 }
 
 func exitWithErr(err error, code int) {
+	if activeSink != nil {
+		activeSink.fail(err, code)
+		activeSink = nil
+	}
 	_, _ = fmt.Fprintf(os.Stderr, "nuro: %v\n", err)
 	os.Exit(code)
 }
 
 func usageError(msg string) error { return fmt.Errorf("usage error: %s", msg) }
 
+// parseSubcommand parses the flags of a subcommand. Like the root command it exits 0
+// after printing the usage for -h/--help, and 2 on any other flag error.
+func parseSubcommand(fs *pflag.FlagSet, args []string) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			os.Exit(0)
+		}
+		exitWithErr(usageError(err.Error()), 2)
+	}
+}
+
+// exitOnHelp prints usage and exits 0 when arg asks for help, for subcommands that take
+// an action or name before their flags.
+func exitOnHelp(arg, usage string) {
+	if arg == "-h" || arg == "--help" {
+		_, _ = fmt.Fprintln(os.Stderr, usage)
+		os.Exit(0)
+	}
+}
+
 func resolvePromptAndData(f *cliFlags) (prompt string, data string, err error) {
 	stdinData, stdinPresent, err := readMaybeStdin()
 	if err != nil {
@@ -307,6 +766,12 @@ func resolvePromptAndData(f *cliFlags) (prompt string, data string, err error) {
 			return "", "", usageError("'-s' or '-p' with no value used but no prompt on stdin")
 		}
 		prompt = string(stdinData)
+	case f.promptFile != "":
+		b, e := os.ReadFile(f.promptFile)
+		if e != nil {
+			return "", "", fmt.Errorf("failed to read --prompt-file: %w", e)
+		}
+		prompt = string(b)
 	case f.promptFlag != "":
 		prompt = f.promptFlag
 	default:
@@ -314,25 +779,37 @@ func resolvePromptAndData(f *cliFlags) (prompt string, data string, err error) {
 		// It's fine to send only data with an instruction-like prompt in data, but users generally pass prompt.
 	}
 
-	// Determine data
-	if f.dataFile != "" && f.dataInline != "" {
-		return "", "", usageError("cannot use both --data and --data-file")
+	// Inline the files named by @path references, only in prompt text the user wrote: a
+	// prompt piped on stdin or taken from the library may come from anywhere
+	if !f.promptUseStdin && !f.namedPrompt {
+		prompt, f.includedBytes, err = expandIncludes(prompt, f.allowIncludes, f.verbose)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
+	// Determine data: --data, each --data-file match and stdin (when the prompt didn't
+	// consume it) are combined, labeled by name when there is more than one
+	var sources []dataSource
 	if f.dataInline != "" {
-		data = f.dataInline
-	} else if f.dataFile != "" {
-		b, e := os.ReadFile(f.dataFile)
-		if e != nil {
-			return "", "", fmt.Errorf("failed to read --data-file: %w", e)
-		}
-		data = string(b)
-	} else {
-		// Default stdin->data if stdin present AND prompt didn't consume stdin
-		if stdinPresent && !f.promptUseStdin {
-			data = string(stdinData)
+		sources = append(sources, dataSource{name: "data", content: f.dataInline})
+	}
+	files, err := readDataFiles(f.dataFiles, f.noExtract)
+	if err != nil {
+		return "", "", err
+	}
+	sources = append(sources, files...)
+	if stdinPresent && !f.promptUseStdin {
+		// Default stdin->data; alongside other sources only when it has content
+		if len(sources) == 0 || strings.TrimSpace(string(stdinData)) != "" {
+			found, err := extractSources("stdin", stdinData, f.noExtract)
+			if err != nil {
+				return "", "", err
+			}
+			sources = append(sources, found...)
 		}
 	}
+	data = assembleData(sources, f.verbose)
 
 	// Conflict: both prompt and data attempt stdin? Covered above because promptUseStdin "consumed" stdin already.
 
@@ -359,10 +836,13 @@ func readMaybeStdin() ([]byte, bool, error) {
 }
 
 func redactKey(key string) string {
+	if key == "" {
+		return "none" // keyless providers (ollama, mock)
+	}
 	if len(key) <= 14 {
 		// Short key, just show first few chars
 		if len(key) <= 6 {
-			return key[:2] + "***"
+			return key[:min(2, len(key))] + "***"
 		}
 		return key[:4] + "***"
 	}
@@ -393,17 +873,19 @@ const (
 	dataSizeErrorThreshold   = 500 * 1024 // 500KB - error threshold (requires --force)
 )
 
-// validateDataSize checks if data is too large and provides warnings
-func validateDataSize(data string, force, verbose bool) error {
-	if data == "" {
+// validateDataSize checks if data, plus the bytes included into the prompt from files,
+// is too large and provides warnings
+func validateDataSize(data string, included int, force, verbose bool) error {
+	if data == "" && included == 0 {
 		return nil // No data, no issue
 	}
 
-	dataSize := len([]byte(data))
+	dataSize := len([]byte(data)) + included
 
 	if verbose {
 		_, _ = fmt.Fprintf(
-			os.Stderr, "nuro: data size=%s (%d bytes)\n", formatBytes(dataSize), dataSize,
+			os.Stderr, "nuro: data size=%s (%d bytes, %d included from files)\n", formatBytes(dataSize),
+			dataSize, included,
 		)
 	}
 
@@ -54,7 +54,7 @@ func TestResolvePromptAndDataStdinPromptWithInlineData(t *testing.T) {
 func TestValidateDataSizeWithSmallData(t *testing.T) {
 	// Test with small data (should not trigger any warnings)
 	smallData := "hello world"
-	err := validateDataSize(smallData, false, false)
+	err := validateDataSize(smallData, 0, false, false)
 	if err != nil {
 		t.Errorf("Expected no error for small data, got: %v", err)
 	}
@@ -63,7 +63,7 @@ func TestValidateDataSizeWithSmallData(t *testing.T) {
 func TestValidateDataSizeWithMediumData(t *testing.T) {
 	// Test with medium data (should trigger warning but not error)
 	mediumData := strings.Repeat("a", 60*1024) // 60KB - above warning threshold
-	err := validateDataSize(mediumData, false, false)
+	err := validateDataSize(mediumData, 0, false, false)
 	if err != nil {
 		t.Errorf("Expected no error for medium data, got: %v", err)
 	}
@@ -72,7 +72,7 @@ func TestValidateDataSizeWithMediumData(t *testing.T) {
 func TestValidateDataSizeWithLargeDataNoForce(t *testing.T) {
 	// Test with large data without --force (should error)
 	largeData := strings.Repeat("a", 600*1024) // 600KB - above error threshold
-	err := validateDataSize(largeData, false, false)
+	err := validateDataSize(largeData, 0, false, false)
 	if err == nil {
 		t.Error("Expected error for large data without --force")
 		return
@@ -85,7 +85,7 @@ func TestValidateDataSizeWithLargeDataNoForce(t *testing.T) {
 func TestValidateDataSizeWithLargeDataWithForce(t *testing.T) {
 	// Test with large data with --force (should not error)
 	largeData := strings.Repeat("a", 600*1024) // 600KB - above error threshold
-	err := validateDataSize(largeData, true, false)
+	err := validateDataSize(largeData, 0, true, false)
 	if err != nil {
 		t.Errorf("Expected no error for large data with --force, got: %v", err)
 	}
@@ -93,7 +93,7 @@ func TestValidateDataSizeWithLargeDataWithForce(t *testing.T) {
 
 func TestValidateDataSizeWithEmptyData(t *testing.T) {
 	// Test with empty data (should not trigger any warnings)
-	err := validateDataSize("", false, false)
+	err := validateDataSize("", 0, false, false)
 	if err != nil {
 		t.Errorf("Expected no error for empty data, got: %v", err)
 	}
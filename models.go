@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+// runModels implements "nuro models": list the models offered by the resolved provider.
+func runModels(args []string) {
+	fs := pflag.NewFlagSet("models", pflag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "Emit the model list as JSON.")
+	configName := fs.StringP("cfg", "c", "", "Use a named configuration profile from .nuro file")
+	modelArg := fs.StringP("model", "m", "", "Model id used to pick the provider (or $ENV).")
+	timeoutSec := fs.Int("timeout", 30, "Request timeout in seconds.")
+	parseSubcommand(fs, args)
+
+	_, settings, err := loadSettings(*configName)
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	res, err := resolver.ResolveProviderAndModel(settings, *modelArg)
+	if err != nil {
+		exitWithErr(err, 3)
+	}
+	prov, err := provider.BuildProvider(res)
+	if err != nil {
+		exitWithErr(err, 3)
+	}
+	lister, ok := prov.(provider.ModelLister)
+	if !ok {
+		exitWithErr(fmt.Errorf("provider '%s' does not support listing models", prov.Name()), 3)
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Duration(*timeoutSec)*time.Second,
+	)
+	defer cancel()
+
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		exitWithErr(err, 4)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+
+	if *jsonOut {
+		out := struct {
+			Provider string               `json:"provider"`
+			Models   []provider.ModelInfo `json:"models"`
+		}{Provider: prov.Name(), Models: models}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(out)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "MODEL\tENDPOINT\tOWNER\tSIZE\tDETAILS")
+	for _, m := range models {
+		size := ""
+		if m.Size > 0 {
+			size = formatBytes(int(m.Size))
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.ID, m.Endpoint, m.OwnedBy, size, m.Details)
+	}
+	_ = tw.Flush()
+}
+
+// checkModel validates model against the provider's model list. notFound describes an
+// unknown model (with close matches); err reports a failure to fetch the list.
+// Providers that cannot list models are assumed to accept any model.
+func checkModel(ctx context.Context, prov provider.Provider, model string) (
+	notFound error, err error,
+) {
+	lister, ok := prov.(provider.ModelLister)
+	if !ok {
+		return nil, nil
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(models))
+	for _, m := range models {
+		ids = append(ids, m.ID)
+	}
+	return resolver.ValidateModel(model, ids), nil
+}
+
+// explainNotFound turns a provider 404 into a "did you mean" error when the model is
+// unknown. It returns nil when the 404 cannot be attributed to the model.
+func explainNotFound(prov provider.Provider, model string, err error) error {
+	if !provider.IsNotFound(err) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	notFound, _ := checkModel(ctx, prov, model)
+	return notFound
+}
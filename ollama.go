@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+const ollamaUsage = `usage: nuro ollama <pull|show|ps|rm|unload> [model] [--host url] [--json]`
+
+// runOllama implements "nuro ollama <pull|show|ps|rm|unload>" against the native Ollama API.
+func runOllama(args []string) {
+	if len(args) == 0 {
+		exitWithErr(usageError(ollamaUsage), 2)
+	}
+	exitOnHelp(args[0], ollamaUsage)
+	action := args[0]
+
+	fs := pflag.NewFlagSet("ollama "+action, pflag.ContinueOnError)
+	host := fs.String("host", "", "Ollama base URL (default: profile base_url, $OLLAMA_HOST, or localhost).")
+	configName := fs.StringP("cfg", "c", "", "Use a named configuration profile from .nuro file")
+	jsonOut := fs.Bool("json", false, "Emit JSON instead of a table (show, ps).")
+	timeoutSec := fs.Int("timeout", 0, "Timeout in seconds (default: none for pull, 30 otherwise).")
+	parseSubcommand(fs, args[1:])
+
+	needsModel := action != "ps"
+	if needsModel && fs.NArg() != 1 {
+		exitWithErr(usageError(ollamaUsage), 2)
+	}
+	model := fs.Arg(0)
+
+	_, settings, err := loadSettings(*configName)
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	admin := provider.NewOllamaProvider(ollamaBaseURL(settings, *host)).(provider.OllamaAdmin)
+
+	timeout := time.Duration(*timeoutSec) * time.Second
+	if timeout == 0 && action != "pull" {
+		timeout = 30 * time.Second
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	switch action {
+	case "pull":
+		if err := admin.Pull(ctx, model, pullProgressPrinter(os.Stderr)); err != nil {
+			exitWithErr(err, 4)
+		}
+	case "show":
+		d, err := admin.Show(ctx, model)
+		if err != nil {
+			exitWithErr(err, 4)
+		}
+		if *jsonOut {
+			writeJSON(d)
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "model\t%s\n", model)
+		_, _ = fmt.Fprintf(tw, "family\t%s\n", d.Details.Family)
+		_, _ = fmt.Fprintf(tw, "parameters\t%s\n", d.Details.ParameterSize)
+		_, _ = fmt.Fprintf(tw, "quantization\t%s\n", d.Details.QuantizationLevel)
+		if n := d.ContextLength(); n > 0 {
+			_, _ = fmt.Fprintf(tw, "context length\t%d\n", n)
+		}
+		if len(d.Capabilities) > 0 {
+			_, _ = fmt.Fprintf(tw, "capabilities\t%s\n", strings.Join(d.Capabilities, ", "))
+		}
+		for _, line := range strings.Split(strings.TrimSpace(d.Parameters), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				_, _ = fmt.Fprintf(tw, "param\t%s\n", strings.Join(strings.Fields(line), " "))
+			}
+		}
+		_ = tw.Flush()
+	case "ps":
+		models, err := admin.Running(ctx)
+		if err != nil {
+			exitWithErr(err, 4)
+		}
+		if *jsonOut {
+			writeJSON(models)
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "MODEL\tSIZE\tVRAM\tUNTIL")
+		for _, m := range models {
+			_, _ = fmt.Fprintf(
+				tw, "%s\t%s\t%s\t%s\n", m.Name, formatBytes(int(m.Size)),
+				formatBytes(int(m.SizeVRAM)), m.ExpiresAt.Local().Format(time.DateTime),
+			)
+		}
+		_ = tw.Flush()
+	case "rm":
+		if err := admin.Delete(ctx, model); err != nil {
+			exitWithErr(err, 4)
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "nuro: deleted %s\n", model)
+	case "unload":
+		if err := admin.Unload(ctx, model); err != nil {
+			exitWithErr(err, 4)
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "nuro: unloaded %s\n", model)
+	default:
+		exitWithErr(usageError(ollamaUsage), 2)
+	}
+}
+
+// ollamaBaseURL picks the Ollama server: --host, then settings selecting the ollama
+// provider (NURO_BASE_URL), then $OLLAMA_HOST, then the default localhost URL.
+func ollamaBaseURL(s resolver.Settings, host string) string {
+	if host != "" {
+		return host
+	}
+	if strings.EqualFold(s.Provider, "ollama") && s.BaseURL != "" {
+		return s.BaseURL
+	}
+	if h := s.Env["OLLAMA_HOST"]; h != "" {
+		if !strings.Contains(h, "://") {
+			h = "http://" + h
+		}
+		return h
+	}
+	return ""
+}
+
+// autoPull pulls model, reporting progress to w, when prov is an Ollama server that
+// does not have it. It runs before the request timeout starts, since a pull can take
+// far longer than a request; ctx bounds it instead.
+func autoPull(ctx context.Context, prov provider.Provider, model string, w io.Writer) error {
+	admin, ok := prov.(provider.OllamaAdmin)
+	if !ok {
+		return nil
+	}
+	_, err := admin.Show(ctx, model)
+	if err == nil || !provider.IsNotFound(err) {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "nuro: ollama: model %s not found locally, pulling\n", model)
+	err = admin.Pull(ctx, model, pullProgressPrinter(w))
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf(
+			"ollama: pulling %s took longer than --pull-timeout; run 'nuro ollama pull %s' first", model, model,
+		)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("ollama: pulling %s was interrupted", model)
+	}
+	return err
+}
+
+// pullProgressPrinter returns a progress callback that renders pull status lines to w.
+// Byte progress for the same layer is rewritten in place with a carriage return.
+func pullProgressPrinter(w io.Writer) func(provider.PullProgress) {
+	var lastStatus string
+	var inPlace bool
+	return func(pp provider.PullProgress) {
+		if inPlace && pp.Status != lastStatus {
+			_, _ = fmt.Fprintln(w)
+			inPlace = false
+		}
+		if pp.Total > 0 {
+			pct := float64(pp.Completed) * 100 / float64(pp.Total)
+			_, _ = fmt.Fprintf(
+				w, "\rnuro: %s %5.1f%% (%s/%s)", pp.Status, pct, formatBytes(int(pp.Completed)),
+				formatBytes(int(pp.Total)),
+			)
+			inPlace = true
+			lastStatus = pp.Status
+			return
+		}
+		if pp.Status == lastStatus {
+			return
+		}
+		_, _ = fmt.Fprintf(w, "nuro: %s\n", pp.Status)
+		lastStatus = pp.Status
+	}
+}
+
+func writeJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
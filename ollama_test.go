@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+func TestAutoPullMissingModel(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	pulled := false
+
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, r.URL.Path)
+				switch r.URL.Path {
+				case "/api/show":
+					if !pulled {
+						http.Error(w, `{"error":"model 'tiny' not found"}`, http.StatusNotFound)
+						return
+					}
+					_, _ = w.Write([]byte(`{}`))
+				case "/api/pull":
+					pulled = true
+					_, _ = w.Write([]byte("{\"status\":\"pulling manifest\"}\n{\"status\":\"pulling abc\",\"total\":10,\"completed\":10}\n{\"status\":\"success\"}\n"))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	defer srv.Close()
+
+	var progress bytes.Buffer
+	prov := provider.NewOllamaProvider(srv.URL)
+	for range 2 {
+		if err := autoPull(context.Background(), prov, "tiny", &progress); err != nil {
+			t.Fatalf("autoPull: %v", err)
+		}
+	}
+	expected := []string{"/api/show", "/api/pull", "/api/show"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+	if !strings.Contains(progress.String(), "model tiny not found locally, pulling\nnuro: pulling manifest\n") {
+		t.Errorf("expected pull progress, got %q", progress.String())
+	}
+
+	mock, err := provider.NewMockProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := autoPull(context.Background(), mock, "tiny", &progress); err != nil {
+		t.Errorf("expected other providers to be left alone, got %v", err)
+	}
+}
+
+func TestAutoPullTimeout(t *testing.T) {
+	// The registry stalls after the first progress line
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/show" {
+					http.Error(w, `{"error":"model 'tiny' not found"}`, http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write([]byte("{\"status\":\"pulling manifest\"}\n"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			},
+		),
+	)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := autoPull(ctx, provider.NewOllamaProvider(srv.URL), "tiny", io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "took longer than --pull-timeout") {
+		t.Errorf("expected a pull timeout, got %v", err)
+	}
+}
+
+func TestPullProgressPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	pr := pullProgressPrinter(&buf)
+	pr(provider.PullProgress{Status: "pulling manifest"})
+	pr(provider.PullProgress{Status: "pulling abc", Total: 2048, Completed: 1024})
+	pr(provider.PullProgress{Status: "pulling abc", Total: 2048, Completed: 2048})
+	pr(provider.PullProgress{Status: "success"})
+
+	out := buf.String()
+	if !strings.Contains(out, "nuro: pulling manifest\n") {
+		t.Errorf("missing manifest line: %q", out)
+	}
+	if !strings.Contains(out, "\rnuro: pulling abc 100.0% (2.0KB/2.0KB)\nnuro: success\n") {
+		t.Errorf("unexpected progress rendering: %q", out)
+	}
+}
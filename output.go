@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+// Output formats accepted by --output
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputNDJSON   = "ndjson"
+	outputYAML     = "yaml"
+	outputMarkdown = "markdown"
+	outputRaw      = "raw"
+)
+
+// extractCode is the only value accepted by --extract
+const extractCode = "code"
+
+// activeSink is set once the output destination is open so that exitWithErr can report
+// the error as an NDJSON event and discard a partially written --out file.
+var activeSink *outputSink
+
+// outputSink writes the answer in the selected --output format to stdout or, with --out,
+// to a file that only replaces the target once the answer is complete.
+type outputSink struct {
+	format  string
+	extract string
+	w       io.Writer   // final destination: stdout, the --out file, or both with --tee
+	file    *atomicFile // nil when writing to stdout
+	echo    io.Writer   // where streamed deltas are written as they arrive, nil to buffer
+	ndjson  *ndjsonWriter
+	md      *mdRenderer // set when text answers are rendered for the terminal
+}
+
+func newOutputSink(f *cliFlags) (*outputSink, error) {
+	s := &outputSink{format: f.output, extract: f.extract, w: os.Stdout}
+	if f.outFile != "" && f.outFile != "-" {
+		af, err := createAtomic(f.outFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot write --out file: %w", err)
+		}
+		s.file = af
+		s.w = af
+		if f.tee {
+			s.w = io.MultiWriter(af, os.Stdout)
+		}
+	}
+
+	if s.format == outputText && s.extract == "" && s.file == nil && shouldRender(f.render) {
+		s.md = newMarkdownRenderer(os.Stdout, terminalWidth())
+		s.w = s.md
+	}
+
+	switch {
+	case s.format == outputNDJSON:
+		s.ndjson = newNDJSONWriter(s.w)
+	case s.extract != "":
+		// Code blocks can only be extracted from the complete answer
+	case s.format == outputText || s.format == outputRaw:
+		s.echo = s.w
+	case s.format == outputJSON && s.file == nil:
+		// --stream --json shows the text on stderr as it arrives, so stdout holds only
+		// the JSON result; --output ndjson is the way to stream machine-readable output
+		s.echo = os.Stderr
+	}
+	return s, nil
+}
+
+// delta handles one streamed chunk of the answer.
+func (s *outputSink) delta(text string) {
+	if s.ndjson != nil {
+		s.ndjson.delta(text)
+		return
+	}
+	if s.echo != nil {
+		_, _ = fmt.Fprint(s.echo, text)
+	}
+}
+
+// finish writes the result and, with --out, moves the file into place. streamed reports
+// whether delta has already been called with the answer text.
+func (s *outputSink) finish(out provider.JSONResult, streamed bool) error {
+	if s.extract == extractCode {
+		blocks := extractCodeBlocks(out.Text)
+		if len(blocks) == 0 {
+			_, _ = fmt.Fprintln(os.Stderr, "nuro: --extract code: no fenced code blocks in the answer")
+		}
+		out.Text = strings.Join(blocks, "\n\n")
+	}
+	echoed := streamed && s.echo != nil
+
+	var err error
+	switch s.format {
+	case outputNDJSON:
+		s.ndjson.done(out)
+	case outputText:
+		if !echoed {
+			_, err = fmt.Fprintln(s.w, out.Text)
+		}
+	case outputRaw:
+		if !echoed {
+			_, err = fmt.Fprint(s.w, out.Text)
+		}
+	case outputJSON:
+		if echoed {
+			_, _ = fmt.Fprintln(s.echo)
+		}
+		enc := json.NewEncoder(s.w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(out)
+	case outputYAML:
+		_, err = s.w.Write(marshalYAML(out))
+	case outputMarkdown:
+		_, err = io.WriteString(s.w, formatMarkdown(out))
+	}
+	if s.md != nil {
+		s.md.Flush()
+	}
+	if err != nil {
+		s.abort()
+		return err
+	}
+	if s.file != nil {
+		return s.file.commit()
+	}
+	return nil
+}
+
+// fail reports err as an NDJSON error event when that format is active. A partially
+// written --out file is discarded, except for NDJSON where the error event completes it.
+func (s *outputSink) fail(err error, code int) {
+	if s.md != nil {
+		s.md.Flush()
+	}
+	if s.ndjson != nil {
+		s.ndjson.error(err, code)
+		if s.file != nil {
+			_ = s.file.commit()
+		}
+		return
+	}
+	s.abort()
+}
+
+func (s *outputSink) abort() {
+	if s.file != nil {
+		s.file.abort()
+	}
+}
+
+// formatMarkdown renders the answer followed by a short metadata footer and its sources.
+func formatMarkdown(out provider.JSONResult) string {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(out.Text, "\n"))
+	sb.WriteString("\n\n---\n\n")
+	meta := []string{out.Provider, out.Model}
+	meta = append(
+		meta,
+		fmt.Sprintf("%d prompt + %d completion tokens", out.Usage.PromptTokens, out.Usage.CompletionTokens),
+	)
+	if out.FinishReason != "" {
+		meta = append(meta, "finish: "+out.FinishReason)
+	}
+	if len(out.Fallback) > 0 {
+		meta = append(meta, "fallback from "+strings.Join(out.Fallback, ", "))
+	}
+	sb.WriteString("*" + strings.Join(meta, " · ") + "*\n")
+	if len(out.Sources) > 0 {
+		sb.WriteString("\n**Sources**\n\n")
+		for _, src := range out.Sources {
+			sb.WriteString("- " + src + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// extractCodeBlocks returns the contents of the fenced (``` or ~~~) code blocks in text.
+// A block left open at the end of the text (e.g. a truncated answer) is returned as is.
+func extractCodeBlocks(text string) []string {
+	var blocks []string
+	var cur []string
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence == "" {
+			if len(line)-len(trimmed) <= 3 {
+				if f := fenceOf(trimmed); f != "" {
+					fence = f
+					cur = nil
+				}
+			}
+			continue
+		}
+		if f := fenceOf(trimmed); f != "" && f[0] == fence[0] && len(f) >= len(fence) &&
+			strings.TrimSpace(trimmed[len(f):]) == "" {
+			blocks = append(blocks, strings.Join(cur, "\n"))
+			fence = ""
+			continue
+		}
+		cur = append(cur, line)
+	}
+	if fence != "" && len(cur) > 0 {
+		blocks = append(blocks, strings.TrimRight(strings.Join(cur, "\n"), "\n"))
+	}
+	return blocks
+}
+
+// fenceOf returns the run of three or more backticks or tildes that line starts with.
+func fenceOf(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return line[:n]
+}
+
+// atomicFile is a temporary file next to path that is renamed over path on commit, so
+// readers never see a partially written output file.
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+func createAtomic(path string) (*atomicFile, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: tmp, path: path}, nil
+}
+
+func (f *atomicFile) commit() error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(f.path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.abort()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), f.path)
+}
+
+func (f *atomicFile) abort() {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// ndjsonWriter emits provider.StreamEvent values, one JSON object per line.
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &ndjsonWriter{enc: enc}
+}
+
+func (w *ndjsonWriter) delta(text string) {
+	_ = w.enc.Encode(provider.StreamEvent{Type: provider.EventDelta, Text: text})
+}
+
+func (w *ndjsonWriter) done(res provider.JSONResult) {
+	usage := res.Usage
+	_ = w.enc.Encode(
+		provider.StreamEvent{
+			Type:         provider.EventDone,
+			Provider:     res.Provider,
+			Model:        res.Model,
+			Usage:        &usage,
+			FinishReason: res.FinishReason,
+			Sources:      res.Sources,
+			Cached:       res.Cached,
+			Fallback:     res.Fallback,
+		},
+	)
+}
+
+func (w *ndjsonWriter) error(err error, code int) {
+	_ = w.enc.Encode(
+		provider.StreamEvent{Type: provider.EventError, Error: err.Error(), ExitCode: code},
+	)
+}
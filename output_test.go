@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newNDJSONWriter(&buf)
+	w.delta("Hel")
+	w.delta("lo <b>")
+	w.done(
+		provider.JSONResult{
+			Provider:     "openai",
+			Model:        "gpt-4o-mini",
+			Usage:        provider.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+			Text:         "Hello <b>",
+			FinishReason: provider.FinishStop,
+		},
+	)
+	w.error(errors.New("boom"), 4)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[1], "<b>") {
+		t.Errorf("HTML should not be escaped: %s", lines[1])
+	}
+
+	var events []provider.StreamEvent
+	for _, l := range lines {
+		var ev provider.StreamEvent
+		if err := json.Unmarshal([]byte(l), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", l, err)
+		}
+		events = append(events, ev)
+	}
+
+	if events[0].Type != provider.EventDelta || events[0].Text != "Hel" {
+		t.Errorf("unexpected first event %+v", events[0])
+	}
+	done := events[2]
+	if done.Type != provider.EventDone || done.Text != "" || done.Usage == nil ||
+		done.Usage.TotalTokens != 5 || done.FinishReason != provider.FinishStop {
+		t.Errorf("unexpected done event %+v", done)
+	}
+	if events[3].Type != provider.EventError || events[3].Error != "boom" || events[3].ExitCode != 4 {
+		t.Errorf("unexpected error event %+v", events[3])
+	}
+}
+
+func TestExtractCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "single block",
+			text: "Here you go:\n\n```bash\n#!/bin/sh\necho hi\n```\n\nEnjoy.",
+			want: []string{"#!/bin/sh\necho hi"},
+		},
+		{
+			name: "two blocks and tildes",
+			text: "```go\nfmt.Println(1)\n```\ntext\n~~~\nplain\n~~~\n",
+			want: []string{"fmt.Println(1)", "plain"},
+		},
+		{
+			name: "longer fence contains shorter",
+			text: "````md\n```\ninner\n```\n````",
+			want: []string{"```\ninner\n```"},
+		},
+		{
+			name: "unterminated block from a truncated answer",
+			text: "```python\nprint(1)\n",
+			want: []string{"print(1)"},
+		},
+		{
+			name: "no blocks",
+			text: "just prose with `inline` code",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := extractCodeBlocks(tt.text)
+				if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+					t.Errorf("extractCodeBlocks() = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestOutputSinkWritesFileAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "answer.sh")
+	if err := os.WriteFile(path, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := newOutputSink(&cliFlags{output: outputRaw, outFile: path, extract: extractCode})
+	if err != nil {
+		t.Fatalf("newOutputSink: %v", err)
+	}
+	sink.delta("```sh\necho ")
+	sink.delta("hi\n```")
+
+	// Nothing replaces the target until the answer is complete
+	if b, _ := os.ReadFile(path); string(b) != "old" {
+		t.Fatalf("target changed before finish: %q", b)
+	}
+
+	if err := sink.finish(provider.JSONResult{Text: "```sh\necho hi\n```"}, true); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "echo hi" {
+		t.Errorf("unexpected file content %q", b)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o755 {
+		t.Errorf("expected existing mode to be kept, got %v", fi.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+}
+
+func TestOutputSinkAbortRemovesTempFile(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := newOutputSink(&cliFlags{output: outputText, outFile: filepath.Join(dir, "out.txt")})
+	if err != nil {
+		t.Fatalf("newOutputSink: %v", err)
+	}
+	sink.delta("partial")
+	sink.fail(errors.New("connection reset"), 4)
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected no files after a failed request, got %v", entries)
+	}
+}
+
+func TestOutputSinkStreamJSONKeepsStdoutParseable(t *testing.T) {
+	dir := t.TempDir()
+	stdout, stderr := os.Stdout, os.Stderr
+	t.Cleanup(func() { os.Stdout, os.Stderr = stdout, stderr })
+	var err error
+	if os.Stdout, err = os.Create(filepath.Join(dir, "stdout")); err != nil {
+		t.Fatal(err)
+	}
+	if os.Stderr, err = os.Create(filepath.Join(dir, "stderr")); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := newOutputSink(&cliFlags{output: outputJSON, stream: true})
+	if err != nil {
+		t.Fatalf("newOutputSink: %v", err)
+	}
+	sink.delta("Hello ")
+	sink.delta("there")
+	if err := sink.finish(provider.JSONResult{Text: "Hello there"}, true); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+
+	out, _ := os.ReadFile(filepath.Join(dir, "stdout"))
+	var res provider.JSONResult
+	if err := json.Unmarshal(out, &res); err != nil || res.Text != "Hello there" {
+		t.Errorf("expected only the JSON result on stdout, got %q (%v)", out, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "stderr")); string(b) != "Hello there\n" {
+		t.Errorf("expected the streamed text on stderr, got %q", b)
+	}
+}
+
+func TestFormatMarkdown(t *testing.T) {
+	got := formatMarkdown(
+		provider.JSONResult{
+			Provider:     "ollama",
+			Model:        "llama3.1:8b",
+			Usage:        provider.Usage{PromptTokens: 10, CompletionTokens: 20},
+			Text:         "# Title\n\nBody\n",
+			FinishReason: provider.FinishStop,
+			Sources:      []string{"docs/a.md:1-9"},
+		},
+	)
+	want := "# Title\n\nBody\n\n---\n\n*ollama · llama3.1:8b · 10 prompt + 20 completion tokens · finish: stop*\n" +
+		"\n**Sources**\n\n- docs/a.md:1-9\n"
+	if got != want {
+		t.Errorf("formatMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+}
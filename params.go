@@ -0,0 +1,444 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/provider"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+// Request parameters are resolved in layers, highest first: command-line flags, the
+// profile selected with --cfg (or by the prompt of "nuro run"), NURO_* environment
+// variables, the default profile of .nuro, and the built-in defaults of the flags.
+
+// cliEnv is the process environment, read once here at the CLI edge. Nothing below it
+// reads or changes the environment: profiles and variables reach the resolver as
+// resolver.Settings and the request through resolveParams.
+var cliEnv = resolver.EnvSettings(os.Environ())
+
+// appliedProfile is the .nuro profile in use and how it was chosen.
+type appliedProfile struct {
+	*config.Profile
+	name     string
+	selected bool // chosen by name (--cfg or a library prompt) rather than by default
+}
+
+// source names the profile as a parameter source.
+func (p *appliedProfile) source() string {
+	if p.selected {
+		return "profile " + p.name + " (--cfg)"
+	}
+	return "profile " + p.name + " (default)"
+}
+
+// resolverSettings layers the provider, model, key and base URL of prof over env: a
+// selected profile overrides the NURO_* variables, which override the default profile.
+func resolverSettings(prof *appliedProfile, env resolver.Settings) resolver.Settings {
+	if prof == nil {
+		return env
+	}
+	hi := resolver.Settings{
+		APIKey: prof.APIKey, BaseURL: prof.BaseURL, Provider: prof.Provider, Model: prof.Model,
+	}
+	lo := env
+	if !prof.selected {
+		hi, lo = lo, hi
+	}
+	return resolver.Settings{
+		APIKey:   cmp.Or(hi.APIKey, lo.APIKey),
+		BaseURL:  cmp.Or(hi.BaseURL, lo.BaseURL),
+		Provider: cmp.Or(hi.Provider, lo.Provider),
+		Model:    cmp.Or(hi.Model, lo.Model),
+		Env:      env.Env,
+	}
+}
+
+// param is a setting that a flag, a .nuro profile or a NURO_* variable can provide.
+type param struct {
+	flag, env string
+	// given reports whether the setting was given on the command line; nil uses the flag.
+	given func() bool
+	// fromProfile copies the profile's value into the flags, reporting whether it has one.
+	fromProfile func(p *config.Profile) bool
+	// fromEnv parses the value of the environment variable into the flags.
+	fromEnv func(v string) error
+	// show formats the resolved value; nil uses the flag's value.
+	show func() string
+}
+
+// paramSource records the resolved value of a parameter and the layer it came from.
+type paramSource struct {
+	name, value, source string
+}
+
+// provenance lists the resolved parameters in the order they are printed.
+type provenance []paramSource
+
+// source returns the layer the named parameter came from.
+func (p provenance) source(name string) string {
+	for _, s := range p {
+		if s.name == name {
+			return s.source
+		}
+	}
+	return ""
+}
+
+// Sources of a parameter; a profile is reported as "profile NAME (--cfg)" or
+// "profile NAME (default)".
+const (
+	sourceFlag    = "flag"
+	sourceBuiltin = "built-in"
+)
+
+// intParam and floatParam take the profile's value as a pointer that is nil when the
+// profile leaves the setting out, so an explicit 0 (e.g. temperature: 0) still applies.
+func intParam(flag, env string, dst *int, get func(*config.Profile) *int) param {
+	return param{
+		flag: flag, env: env,
+		fromProfile: func(p *config.Profile) bool {
+			if v := get(p); v != nil {
+				*dst = *v
+				return true
+			}
+			return false
+		},
+		fromEnv: func(v string) error {
+			i, err := strconv.Atoi(v)
+			if err == nil {
+				*dst = i
+			}
+			return err
+		},
+	}
+}
+
+func floatParam(flag, env string, dst *float64, get func(*config.Profile) *float64) param {
+	return param{
+		flag: flag, env: env,
+		fromProfile: func(p *config.Profile) bool {
+			if v := get(p); v != nil {
+				*dst = *v
+				return true
+			}
+			return false
+		},
+		fromEnv: func(v string) error {
+			x, err := strconv.ParseFloat(v, 64)
+			if err == nil {
+				*dst = x
+			}
+			return err
+		},
+	}
+}
+
+// positive treats 0 as unset, for the profile settings where 0 means no value.
+func positive(v int) *int {
+	if v > 0 {
+		return &v
+	}
+	return nil
+}
+
+// valueOr returns *v, or def when v is nil.
+func valueOr[T any](v *T, def T) T {
+	if v != nil {
+		return *v
+	}
+	return def
+}
+
+func stringParam(flag, env string, dst *string, get func(*config.Profile) string) param {
+	return param{
+		flag: flag, env: env,
+		fromProfile: func(p *config.Profile) bool {
+			if v := get(p); v != "" {
+				*dst = v
+				return true
+			}
+			return false
+		},
+		fromEnv: func(v string) error {
+			*dst = v
+			return nil
+		},
+	}
+}
+
+// requestParams returns the parameters of f, parsed by fs, that profiles and NURO_*
+// variables can set.
+func requestParams(f *cliFlags, fs *pflag.FlagSet) []param {
+	return []param{
+		intParam(
+			"max-tokens", "NURO_MAX_TOKENS", &f.maxTokens,
+			func(p *config.Profile) *int { return positive(p.MaxTokens) },
+		),
+		floatParam(
+			"temperature", "NURO_TEMPERATURE", &f.temperature,
+			func(p *config.Profile) *float64 { return p.Temperature },
+		),
+		floatParam("top-p", "NURO_TOP_P", &f.topP, func(p *config.Profile) *float64 { return p.TopP }),
+		stringParam("system", "NURO_SYSTEM", &f.system, func(p *config.Profile) string { return p.System }),
+		{
+			flag: "seed", env: "NURO_SEED",
+			fromProfile: func(p *config.Profile) bool {
+				if p.Seed != nil {
+					f.seed = *p.Seed
+				}
+				return p.Seed != nil
+			},
+			fromEnv: func(v string) error {
+				i, err := strconv.Atoi(v)
+				if err == nil {
+					f.seed = i
+				}
+				return err
+			},
+		},
+		{
+			flag: "stop", env: "NURO_STOP",
+			fromProfile: func(p *config.Profile) bool {
+				if len(p.Stop) > 0 {
+					f.stop = p.Stop
+				}
+				return len(p.Stop) > 0
+			},
+			// Stop sequences may contain any character, so they are given as a JSON array
+			fromEnv: func(v string) error { return json.Unmarshal([]byte(v), &f.stop) },
+		},
+		stringParam(
+			"keep-alive", "NURO_KEEP_ALIVE", &f.keepAlive,
+			func(p *config.Profile) string { return p.KeepAlive },
+		),
+		intParam("num-ctx", "NURO_NUM_CTX", &f.numCtx, func(p *config.Profile) *int { return positive(p.NumCtx) }),
+		floatParam(
+			"repeat-penalty", "NURO_REPEAT_PENALTY", &f.repeatPenalty,
+			func(p *config.Profile) *float64 { return p.RepeatPenalty },
+		),
+		intParam("top-k", "NURO_TOP_K", &f.topK, func(p *config.Profile) *int { return p.TopK }),
+		floatParam("min-p", "NURO_MIN_P", &f.minP, func(p *config.Profile) *float64 { return p.MinP }),
+		intParam("mirostat", "NURO_MIROSTAT", &f.mirostat, func(p *config.Profile) *int { return p.Mirostat }),
+		stringParam(
+			"ollama-api", "NURO_OLLAMA_API", &f.ollamaAPI,
+			func(p *config.Profile) string { return p.OllamaAPI },
+		),
+		{
+			flag: "cache", env: "NURO_CACHE",
+			given: func() bool { return fs.Changed("cache") || f.noCache || f.cacheOnly },
+			fromProfile: func(p *config.Profile) bool {
+				if p.Cache {
+					f.cache = true
+				}
+				return p.Cache
+			},
+			fromEnv: func(v string) error {
+				on, err := strconv.ParseBool(v)
+				if err == nil {
+					f.cache = on
+				}
+				return err
+			},
+		},
+		stringParam(
+			"cache-ttl", "NURO_CACHE_TTL", &f.cacheTTL,
+			func(p *config.Profile) string { return p.CacheTTL },
+		),
+		{
+			flag: "fallback", env: "NURO_FALLBACK",
+			fromProfile: func(p *config.Profile) bool {
+				if len(p.Fallback) > 0 {
+					f.fallback = p.Fallback
+				}
+				return len(p.Fallback) > 0
+			},
+			// Targets may carry their own keys and base URLs, so they are given as JSON
+			fromEnv: func(v string) error { return json.Unmarshal([]byte(v), &f.fallback) },
+			show: func() string {
+				targets := make([]string, len(f.fallback))
+				for i, t := range f.fallback {
+					targets[i] = t.String()
+				}
+				return "[" + strings.Join(targets, ",") + "]"
+			},
+		},
+		{
+			flag: "fallback-on", env: "NURO_FALLBACK_ON",
+			fromProfile: func(p *config.Profile) bool {
+				if len(p.FallbackOn) > 0 {
+					f.fallbackOn = p.FallbackOn
+				}
+				return len(p.FallbackOn) > 0
+			},
+			fromEnv: func(v string) error {
+				f.fallbackOn = strings.Split(v, ",")
+				return nil
+			},
+		},
+		{
+			// Only a flag sets the timeout
+			flag: "timeout", fromProfile: func(*config.Profile) bool { return false },
+		},
+		stringParam(
+			"fallback-timeout", "NURO_FALLBACK_TIMEOUT", &f.fallbackTimeout,
+			func(p *config.Profile) string { return p.FallbackTimeout },
+		),
+	}
+}
+
+// resolveParams fills the parameters of f that were not given on the command line fs
+// from the profile and the environment env, and returns where each value came from.
+func resolveParams(
+	f *cliFlags, fs *pflag.FlagSet, prof *appliedProfile, env map[string]string,
+) (provenance, error) {
+	var prov provenance
+	for _, p := range requestParams(f, fs) {
+		given := p.given
+		if given == nil {
+			flag := p.flag
+			given = func() bool { return fs.Changed(flag) }
+		}
+		var source string
+		switch {
+		case given():
+			source = sourceFlag
+		case prof != nil && prof.selected && p.fromProfile(prof.Profile):
+			source = prof.source()
+		case env[p.env] != "":
+			if err := p.fromEnv(env[p.env]); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", p.env, env[p.env], err)
+			}
+			source = p.env
+		case prof != nil && !prof.selected && p.fromProfile(prof.Profile):
+			source = prof.source()
+		default:
+			source = sourceBuiltin
+		}
+
+		var value string
+		if p.show != nil {
+			value = p.show()
+		} else if fl := fs.Lookup(p.flag); fl != nil {
+			value = fl.Value.String()
+		}
+		prov = append(prov, paramSource{name: p.flag, value: oneLine(value), source: source})
+	}
+	if err := validateFallbackOptions(f); err != nil {
+		return nil, err
+	}
+	return prov, nil
+}
+
+// targetProvenance returns the resolved provider, model, base URL and key of res and the
+// layer each came from, in the order resolverSettings layers prof over env. Values the
+// resolver chose itself are "inferred" (the provider, from the model or the keys found)
+// or "built-in" (the provider's default model and endpoint); the key reports the
+// resolver's own source.
+func targetProvenance(
+	f *cliFlags, fs *pflag.FlagSet, prof *appliedProfile, env resolver.Settings,
+	res *provider.ProviderResolution,
+) provenance {
+	var ps resolver.Settings
+	if prof != nil {
+		ps = resolver.Settings{
+			APIKey: prof.APIKey, BaseURL: prof.BaseURL, Provider: prof.Provider, Model: prof.Model,
+		}
+	}
+	layer := func(get func(resolver.Settings) string, name string) string {
+		switch {
+		case prof != nil && prof.selected && get(ps) != "":
+			return prof.source()
+		case get(env) != "":
+			return name
+		case prof != nil && !prof.selected && get(ps) != "":
+			return prof.source()
+		}
+		return ""
+	}
+
+	providerSource := cmp.Or(
+		layer(func(s resolver.Settings) string { return s.Provider }, "NURO_PROVIDER"), "inferred",
+	)
+	modelSource := cmp.Or(layer(func(s resolver.Settings) string { return s.Model }, "NURO_MODEL"), sourceBuiltin)
+	switch {
+	case fs.Changed("model"):
+		modelSource = sourceFlag
+	case f.modelArg != "":
+		modelSource = "prompt library"
+	}
+	baseURLSource := layer(func(s resolver.Settings) string { return s.BaseURL }, "NURO_BASE_URL")
+	switch {
+	case baseURLSource != "":
+	case res.BaseURL != "" && res.BaseURL == env.Env["OPENAI_BASE_URL"]:
+		baseURLSource = "OPENAI_BASE_URL"
+	default:
+		baseURLSource = sourceBuiltin
+	}
+	keySource := res.KeySource
+	if keySource == "NURO_API_KEY" {
+		keySource = layer(func(s resolver.Settings) string { return s.APIKey }, "NURO_API_KEY")
+	}
+	if res.APIKey == "" {
+		keySource = sourceBuiltin
+	}
+
+	return provenance{
+		{name: "provider", value: res.ProviderName, source: providerSource},
+		{name: "model", value: res.Model, source: modelSource},
+		{name: "base-url", value: res.BaseURL, source: baseURLSource},
+		{name: "api-key", value: redactKey(res.APIKey), source: keySource},
+	}
+}
+
+// completionArgs returns the request parameters of f, as resolved by resolveParams into
+// params; the caller adds the model and the messages.
+func completionArgs(f *cliFlags, params provenance) provider.CompletionArgs {
+	args := provider.CompletionArgs{
+		MaxTokens:   f.maxTokens,
+		Temperature: f.temperature,
+		TopP:        f.topP,
+		Timeout:     time.Duration(f.timeoutSec) * time.Second,
+		KeepAlive:   f.keepAlive,
+
+		System:        f.system,
+		Stop:          f.stop,
+		NumCtx:        f.numCtx,
+		RepeatPenalty: f.repeatPenalty,
+		TopK:          f.topK,
+		MinP:          f.minP,
+		Mirostat:      f.mirostat,
+		OllamaAPI:     f.ollamaAPI,
+
+		ReasoningEffort: f.reasoning,
+		Verbosity:       f.verbosity,
+	}
+	if params.source("seed") != sourceBuiltin {
+		seed := f.seed
+		args.Seed = &seed
+	}
+	return args
+}
+
+// printProvenance writes the resolved parameters and their sources, for --verbose.
+func printProvenance(w io.Writer, prov provenance) {
+	_, _ = fmt.Fprintln(w, "nuro: parameters:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range prov {
+		value := s.value
+		if value == "" {
+			value = `""`
+		}
+		_, _ = fmt.Fprintf(tw, "nuro:   %s\t%s\t%s\n", s.name, value, s.source)
+	}
+	_ = tw.Flush()
+}
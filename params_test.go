@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+func TestResolveParamsPrecedence(t *testing.T) {
+	temperature, topP := 0.2, 0.9
+	profile := &config.Profile{MaxTokens: 2048, Temperature: &temperature, TopP: &topP}
+	env := map[string]string{"NURO_MAX_TOKENS": "512", "NURO_TEMPERATURE": "0.5"}
+
+	tests := []struct {
+		name     string
+		args     []string
+		selected bool
+		want     map[string]string // parameter to "value source"
+	}{
+		{
+			name:     "flag over selected profile",
+			args:     []string{"--max-tokens", "100"},
+			selected: true,
+			want: map[string]string{
+				"max-tokens":  "100 flag",
+				"temperature": "0.2 profile work (--cfg)",
+				"top-p":       "0.9 profile work (--cfg)",
+			},
+		},
+		{
+			name: "env over default profile",
+			args: []string{"--temperature", "1"},
+			want: map[string]string{
+				"max-tokens":  "512 NURO_MAX_TOKENS",
+				"temperature": "1 flag",
+				"top-p":       "0.9 profile work (default)",
+				"num-ctx":     "0 built-in",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var f cliFlags
+				fs := pflag.NewFlagSet("nuro", pflag.ContinueOnError)
+				fs.IntVar(&f.maxTokens, "max-tokens", 1024, "")
+				fs.Float64Var(&f.temperature, "temperature", 0.7, "")
+				fs.Float64Var(&f.topP, "top-p", 1.0, "")
+				fs.IntVar(&f.numCtx, "num-ctx", 0, "")
+				if err := fs.Parse(tt.args); err != nil {
+					t.Fatal(err)
+				}
+
+				prof := &appliedProfile{Profile: profile, name: "work", selected: tt.selected}
+				prov, err := resolveParams(&f, fs, prof, env)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, s := range prov {
+					if want, ok := tt.want[s.name]; ok && s.value+" "+s.source != want {
+						t.Errorf("%s = %q, want %q", s.name, s.value+" "+s.source, want)
+					}
+				}
+			},
+		)
+	}
+
+	fs := pflag.NewFlagSet("nuro", pflag.ContinueOnError)
+	_, err := resolveParams(&cliFlags{}, fs, nil, map[string]string{"NURO_TOP_K": "many"})
+	if err == nil || !strings.Contains(err.Error(), "invalid NURO_TOP_K") {
+		t.Errorf("expected an invalid NURO_TOP_K error, got %v", err)
+	}
+}
+
+func TestResolveParamsProfileZero(t *testing.T) {
+	var cfg config.Config
+	err := json.Unmarshal(
+		[]byte(`{"profiles": {"exact": {"temperature": 0, "top_p": 0, "min_p": 0, "mirostat": 0}}}`), &cfg,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := cfg.GetProfile("exact", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := cliFlags{temperature: 0.7, topP: 1.0, minP: 0.05, mirostat: 2}
+	fs := pflag.NewFlagSet("nuro", pflag.ContinueOnError)
+	fs.Float64Var(&f.temperature, "temperature", 0.7, "")
+	fs.Float64Var(&f.topP, "top-p", 1.0, "")
+	fs.Float64Var(&f.minP, "min-p", 0, "")
+	fs.IntVar(&f.mirostat, "mirostat", 0, "")
+	prof := &appliedProfile{Profile: profile, name: "exact", selected: true}
+	params, err := resolveParams(&f, fs, prof, map[string]string{"NURO_MIN_P": "0.05", "NURO_MIROSTAT": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.temperature != 0 || f.topP != 0 || f.minP != 0 || f.mirostat != 0 {
+		t.Errorf("expected the profile's zeros to apply, got %+v", f)
+	}
+	for _, name := range []string{"temperature", "top-p", "min-p", "mirostat"} {
+		if src := params.source(name); src != "profile exact (--cfg)" {
+			t.Errorf("%s comes from %q, want the profile", name, src)
+		}
+	}
+}
+
+func TestTargetProvenance(t *testing.T) {
+	env := resolver.EnvSettings(
+		[]string{"NURO_MODEL=env-model", "OPENAI_API_KEY=sk-env-0123456789", "OPENAI_BASE_URL=http://proxy"},
+	)
+	profile := &config.Profile{Provider: "ollama", Model: "profile-model", APIKey: "sk-profile"}
+
+	tests := []struct {
+		name string
+		args []string
+		prof *appliedProfile
+		env  resolver.Settings
+		want map[string]string // parameter to source
+	}{
+		{
+			name: "selected profile",
+			prof: &appliedProfile{Profile: profile, name: "p", selected: true},
+			env:  env,
+			want: map[string]string{
+				"provider": "profile p (--cfg)", "model": "profile p (--cfg)", "base-url": "OPENAI_BASE_URL",
+				"api-key": "profile p (--cfg)",
+			},
+		},
+		{
+			name: "flag over environment",
+			args: []string{"-m", "flag-model"},
+			prof: &appliedProfile{Profile: profile, name: "p"},
+			env:  env,
+			want: map[string]string{
+				"provider": "profile p (default)", "model": "flag", "api-key": "profile p (default)",
+			},
+		},
+		{
+			name: "discovered from provider keys",
+			env:  resolver.EnvSettings([]string{"OPENAI_API_KEY=sk-env-0123456789"}),
+			want: map[string]string{
+				"provider": "inferred", "model": "built-in", "base-url": "built-in", "api-key": "OPENAI_API_KEY",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var f cliFlags
+				fs := pflag.NewFlagSet("nuro", pflag.ContinueOnError)
+				fs.StringVarP(&f.modelArg, "model", "m", "", "")
+				if err := fs.Parse(tt.args); err != nil {
+					t.Fatal(err)
+				}
+				res, err := resolver.ResolveProviderAndModel(resolverSettings(tt.prof, tt.env), f.modelArg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				prov := targetProvenance(&f, fs, tt.prof, tt.env, res)
+				for name, want := range tt.want {
+					if got := prov.source(name); got != want {
+						t.Errorf("%s comes from %q, want %q", name, got, want)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestResolverSettings(t *testing.T) {
+	env := resolver.EnvSettings([]string{"NURO_MODEL=env-model", "OPENAI_API_KEY=sk-env"})
+	profile := &config.Profile{Provider: "ollama", Model: "profile-model", BaseURL: "http://box:11434"}
+
+	selected := resolverSettings(&appliedProfile{Profile: profile, name: "p", selected: true}, env)
+	if selected.Model != "profile-model" || selected.Provider != "ollama" {
+		t.Errorf("expected a selected profile to override NURO_MODEL, got %+v", selected)
+	}
+	byDefault := resolverSettings(&appliedProfile{Profile: profile, name: "p"}, env)
+	if byDefault.Model != "env-model" || byDefault.BaseURL != "http://box:11434" {
+		t.Errorf("expected NURO_MODEL to override the default profile, got %+v", byDefault)
+	}
+	if byDefault.Env["OPENAI_API_KEY"] != "sk-env" || resolverSettings(nil, env).Model != "env-model" {
+		t.Errorf("expected the environment to be kept, got %+v", byDefault)
+	}
+}
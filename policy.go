@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/eval"
+	"github.com/heather7532/nuro/provider"
+)
+
+// exitPolicy is the exit code for requests a .nuro policy refuses.
+const exitPolicy = 8
+
+// loadPolicies reads the policies of the .nuro files around the working directory once
+// per run.
+var loadPolicies = sync.OnceValues(config.LoadPolicies)
+
+// policies returns the policies in force, exiting with code 2 when a .nuro is broken.
+func policies() []config.Policy {
+	ps, err := loadPolicies()
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	return ps
+}
+
+// checkTargetPolicy reports whether every policy allows sending to res.
+func checkTargetPolicy(res *provider.ProviderResolution) error {
+	for _, p := range policies() {
+		if err := p.CheckTarget(res.ProviderName, res.Model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enforceTargetPolicy exits with code 8 unless every policy allows sending to res.
+func enforceTargetPolicy(res *provider.ProviderResolution) {
+	if err := checkTargetPolicy(res); err != nil {
+		exitWithErr(err, exitPolicy)
+	}
+}
+
+// enforceEmbeddingPolicy exits with code 8 unless every policy allows sending to the
+// embedding model of the named provider.
+func enforceEmbeddingPolicy(providerName, model string) {
+	enforceTargetPolicy(&provider.ProviderResolution{ProviderName: providerName, Model: model})
+}
+
+// checkDataPolicy reports whether size bytes of data are within every policy's limit
+// and none of texts, about to be sent, matches a forbidden pattern.
+func checkDataPolicy(size int, texts ...string) error {
+	for _, p := range policies() {
+		if err := p.CheckDataSize(size); err != nil {
+			return err
+		}
+		for _, text := range texts {
+			if err := p.CheckContent(text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enforceDataPolicy exits with code 8 unless checkDataPolicy passes.
+func enforceDataPolicy(size int, texts ...string) {
+	if err := checkDataPolicy(size, texts...); err != nil {
+		exitWithErr(err, exitPolicy)
+	}
+}
+
+// redactionRequiredBy returns the .nuro file of a policy that requires redaction, or "".
+func redactionRequiredBy() string {
+	for _, p := range policies() {
+		if p.RequireRedaction {
+			return p.Source
+		}
+	}
+	return ""
+}
+
+// enforceNoRedactionPolicy exits with code 8 when a policy requires redaction, for
+// commands that cannot redact; what names the command.
+func enforceNoRedactionPolicy(what string) {
+	if src := redactionRequiredBy(); src != "" {
+		exitWithErr(fmt.Errorf("%s cannot redact, which the policy in %s requires", what, src), exitPolicy)
+	}
+}
+
+// enforceSuitePolicy applies enforceDataPolicy to the system prompt, prompts, data and
+// vars of an eval suite, as written in the suite.
+func enforceSuitePolicy(s *eval.Suite) {
+	texts := []string{string(s.System)}
+	for _, v := range s.Vars {
+		texts = append(texts, string(v))
+	}
+	for _, c := range s.Tests {
+		texts = append(texts, string(c.Prompt), string(c.Data))
+		for _, v := range c.Vars {
+			texts = append(texts, string(v))
+		}
+		enforceDataPolicy(len(c.Data))
+	}
+	enforceDataPolicy(0, texts...)
+}
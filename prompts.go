@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/eval"
+	"github.com/heather7532/nuro/resolver"
+	"github.com/spf13/pflag"
+)
+
+// namedPrompt is the library prompt selected by "nuro run", or nil.
+var namedPrompt *config.Prompt
+
+// runNamedPrompt implements "nuro run <prompt> [flags]": a prompt from the library is
+// sent like "nuro -p", with stdin or --data as its data and every other flag available.
+func runNamedPrompt(args []string) {
+	const usage = "usage: nuro run <prompt> [--var name=value ...] [flags]"
+	if len(args) > 0 {
+		exitOnHelp(args[0], usage)
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		_, _ = fmt.Fprintln(os.Stderr, usage)
+		exitWithErr(usageError("expected a prompt name; see 'nuro prompts list'"), 2)
+	}
+	p, err := findPrompt(args[0])
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	namedPrompt = p
+	os.Args = append([]string{os.Args[0]}, args[1:]...)
+	runCompletion()
+}
+
+// runPrompts implements "nuro prompts list".
+func runPrompts(args []string) {
+	fs := pflag.NewFlagSet("prompts", pflag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "Emit the prompts as JSON.")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: nuro prompts list [--json]")
+		fs.PrintDefaults()
+	}
+	parseSubcommand(fs, args)
+	if fs.NArg() != 1 || fs.Arg(0) != "list" {
+		fs.Usage()
+		exitWithErr(usageError("expected 'list'"), 2)
+	}
+
+	prompts, err := config.LoadPrompts()
+	if err != nil {
+		exitWithErr(err, 2)
+	}
+	names := make([]string, 0, len(prompts))
+	for name := range prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if *jsonOut {
+		type promptInfo struct {
+			Name        string   `json:"name"`
+			Description string   `json:"description,omitempty"`
+			Profile     string   `json:"profile,omitempty"`
+			Model       string   `json:"model,omitempty"`
+			Vars        []string `json:"vars,omitempty"`
+			Schema      bool     `json:"schema,omitempty"`
+			Source      string   `json:"source"`
+		}
+		list := make([]promptInfo, 0, len(names))
+		for _, name := range names {
+			p := prompts[name]
+			list = append(
+				list, promptInfo{
+					Name: name, Description: p.Description, Profile: p.Profile, Model: p.Model,
+					Vars: p.VarNames(), Schema: p.Schema != nil, Source: p.Source,
+				},
+			)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(struct {
+			Prompts []promptInfo `json:"prompts"`
+		}{list})
+		return
+	}
+	if len(names) == 0 {
+		_, _ = fmt.Fprintf(
+			os.Stderr, "nuro: no prompts found; add a \"prompts\" section to .nuro or files to %s/\n",
+			config.PromptDir,
+		)
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tMODEL\tVARS\tDESCRIPTION\tSOURCE")
+	for _, name := range names {
+		p := prompts[name]
+		model := p.Model
+		if model == "" && p.Profile != "" {
+			model = "cfg:" + p.Profile
+		}
+		_, _ = fmt.Fprintf(
+			tw, "%s\t%s\t%s\t%s\t%s\n", name, dash(model), dash(strings.Join(p.VarNames(), ",")),
+			dash(p.Description), p.Source,
+		)
+	}
+	_ = tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// findPrompt looks up a library prompt by name, suggesting close names when it is
+// missing.
+func findPrompt(name string) (*config.Prompt, error) {
+	prompts, err := config.LoadPrompts()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := prompts[name]
+	if !ok {
+		names := make([]string, 0, len(prompts))
+		for n := range prompts {
+			names = append(names, n)
+		}
+		msg := fmt.Sprintf("prompt '%s' not found", name)
+		if similar := resolver.SuggestModels(name, names, 3); len(similar) > 0 {
+			msg += "; did you mean: " + strings.Join(similar, ", ") + "?"
+		} else {
+			msg += "; see 'nuro prompts list'"
+		}
+		return nil, fmt.Errorf("%s", msg)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Source, err)
+	}
+	return p, nil
+}
+
+// applyNamedPrompt fills the flags from the prompt selected by "nuro run": its text
+// becomes the prompt (stdin and --data stay the data), and its profile, model and system
+// prompt apply unless given on the command line.
+func applyNamedPrompt(f *cliFlags, p *config.Prompt) error {
+	if f.promptFlag != "" || f.promptUseStdin || f.promptFile != "" {
+		return usageError("'nuro run " + p.Name + "' supplies the prompt; -p, -s and --prompt-file cannot be used")
+	}
+	vars := map[string]string{}
+	for _, kv := range f.vars {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			return usageError("--var must be name=value, got '" + kv + "'")
+		}
+		vars[name] = value
+	}
+	prompt, system, err := p.Render(vars)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	f.promptFlag = prompt
+	f.namedPrompt = true
+
+	changed := pflag.CommandLine.Changed
+	if !changed("cfg") && p.Profile != "" {
+		f.configName = p.Profile
+	}
+	if !changed("model") && p.Model != "" {
+		f.modelArg = p.Model
+	}
+	if changed("system") {
+		system = f.system
+	}
+	if p.Schema != nil {
+		schema, err := json.Marshal(p.Schema)
+		if err != nil {
+			return err
+		}
+		system = strings.TrimSpace(system + "\n\nRespond only with JSON that matches this JSON Schema:\n" + string(schema))
+	}
+	if system != "" {
+		// Set marks --system as given, so a profile's system prompt does not replace it
+		if err := pflag.CommandLine.Set("system", system); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPromptSchema validates the answer against the output schema of the prompt run by
+// "nuro run", exiting with code 1 when it does not match.
+func checkPromptSchema(text string) {
+	if namedPrompt == nil || namedPrompt.Schema == nil {
+		return
+	}
+	if err := eval.CheckSchema(namedPrompt.Schema, text); err != nil {
+		activeSink = nil // the answer has been written; only the mismatch is reported
+		exitWithErr(
+			fmt.Errorf("answer does not match the output schema of prompt '%s': %w", namedPrompt.Name, err), 1,
+		)
+	}
+}
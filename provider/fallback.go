@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Error classes that can trigger a fallback to the next provider
+const (
+	ErrorClass5xx       = "5xx"
+	ErrorClass429       = "429"
+	ErrorClassTimeout   = "timeout"
+	ErrorClassNetwork   = "network"
+	errorClassPermanent = ""
+)
+
+// ErrorClass classifies a provider error for fallback decisions: server errors
+// (including errors reported in-band during a stream), rate limits, timeouts and
+// connection failures. Other errors (bad key, unknown model, invalid request) return ""
+// and never fall back.
+func ErrorClass(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests,
+			strings.Contains(apiErr.Status, "rate_limit"):
+			return ErrorClass429
+		case apiErr.StatusCode >= 500, apiErr.Status == "server_error", apiErr.Status == streamErrorStatus:
+			return ErrorClass5xx
+		}
+		return errorClassPermanent
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	}
+	return errorClassPermanent
+}
+
+// FallbackLink is one provider of a fallback chain with the model to ask it for.
+type FallbackLink struct {
+	Provider Provider
+	Model    string
+}
+
+func (l FallbackLink) String() string { return l.Provider.Name() + ":" + l.Model }
+
+// Fallback is a Provider that tries a chain of providers in order, moving on when one
+// fails with an error class listed in On. A stream never falls back once a delta has
+// been delivered, and once a provider has answered, later requests (continuations) go
+// to the same provider.
+type Fallback struct {
+	Links []FallbackLink
+	// On lists the error classes that trigger a fallback; empty means all of them.
+	On []string
+	// AttemptTimeout, when set, limits each provider's attempt so a timeout can still
+	// fall back within the overall request timeout.
+	AttemptTimeout time.Duration
+	// OnFallback, when set, is called before moving from one link to the next.
+	OnFallback func(from, to FallbackLink, err error)
+
+	current int
+	skipped []string
+}
+
+// Name reports the provider currently in use: the first link until one has answered.
+func (f *Fallback) Name() string { return f.Links[f.current].Provider.Name() }
+
+// Answered returns the link that served the last request.
+func (f *Fallback) Answered() FallbackLink { return f.Links[f.current] }
+
+// Degraded reports whether the last request was served by a link other than the first.
+func (f *Fallback) Degraded() bool { return f.current > 0 }
+
+// Skipped lists the links that failed over, as provider:model.
+func (f *Fallback) Skipped() []string { return f.skipped }
+
+func (f *Fallback) Complete(ctx context.Context, args CompletionArgs) (string, Usage, string, error) {
+	return f.try(
+		ctx, args, func(ctx context.Context, p Provider, args CompletionArgs) (string, Usage, string, error) {
+			return p.Complete(ctx, args)
+		}, func() bool { return false },
+	)
+}
+
+func (f *Fallback) Stream(ctx context.Context, args CompletionArgs, onDelta func(delta string)) (
+	string, Usage, string, error,
+) {
+	emitted := false
+	return f.try(
+		ctx, args, func(ctx context.Context, p Provider, args CompletionArgs) (string, Usage, string, error) {
+			return p.Stream(
+				ctx, args, func(d string) {
+					emitted = true
+					onDelta(d)
+				},
+			)
+		}, func() bool { return emitted },
+	)
+}
+
+func (f *Fallback) try(
+	ctx context.Context, args CompletionArgs,
+	call func(context.Context, Provider, CompletionArgs) (string, Usage, string, error),
+	emitted func() bool,
+) (string, Usage, string, error) {
+	for i := f.current; ; i++ {
+		f.current = i
+		link := f.Links[i]
+		a := args
+		a.Model = link.Model
+
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if f.AttemptTimeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, f.AttemptTimeout)
+		}
+		text, usage, finish, err := call(actx, link.Provider, a)
+		cancel()
+
+		if err == nil || i == len(f.Links)-1 || emitted() || ctx.Err() != nil || !f.fallsBack(err) {
+			return text, usage, finish, err
+		}
+		f.skipped = append(f.skipped, link.String())
+		if f.OnFallback != nil {
+			f.OnFallback(link, f.Links[i+1], err)
+		}
+	}
+}
+
+func (f *Fallback) fallsBack(err error) bool {
+	class := ErrorClass(err)
+	if class == errorClassPermanent {
+		return false
+	}
+	return len(f.On) == 0 || slices.Contains(f.On, class)
+}
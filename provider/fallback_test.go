@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heather7532/nuro/provider/providertest"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rate limited", &APIError{StatusCode: 429}, ErrorClass429},
+		{"error mid-stream", &APIError{Status: streamErrorStatus}, ErrorClass5xx},
+		{"rate limit event", &APIError{Op: "openai responses", Status: "rate_limit_exceeded"}, ErrorClass429},
+		{"server error", &APIError{StatusCode: 503}, ErrorClass5xx},
+		{"unauthorized", &APIError{StatusCode: 401}, ""},
+		{"not found", &APIError{StatusCode: 404}, ""},
+		{"deadline", context.DeadlineExceeded, ErrorClassTimeout},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrorClassNetwork},
+		{"canceled", context.Canceled, ""},
+		{"other", errors.New("boom"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := ErrorClass(tt.err); got != tt.want {
+					t.Errorf("expected %q, got %q", tt.want, got)
+				}
+			},
+		)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	tests := []struct {
+		name           string
+		primary        providertest.Reply
+		on             []string
+		attemptTimeout time.Duration
+		stream         bool
+		wantText       string
+		wantProvider   string
+		wantSkipped    int
+		wantErr        string
+	}{
+		{
+			name:         "primary answers",
+			primary:      providertest.Reply{Text: "from openai"},
+			wantText:     "from openai",
+			wantProvider: "openai",
+		},
+		{
+			name:         "5xx falls back",
+			primary:      providertest.Reply{Status: 503, ErrorMessage: "overloaded"},
+			wantText:     "from ollama",
+			wantProvider: "ollama",
+			wantSkipped:  1,
+		},
+		{
+			name:         "429 falls back",
+			primary:      providertest.Reply{Status: 429, ErrorMessage: "Rate limit reached"},
+			stream:       true,
+			wantText:     "from ollama",
+			wantProvider: "ollama",
+			wantSkipped:  1,
+		},
+		{
+			name:           "timeout falls back",
+			primary:        providertest.Reply{Text: "too slow", ChunkDelay: 200 * time.Millisecond},
+			attemptTimeout: 100 * time.Millisecond,
+			stream:         true,
+			wantText:       "from ollama",
+			wantProvider:   "ollama",
+			wantSkipped:    1,
+		},
+		{
+			name:         "class not enabled",
+			primary:      providertest.Reply{Status: 429, ErrorMessage: "Rate limit reached"},
+			on:           []string{ErrorClass5xx},
+			wantProvider: "openai",
+			wantErr:      "Rate limit reached",
+		},
+		{
+			name:         "client errors never fall back",
+			primary:      providertest.Reply{Status: 401, ErrorMessage: "Incorrect API key"},
+			wantProvider: "openai",
+			wantErr:      "Incorrect API key",
+		},
+		{
+			name:         "stream error before output falls back",
+			primary:      providertest.Reply{Chunks: []string{}, StreamError: "server_error"},
+			stream:       true,
+			wantText:     "from ollama",
+			wantProvider: "ollama",
+			wantSkipped:  1,
+		},
+		{
+			name:         "no fallback after streamed output",
+			primary:      providertest.Reply{Text: "partial", StreamError: "server_error"},
+			stream:       true,
+			wantText:     "partial",
+			wantProvider: "openai",
+			wantErr:      "server_error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				primary := providertest.NewServer(tt.primary)
+				defer primary.Close()
+				backup := providertest.NewServer(providertest.Reply{Text: "from ollama"})
+				defer backup.Close()
+
+				var notified []string
+				f := &Fallback{
+					Links: []FallbackLink{
+						{Provider: NewOpenAIProvider("sk-test-key", primary.URL), Model: "gpt-4o-mini"},
+						{Provider: NewOllamaProvider(backup.URL), Model: "llama3.1:8b"},
+					},
+					On:             tt.on,
+					AttemptTimeout: tt.attemptTimeout,
+					OnFallback: func(from, to FallbackLink, err error) {
+						notified = append(notified, from.String()+" -> "+to.String())
+					},
+				}
+
+				var text string
+				var err error
+				var streamed strings.Builder
+				if tt.stream {
+					text, _, _, err = f.Stream(
+						context.Background(), CompletionArgs{Prompt: "hi"},
+						func(d string) { streamed.WriteString(d) },
+					)
+				} else {
+					text, _, _, err = f.Complete(context.Background(), CompletionArgs{Prompt: "hi"})
+				}
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+					}
+				} else if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if text != tt.wantText {
+					t.Errorf("expected text %q, got %q", tt.wantText, text)
+				}
+				if tt.stream && streamed.String() != text {
+					t.Errorf("streamed %q but returned %q", streamed.String(), text)
+				}
+				if got := f.Answered().Provider.Name(); got != tt.wantProvider {
+					t.Errorf("expected %s to answer, got %s", tt.wantProvider, got)
+				}
+				if len(f.Skipped()) != tt.wantSkipped || len(notified) != tt.wantSkipped {
+					t.Errorf("expected %d fallbacks, got skipped=%v notified=%v", tt.wantSkipped, f.Skipped(), notified)
+				}
+				if tt.wantSkipped > 0 && backup.LastRequest().Body["model"] != "llama3.1:8b" {
+					t.Errorf("expected the fallback model in the request, got %v", backup.LastRequest().Body["model"])
+				}
+			},
+		)
+	}
+}
+
+func TestFallbackStaysWithAnsweringProvider(t *testing.T) {
+	primary := providertest.NewServer(
+		providertest.Reply{Status: 500, ErrorMessage: "down"}, providertest.Reply{Text: "recovered"},
+	)
+	defer primary.Close()
+	backup := providertest.NewServer(providertest.Reply{Text: "backup"})
+	defer backup.Close()
+
+	f := &Fallback{
+		Links: []FallbackLink{
+			{Provider: NewOpenAIProvider("sk-test-key", primary.URL), Model: "gpt-4o-mini"},
+			{Provider: NewOllamaProvider(backup.URL), Model: "llama3.1:8b"},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		text, _, _, err := f.Complete(context.Background(), CompletionArgs{Prompt: "hi"})
+		if err != nil || text != "backup" {
+			t.Fatalf("request %d: expected backup answer, got %q, %v", i+1, text, err)
+		}
+	}
+	if n := len(primary.Requests()); n != 1 {
+		t.Errorf("expected the primary to be tried once, got %d requests", n)
+	}
+}
@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MockScript scripts the responses of the mock provider. It is read from the JSON file
+// given as the provider's base URL; without a script the mock echoes its input.
+type MockScript struct {
+	// Rules are tried in order against the user message (prompt and data); the first
+	// match answers. Replies may reference capture groups as $1 or ${name}.
+	Rules []MockRule `json:"rules,omitempty"`
+	// Default is the reply when no rule matches. Empty echoes the user message.
+	Default string `json:"default,omitempty"`
+	// Usage, when set, is reported for every response instead of word-count estimates.
+	Usage *Usage `json:"usage,omitempty"`
+	// Latency is the delay before each streamed chunk (and before a non-streamed
+	// answer), e.g. "50ms".
+	Latency string `json:"latency,omitempty"`
+	// Models are listed by "nuro models"; defaults to just "mock".
+	Models []string `json:"models,omitempty"`
+
+	latency time.Duration
+}
+
+// MockRule maps a regular expression to a reply, or to a simulated API error.
+type MockRule struct {
+	Match        string `json:"match"`
+	Reply        string `json:"reply,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	// Status, when set, fails the request with this HTTP status and Reply as the body.
+	Status int `json:"status,omitempty"`
+
+	re *regexp.Regexp
+}
+
+type mockProvider struct {
+	script MockScript
+}
+
+// NewMockProvider builds the offline mock provider. scriptPath may be empty (echo mode)
+// or a JSON MockScript file, optionally written as a file:// URL.
+func NewMockProvider(scriptPath string) (Provider, error) {
+	p := &mockProvider{}
+	scriptPath = strings.TrimPrefix(scriptPath, "file://")
+	if scriptPath == "" {
+		return p, nil
+	}
+	b, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("mock script: %w", err)
+	}
+	if err := json.Unmarshal(b, &p.script); err != nil {
+		return nil, fmt.Errorf("mock script %s: %w", scriptPath, err)
+	}
+	if p.script.Latency != "" {
+		d, err := time.ParseDuration(p.script.Latency)
+		if err != nil {
+			return nil, fmt.Errorf("mock script %s: invalid latency %q", scriptPath, p.script.Latency)
+		}
+		p.script.latency = d
+	}
+	for i := range p.script.Rules {
+		re, err := regexp.Compile(p.script.Rules[i].Match)
+		if err != nil {
+			return nil, fmt.Errorf("mock script %s: rule %d: %w", scriptPath, i+1, err)
+		}
+		p.script.Rules[i].re = re
+	}
+	return p, nil
+}
+
+func (p *mockProvider) Name() string { return "mock" }
+
+// respond picks the scripted reply for args and applies the max_tokens limit, counting
+// words as tokens. A continuation request gets the words after the partial answer.
+func (p *mockProvider) respond(args CompletionArgs) (string, Usage, string, error) {
+	input := buildOllamaPrompt(args.Prompt, args.Data)
+	reply, finish := input, FinishStop
+	matched := false
+	for _, r := range p.script.Rules {
+		m := r.re.FindStringSubmatchIndex(input)
+		if m == nil {
+			continue
+		}
+		expanded := string(r.re.ExpandString(nil, r.Reply, input, m))
+		if r.Status != 0 {
+			return "", Usage{}, "", &APIError{
+				Op: "mock", StatusCode: r.Status,
+				Status: fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)), Body: expanded,
+			}
+		}
+		reply, matched = expanded, true
+		if r.FinishReason != "" {
+			finish = r.FinishReason
+		}
+		break
+	}
+	if !matched && p.script.Default != "" {
+		reply = p.script.Default
+	}
+
+	words := splitWords(reply)
+	if args.Continuation != "" {
+		done := len(splitWords(args.Continuation))
+		words = words[min(done, len(words)):]
+	}
+	if args.MaxTokens > 0 && len(words) > args.MaxTokens {
+		words = words[:args.MaxTokens]
+		finish = FinishLength
+	}
+	text := strings.Join(words, "")
+
+	usage := Usage{PromptTokens: len(strings.Fields(input)), CompletionTokens: len(words)}
+	if p.script.Usage != nil {
+		usage = *p.script.Usage
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return text, usage, finish, nil
+}
+
+func (p *mockProvider) Complete(ctx context.Context, args CompletionArgs) (string, Usage, string, error) {
+	if err := p.wait(ctx); err != nil {
+		return "", Usage{}, "", err
+	}
+	return p.respond(args)
+}
+
+func (p *mockProvider) Stream(ctx context.Context, args CompletionArgs, onDelta func(delta string)) (
+	string, Usage, string, error,
+) {
+	text, usage, finish, err := p.respond(args)
+	if err != nil {
+		return "", Usage{}, "", err
+	}
+	var sb strings.Builder
+	for _, w := range splitWords(text) {
+		if err := p.wait(ctx); err != nil {
+			return sb.String(), usage, finish, err
+		}
+		sb.WriteString(w)
+		onDelta(w)
+	}
+	return sb.String(), usage, finish, nil
+}
+
+func (p *mockProvider) wait(ctx context.Context) error {
+	if p.script.latency <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(p.script.latency)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ListModels lists the script's models, or just "mock".
+func (p *mockProvider) ListModels(context.Context) ([]ModelInfo, error) {
+	ids := p.script.Models
+	if len(ids) == 0 {
+		ids = []string{"mock"}
+	}
+	models := make([]ModelInfo, 0, len(ids))
+	for _, id := range ids {
+		models = append(models, ModelInfo{ID: id, OwnedBy: "nuro", Endpoint: "mock"})
+	}
+	return models, nil
+}
+
+// mockEmbeddingDims is the size of the mock's hashed bag-of-words embeddings.
+const mockEmbeddingDims = 64
+
+// Embed returns deterministic bag-of-words vectors, so texts sharing words are similar
+// and "nuro index" and --rag can be tried offline.
+func (p *mockProvider) Embed(_ context.Context, _ string, inputs []string) ([][]float64, Usage, error) {
+	out := make([][]float64, len(inputs))
+	var usage Usage
+	for i, in := range inputs {
+		v := make([]float64, mockEmbeddingDims)
+		for _, w := range strings.FieldsFunc(
+			strings.ToLower(in), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) },
+		) {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(w))
+			v[h.Sum32()%mockEmbeddingDims]++
+			usage.PromptTokens++
+		}
+		var norm float64
+		for _, x := range v {
+			norm += x * x
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range v {
+				v[j] /= norm
+			}
+		}
+		out[i] = v
+	}
+	usage.TotalTokens = usage.PromptTokens
+	return out, usage, nil
+}
+
+// splitWords splits s into words that keep their leading whitespace, so joining them
+// restores s exactly.
+func splitWords(s string) []string {
+	var words []string
+	start := 0
+	inWord := false
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if inWord {
+				words = append(words, s[start:i])
+				start = i
+				inWord = false
+			}
+			continue
+		}
+		inWord = true
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
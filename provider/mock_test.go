@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeMockScript(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mock.json")
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMockProviderReplies(t *testing.T) {
+	path := writeMockScript(
+		t, `{
+  "rules": [
+    {"match": "(?i)haiku about (\\w+)", "reply": "A haiku about $1."},
+    {"match": "overloaded", "status": 429, "reply": "rate limited"},
+    {"match": "filter me", "reply": "...", "finish_reason": "content_filter"}
+  ],
+  "default": "I only write haiku."
+}`,
+	)
+	prov, err := BuildProvider(&ProviderResolution{ProviderName: "mock", BaseURL: "file://" + path})
+	if err != nil {
+		t.Fatalf("BuildProvider: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		prompt     string
+		wantText   string
+		wantFinish string
+		wantStatus int
+	}{
+		{name: "rule with capture group", prompt: "write a Haiku about rain", wantText: "A haiku about rain.", wantFinish: FinishStop},
+		{name: "default reply", prompt: "hello", wantText: "I only write haiku.", wantFinish: FinishStop},
+		{name: "scripted finish reason", prompt: "filter me", wantText: "...", wantFinish: FinishContentFilter},
+		{name: "scripted API error", prompt: "overloaded", wantStatus: 429},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				text, usage, finish, err := prov.Complete(
+					context.Background(), CompletionArgs{Model: "mock", Prompt: tt.prompt},
+				)
+				if tt.wantStatus != 0 {
+					var apiErr *APIError
+					if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus || apiErr.Body != "rate limited" {
+						t.Fatalf("expected API error %d, got %v", tt.wantStatus, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Complete: %v", err)
+				}
+				if text != tt.wantText || finish != tt.wantFinish {
+					t.Errorf("got %q (%s), want %q (%s)", text, finish, tt.wantText, tt.wantFinish)
+				}
+				if usage.CompletionTokens != len(strings.Fields(tt.wantText)) || usage.TotalTokens == 0 {
+					t.Errorf("unexpected estimated usage %+v", usage)
+				}
+			},
+		)
+	}
+}
+
+func TestMockProviderEchoStreamAndLimits(t *testing.T) {
+	prov, err := NewMockProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deltas []string
+	text, _, finish, err := prov.Stream(
+		context.Background(), CompletionArgs{Prompt: "count words", Data: "one two"},
+		func(d string) { deltas = append(deltas, d) },
+	)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	want := buildOllamaPrompt("count words", "one two")
+	if text != want || strings.Join(deltas, "") != want || len(deltas) < 3 || finish != FinishStop {
+		t.Errorf("echo stream: %q in %d deltas (%s), want %q", text, len(deltas), finish, want)
+	}
+
+	// max_tokens truncates by words, and a continuation picks up where it stopped
+	args := CompletionArgs{Prompt: "a b c d e", MaxTokens: 3}
+	part, _, finish, _ := prov.Complete(context.Background(), args)
+	if part != "a b c" || finish != FinishLength {
+		t.Fatalf("truncated: %q (%s)", part, finish)
+	}
+	args.Continuation = part
+	rest, _, finish, _ := prov.Complete(context.Background(), args)
+	if part+rest != "a b c d e" || finish != FinishStop {
+		t.Errorf("continuation: %q (%s)", rest, finish)
+	}
+}
+
+func TestMockProviderFixedUsageAndLatency(t *testing.T) {
+	path := writeMockScript(t, `{"usage": {"prompt_tokens": 10, "completion_tokens": 5}, "latency": "20ms"}`)
+	prov, err := NewMockProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, usage, _, err := prov.Stream(context.Background(), CompletionArgs{Prompt: "one two three"}, func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("expected a delay per chunk, took %v", elapsed)
+	}
+	if usage.PromptTokens != 10 || usage.CompletionTokens != 5 || usage.TotalTokens != 15 {
+		t.Errorf("unexpected fixed usage %+v", usage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, _, _, err := prov.Stream(ctx, CompletionArgs{Prompt: "a b c d e f"}, func(string) {}); err == nil {
+		t.Error("expected the stream to stop at the context deadline")
+	}
+}
+
+func TestMockProviderInvalidScript(t *testing.T) {
+	for _, script := range []string{`{"rules": [{"match": "("}]}`, `{"latency": "soon"}`, `not json`} {
+		if _, err := NewMockProvider(writeMockScript(t, script)); err == nil {
+			t.Errorf("expected an error for %s", script)
+		}
+	}
+}
+
+func TestMockProviderEmbed(t *testing.T) {
+	prov, _ := NewMockProvider("")
+	vecs, _, err := prov.(Embedder).Embed(
+		context.Background(), "mock", []string{"rotate the api keys", "how to rotate keys", "banana bread"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := func(a, b []float64) (s float64) {
+		for i := range a {
+			s += a[i] * b[i]
+		}
+		return s
+	}
+	if dot(vecs[0], vecs[1]) <= dot(vecs[0], vecs[2]) {
+		t.Error("texts sharing words should be more similar")
+	}
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIListModelsMarksResponsesEndpoint(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/models" {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte(`{"data":[{"id":"gpt-4o-mini","owned_by":"system"},{"id":"gpt-5","owned_by":"system"}]}`))
+			},
+		),
+	)
+	defer srv.Close()
+
+	models, err := NewOpenAIProvider("k", srv.URL).(ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("expected 2 models, got %d", len(models))
+	}
+	if models[0].Endpoint != "/chat/completions" || models[1].Endpoint != "/responses" {
+		t.Errorf("unexpected endpoints: %+v", models)
+	}
+}
+
+func TestOllamaListModels(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/tags" {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte(`{"models":[{"name":"llama3.1:8b","size":4920753328,"details":{"family":"llama","parameter_size":"8.0B","quantization_level":"Q4_0"}}]}`))
+			},
+		),
+	)
+	defer srv.Close()
+
+	models, err := NewOllamaProvider(srv.URL).(ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 1 || models[0].ID != "llama3.1:8b" || models[0].Details != "llama 8.0B Q4_0" {
+		t.Errorf("unexpected models: %+v", models)
+	}
+}
+
+func TestAPIErrorNotFound(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+			},
+		),
+	)
+	defer srv.Close()
+
+	_, _, _, err := NewOllamaProvider(srv.URL).Complete(
+		context.Background(), CompletionArgs{Model: "nope"},
+	)
+	if !IsNotFound(err) {
+		t.Fatalf("expected not-found APIError, got %v", err)
+	}
+	if err.Error() != `ollama error: 404 Not Found - {"error":"model not found"}` {
+		t.Errorf("unexpected error message: %q", err.Error())
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 )
 
 type ollamaProvider struct {
@@ -23,128 +25,212 @@ func NewOllamaProvider(baseURL string) Provider {
 	}
 	return &ollamaProvider{
 		baseURL: strings.TrimRight(baseURL, "/"),
-		client:  &http.Client{Timeout: 0}, // use context timeouts per request
+		client:  newHTTPClient(),
 	}
 }
 
 func (p *ollamaProvider) Name() string { return "ollama" }
 
+// ollamaOptions are the model parameters accepted by both /api/generate and /api/chat.
+type ollamaOptions struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          float64  `json:"top_p,omitempty"`
+	NumPredict    int      `json:"num_predict,omitempty"`
+	NumCtx        int      `json:"num_ctx,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+	RepeatPenalty float64  `json:"repeat_penalty,omitempty"`
+	TopK          int      `json:"top_k,omitempty"`
+	MinP          float64  `json:"min_p,omitempty"`
+	Mirostat      int      `json:"mirostat,omitempty"`
+}
+
 type ollamaGenerateRequest struct {
-	Model    string `json:"model"`
-	Prompt   string `json:"prompt"`
-	Stream   bool   `json:"stream"`
-	System   string `json:"system,omitempty"`
-	Template string `json:"template,omitempty"`
-	Context  []int  `json:"context,omitempty"`
-	Options  struct {
-		Temperature float64 `json:"temperature,omitempty"`
-		TopP        float64 `json:"top_p,omitempty"`
-		NumPredict  int     `json:"num_predict,omitempty"`
-	} `json:"options,omitempty"`
+	Model     string        `json:"model"`
+	Prompt    string        `json:"prompt"`
+	Stream    bool          `json:"stream"`
+	System    string        `json:"system,omitempty"`
+	Template  string        `json:"template,omitempty"`
+	Context   []int         `json:"context,omitempty"`
+	KeepAlive any           `json:"keep_alive,omitempty"`
+	Options   ollamaOptions `json:"options,omitempty"`
+}
+
+type ollamaChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model     string              `json:"model"`
+	Messages  []ollamaChatMessage `json:"messages"`
+	Stream    bool                `json:"stream"`
+	KeepAlive any                 `json:"keep_alive,omitempty"`
+	Options   ollamaOptions       `json:"options,omitempty"`
 }
 
+// ollamaGenerateResponse decodes both /api/generate and /api/chat responses; the
+// generated text is in Response for generate and Message.Content for chat.
 type ollamaGenerateResponse struct {
-	Model              string `json:"model"`
-	CreatedAt          string `json:"created_at"`
-	Response           string `json:"response"`
-	Done               bool   `json:"done"`
-	Context            []int  `json:"context,omitempty"`
-	TotalDuration      int64  `json:"total_duration,omitempty"`
-	LoadDuration       int64  `json:"load_duration,omitempty"`
-	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
-	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
-	EvalCount          int    `json:"eval_count,omitempty"`
-	EvalDuration       int64  `json:"eval_duration,omitempty"`
+	Model              string             `json:"model"`
+	CreatedAt          string             `json:"created_at"`
+	Response           string             `json:"response"`
+	Message            *ollamaChatMessage `json:"message,omitempty"`
+	Done               bool               `json:"done"`
+	DoneReason         string             `json:"done_reason,omitempty"`
+	Context            []int              `json:"context,omitempty"`
+	TotalDuration      int64              `json:"total_duration,omitempty"`
+	LoadDuration       int64              `json:"load_duration,omitempty"`
+	PromptEvalCount    int                `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64              `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int                `json:"eval_count,omitempty"`
+	EvalDuration       int64              `json:"eval_duration,omitempty"`
+	// Error is set on the last line when generation fails mid-stream
+	Error string `json:"error,omitempty"`
 }
 
-func (p *ollamaProvider) Complete(ctx context.Context, args CompletionArgs) (
-	string,
-	Usage, error,
-) {
-	prompt := buildOllamaPrompt(args.Prompt, args.Data)
+func (r *ollamaGenerateResponse) text() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return r.Response
+}
 
-	body := ollamaGenerateRequest{
-		Model:  args.Model,
-		Prompt: prompt,
-		Stream: false,
+// finishReason normalizes done_reason ("stop", "length", "load", ...) to a Finish* value
+func (r *ollamaGenerateResponse) finishReason() string {
+	if r.DoneReason == "length" {
+		return FinishLength
 	}
+	return FinishStop
+}
+
+// usage converts Ollama's token counts and timings to Usage format
+func (r *ollamaGenerateResponse) usage() Usage {
+	return Usage{
+		PromptTokens:       r.PromptEvalCount,
+		CompletionTokens:   r.EvalCount,
+		TotalTokens:        r.PromptEvalCount + r.EvalCount,
+		TotalDuration:      time.Duration(r.TotalDuration),
+		LoadDuration:       time.Duration(r.LoadDuration),
+		PromptEvalDuration: time.Duration(r.PromptEvalDuration),
+		EvalDuration:       time.Duration(r.EvalDuration),
+	}
+}
 
-	// Set options if provided
-	if args.Temperature != 0 {
-		body.Options.Temperature = args.Temperature
+func buildOllamaOptions(args CompletionArgs) ollamaOptions {
+	// Zero values are omitted so the model's own defaults apply, except for the
+	// temperature, where 0 asks for deterministic output
+	return ollamaOptions{
+		Temperature:   &args.Temperature,
+		TopP:          args.TopP,
+		NumPredict:    args.MaxTokens,
+		NumCtx:        args.NumCtx,
+		Seed:          args.Seed,
+		Stop:          args.Stop,
+		RepeatPenalty: args.RepeatPenalty,
+		TopK:          args.TopK,
+		MinP:          args.MinP,
+		Mirostat:      args.Mirostat,
 	}
-	if args.TopP != 0 {
-		body.Options.TopP = args.TopP
+}
+
+// buildOllamaRequest returns the endpoint path and body for args, using /api/chat with
+// role messages when args.OllamaAPI is "chat" and /api/generate otherwise.
+func buildOllamaRequest(args CompletionArgs, stream bool) (string, any) {
+	if args.OllamaAPI == "chat" {
+		var msgs []ollamaChatMessage
+		if s := strings.TrimSpace(args.System); s != "" {
+			msgs = append(msgs, ollamaChatMessage{Role: "system", Content: s})
+		}
+		msgs = append(
+			msgs, ollamaChatMessage{Role: "user", Content: buildOllamaPrompt(args.Prompt, args.Data)},
+		)
+		if args.Continuation != "" {
+			msgs = append(
+				msgs,
+				ollamaChatMessage{Role: "assistant", Content: args.Continuation},
+				ollamaChatMessage{Role: "user", Content: continuePrompt},
+			)
+		}
+		return "/api/chat", ollamaChatRequest{
+			Model:     args.Model,
+			Messages:  msgs,
+			Stream:    stream,
+			KeepAlive: ollamaKeepAlive(args.KeepAlive),
+			Options:   buildOllamaOptions(args),
+		}
+	}
+	prompt := buildOllamaPrompt(args.Prompt, args.Data)
+	if args.Continuation != "" {
+		// /api/generate has no roles, so the partial answer is quoted in the prompt
+		prompt = fmt.Sprintf(
+			"%s\n\nYour previous answer was cut off:\n%s\n\n%s", prompt, fenced(args.Continuation),
+			continuePrompt,
+		)
 	}
-	if args.MaxTokens != 0 {
-		body.Options.NumPredict = args.MaxTokens
+	return "/api/generate", ollamaGenerateRequest{
+		Model:     args.Model,
+		Prompt:    prompt,
+		Stream:    stream,
+		System:    strings.TrimSpace(args.System),
+		KeepAlive: ollamaKeepAlive(args.KeepAlive),
+		Options:   buildOllamaOptions(args),
 	}
+}
 
+func (p *ollamaProvider) Complete(ctx context.Context, args CompletionArgs) (
+	string,
+	Usage, string, error,
+) {
+	path, body := buildOllamaRequest(args, false)
 	buf, _ := json.Marshal(body)
 
+	if vb, _ := ctx.Value("nuro_verbose").(bool); vb {
+		_, _ = fmt.Fprintf(os.Stderr, "nuro: ollama: using %s for model=%s\n", path, args.Model)
+	}
+
 	req, err := http.NewRequestWithContext(
-		ctx, "POST", p.baseURL+"/api/generate", bytes.NewReader(buf),
+		ctx, "POST", p.baseURL+path, bytes.NewReader(buf),
 	)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return "", Usage{}, fmt.Errorf("ollama error: %s - %s", resp.Status, trimBody(b))
+		return "", Usage{}, "", newAPIError("ollama", resp, b)
 	}
 
 	var r ollamaGenerateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 
-	// Convert Ollama's token counts to Usage format
-	usage := Usage{
-		PromptTokens:     r.PromptEvalCount,
-		CompletionTokens: r.EvalCount,
-		TotalTokens:      r.PromptEvalCount + r.EvalCount,
-	}
-
-	return r.Response, usage, nil
+	return r.text(), r.usage(), r.finishReason(), nil
 }
 
 func (p *ollamaProvider) Stream(
 	ctx context.Context, args CompletionArgs, onDelta func(string),
-) (string, Usage, error) {
-	prompt := buildOllamaPrompt(args.Prompt, args.Data)
+) (string, Usage, string, error) {
+	path, body := buildOllamaRequest(args, true)
+	buf, _ := json.Marshal(body)
 
-	body := ollamaGenerateRequest{
-		Model:  args.Model,
-		Prompt: prompt,
-		Stream: true,
+	if vb, _ := ctx.Value("nuro_verbose").(bool); vb {
+		_, _ = fmt.Fprintf(os.Stderr, "nuro: ollama: streaming %s for model=%s\n", path, args.Model)
 	}
 
-	// Set options if provided
-	if args.Temperature != 0 {
-		body.Options.Temperature = args.Temperature
-	}
-	if args.TopP != 0 {
-		body.Options.TopP = args.TopP
-	}
-	if args.MaxTokens != 0 {
-		body.Options.NumPredict = args.MaxTokens
-	}
-
-	buf, _ := json.Marshal(body)
-
 	req, err := http.NewRequestWithContext(
-		ctx, "POST", p.baseURL+"/api/generate", bytes.NewReader(buf),
+		ctx, "POST", p.baseURL+path, bytes.NewReader(buf),
 	)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
@@ -155,22 +241,23 @@ func (p *ollamaProvider) Stream(
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return "", Usage{}, fmt.Errorf("ollama error: %s - %s", resp.Status, trimBody(b))
+		return "", Usage{}, "", newAPIError("ollama", resp, b)
 	}
 
 	reader := bufio.NewReader(resp.Body)
 	var total strings.Builder
 	var finalUsage Usage
+	var finish string
 	for {
 		// Check context cancellation before reading
 		if ctx.Err() != nil {
-			return total.String(), finalUsage, ctx.Err()
+			return total.String(), finalUsage, finish, ctx.Err()
 		}
 
 		line, err := reader.ReadString('\n')
@@ -179,18 +266,20 @@ func (p *ollamaProvider) Stream(
 			if line != "" {
 				var chunk ollamaGenerateResponse
 				if err := json.Unmarshal([]byte(line), &chunk); err == nil {
-					if chunk.Response != "" {
-						onDelta(chunk.Response)
-						total.WriteString(chunk.Response)
+					if chunk.Error != "" {
+						return total.String(), finalUsage, finish, &APIError{
+							Op: "ollama", Status: streamErrorStatus, Body: chunk.Error,
+						}
+					}
+					if t := chunk.text(); t != "" {
+						onDelta(t)
+						total.WriteString(t)
 					}
 
 					// If this is the final chunk, capture usage info
 					if chunk.Done {
-						finalUsage = Usage{
-							PromptTokens:     chunk.PromptEvalCount,
-							CompletionTokens: chunk.EvalCount,
-							TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
-						}
+						finalUsage = chunk.usage()
+						finish = chunk.finishReason()
 						break
 					}
 				}
@@ -201,7 +290,7 @@ func (p *ollamaProvider) Stream(
 		if err != nil {
 			// Check for context cancellation first
 			if ctx.Err() != nil {
-				return total.String(), finalUsage, ctx.Err()
+				return total.String(), finalUsage, finish, ctx.Err()
 			}
 
 			// Handle EOF conditions
@@ -213,11 +302,116 @@ func (p *ollamaProvider) Stream(
 			}
 
 			// Return other errors
-			return total.String(), finalUsage, err
+			return total.String(), finalUsage, finish, err
 		}
 	}
 
-	return total.String(), finalUsage, nil
+	return total.String(), finalUsage, finish, nil
+}
+
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float64 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+}
+
+func (p *ollamaProvider) Embed(ctx context.Context, model string, inputs []string) (
+	[][]float64, Usage, error,
+) {
+	buf, _ := json.Marshal(ollamaEmbedRequest{Model: model, Input: inputs})
+
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", p.baseURL+"/api/embed", bytes.NewReader(buf),
+	)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, Usage{}, newAPIError("ollama embed", resp, b)
+	}
+
+	var r ollamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, Usage{}, err
+	}
+	if len(r.Embeddings) != len(inputs) {
+		return nil, Usage{}, fmt.Errorf(
+			"ollama embed: expected %d vectors, got %d", len(inputs), len(r.Embeddings),
+		)
+	}
+
+	usage := Usage{PromptTokens: r.PromptEvalCount, TotalTokens: r.PromptEvalCount}
+	return r.Embeddings, usage, nil
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name    string `json:"name"`
+		Size    int64  `json:"size"`
+		Details struct {
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+	} `json:"models"`
+}
+
+func (p *ollamaProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("ollama tags", resp, b)
+	}
+
+	var r ollamaTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(r.Models))
+	for _, m := range r.Models {
+		var details []string
+		for _, d := range []string{
+			m.Details.Family, m.Details.ParameterSize, m.Details.QuantizationLevel,
+		} {
+			if d != "" {
+				details = append(details, d)
+			}
+		}
+		models = append(
+			models, ModelInfo{
+				ID:       m.Name,
+				Size:     m.Size,
+				Details:  strings.Join(details, " "),
+				Endpoint: "/api/generate",
+			},
+		)
+	}
+	return models, nil
 }
 
 // buildOllamaPrompt creates a prompt for Ollama's native format
@@ -227,7 +421,7 @@ func buildOllamaPrompt(prompt, data string) string {
 
 	// When both prompt and data are present, combine them naturally
 	if p != "" && d != "" {
-		return fmt.Sprintf("%s\n\nData:\n```\n%s\n```", p, d)
+		return fmt.Sprintf("%s\n\nData:\n%s", p, fenced(d))
 	}
 
 	// If only prompt, use it directly
@@ -237,7 +431,7 @@ func buildOllamaPrompt(prompt, data string) string {
 
 	// If only data, present it clearly
 	if d != "" {
-		return fmt.Sprintf("Here is some data to analyze:\n\n```\n%s\n```", d)
+		return fmt.Sprintf("Here is some data to analyze:\n\n%s", fenced(d))
 	}
 
 	// If both are empty, still send an empty string
@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OllamaAdmin exposes the model lifecycle endpoints of the native Ollama API.
+type OllamaAdmin interface {
+	Pull(ctx context.Context, model string, onProgress func(PullProgress)) error
+	Show(ctx context.Context, model string) (*OllamaModelDetails, error)
+	Running(ctx context.Context) ([]OllamaRunningModel, error)
+	Delete(ctx context.Context, model string) error
+	Unload(ctx context.Context, model string) error
+}
+
+// PullProgress is one status line streamed by /api/pull.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// OllamaModelDetails is the subset of /api/show that nuro reports.
+type OllamaModelDetails struct {
+	License    string `json:"license,omitempty"`
+	Modelfile  string `json:"modelfile,omitempty"`
+	Parameters string `json:"parameters,omitempty"`
+	Template   string `json:"template,omitempty"`
+	Details    struct {
+		Format            string `json:"format,omitempty"`
+		Family            string `json:"family,omitempty"`
+		ParameterSize     string `json:"parameter_size,omitempty"`
+		QuantizationLevel string `json:"quantization_level,omitempty"`
+	} `json:"details"`
+	ModelInfo    map[string]any `json:"model_info,omitempty"`
+	Capabilities []string       `json:"capabilities,omitempty"`
+}
+
+// ContextLength returns the model's trained context length from model_info, if reported.
+func (d *OllamaModelDetails) ContextLength() int {
+	for k, v := range d.ModelInfo {
+		if strings.HasSuffix(k, ".context_length") {
+			if f, ok := v.(float64); ok {
+				return int(f)
+			}
+		}
+	}
+	return 0
+}
+
+// OllamaRunningModel is a model currently loaded in memory, as reported by /api/ps.
+type OllamaRunningModel struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SizeVRAM  int64     `json:"size_vram"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (p *ollamaProvider) postJSON(ctx context.Context, method, path string, body any) (
+	*http.Response, error,
+) {
+	var r io.Reader
+	if body != nil {
+		buf, _ := json.Marshal(body)
+		r = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return p.client.Do(req)
+}
+
+// Pull downloads a model, reporting each streamed status line to onProgress.
+func (p *ollamaProvider) Pull(ctx context.Context, model string, onProgress func(PullProgress)) error {
+	resp, err := p.postJSON(
+		ctx, "POST", "/api/pull", map[string]any{"model": model, "stream": true},
+	)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return newAPIError("ollama pull", resp, b)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if l := strings.TrimSpace(line); l != "" {
+			var pp PullProgress
+			if e := json.Unmarshal([]byte(l), &pp); e == nil {
+				if pp.Error != "" {
+					return fmt.Errorf("ollama pull %s: %s", model, pp.Error)
+				}
+				if onProgress != nil {
+					onProgress(pp)
+				}
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Show returns details about a local model.
+func (p *ollamaProvider) Show(ctx context.Context, model string) (*OllamaModelDetails, error) {
+	resp, err := p.postJSON(ctx, "POST", "/api/show", map[string]any{"model": model})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("ollama show", resp, b)
+	}
+
+	var d OllamaModelDetails
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Running lists the models currently loaded into memory.
+func (p *ollamaProvider) Running(ctx context.Context) ([]OllamaRunningModel, error) {
+	resp, err := p.postJSON(ctx, "GET", "/api/ps", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("ollama ps", resp, b)
+	}
+
+	var r struct {
+		Models []OllamaRunningModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return r.Models, nil
+}
+
+// Delete removes a model from local storage.
+func (p *ollamaProvider) Delete(ctx context.Context, model string) error {
+	resp, err := p.postJSON(ctx, "DELETE", "/api/delete", map[string]any{"model": model})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return newAPIError("ollama delete", resp, b)
+	}
+	return nil
+}
+
+// Unload evicts a model from memory by sending an empty request with keep_alive=0.
+func (p *ollamaProvider) Unload(ctx context.Context, model string) error {
+	resp, err := p.postJSON(
+		ctx, "POST", "/api/generate", map[string]any{"model": model, "keep_alive": 0},
+	)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return newAPIError("ollama unload", resp, b)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// ollamaKeepAlive converts a keep_alive flag value to the JSON Ollama expects:
+// plain integers are seconds (e.g. "-1" keeps the model loaded forever), anything
+// else is passed through as a duration string (e.g. "10m").
+func ollamaKeepAlive(s string) any {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i
+	}
+	return s
+}
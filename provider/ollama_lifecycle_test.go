@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaKeepAlive(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected any
+	}{
+		{"", nil},
+		{"-1", -1},
+		{"0", 0},
+		{"10m", "10m"},
+		{" 1h ", "1h"},
+	}
+	for _, tt := range tests {
+		if got := ollamaKeepAlive(tt.in); got != tt.expected {
+			t.Errorf("ollamaKeepAlive(%q) = %#v, expected %#v", tt.in, got, tt.expected)
+		}
+	}
+}
+
+func TestOllamaPullReportsStreamError(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("{\"status\":\"pulling manifest\"}\n{\"error\":\"pull model manifest: file does not exist\"}\n"))
+			},
+		),
+	)
+	defer srv.Close()
+
+	admin := NewOllamaProvider(srv.URL).(OllamaAdmin)
+	err := admin.Pull(context.Background(), "nope", nil)
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Fatalf("expected streamed pull error, got %v", err)
+	}
+}
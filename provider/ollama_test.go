@@ -1,7 +1,13 @@
 package provider
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestOllamaProviderName(t *testing.T) {
@@ -85,6 +91,12 @@ func TestBuildOllamaPrompt(t *testing.T) {
 			data:           "Hello world",
 			expectedResult: "translate to Spanish\n\nData:\n```\nHello world\n```",
 		},
+		{
+			name:           "Labeled sources",
+			prompt:         "compare",
+			data:           "2 sources:\n\n```a.yaml\nx: 1\n```\n\n```b.yaml\nx: 2\n```",
+			expectedResult: "compare\n\nData:\n````\n2 sources:\n\n```a.yaml\nx: 1\n```\n\n```b.yaml\nx: 2\n```\n````",
+		},
 	}
 
 	for _, tt := range tests {
@@ -122,3 +134,82 @@ func TestOllamaProviderBuild(t *testing.T) {
 		t.Errorf("Expected provider name 'ollama', got '%s'", provider.Name())
 	}
 }
+
+func TestOllamaChatStreamWithOptions(t *testing.T) {
+	var gotPath string
+	var gotBody ollamaChatRequest
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				b, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(b, &gotBody)
+				_, _ = w.Write(
+					[]byte(`{"message":{"role":"assistant","content":"Hel"},"done":false}
+{"message":{"role":"assistant","content":"lo"},"done":false}
+{"message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":5,"eval_count":2,"total_duration":3000000000,"eval_duration":1000000000}
+`),
+				)
+			},
+		),
+	)
+	defer srv.Close()
+
+	seed := 42
+	args := CompletionArgs{
+		Model:     "llama3.1:8b",
+		Prompt:    "say hello",
+		System:    "be brief",
+		OllamaAPI: "chat",
+		Seed:      &seed,
+		Stop:      []string{"\n\n"},
+		NumCtx:    8192,
+		TopK:      40,
+		MinP:      0.05,
+		Mirostat:  2,
+	}
+	var deltas []string
+	total, usage, finish, err := NewOllamaProvider(srv.URL).Stream(
+		context.Background(), args, func(d string) { deltas = append(deltas, d) },
+	)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	if gotPath != "/api/chat" {
+		t.Errorf("expected /api/chat, got %s", gotPath)
+	}
+	if len(gotBody.Messages) != 2 || gotBody.Messages[0].Role != "system" ||
+		gotBody.Messages[1].Role != "user" || gotBody.Messages[1].Content != "say hello" {
+		t.Errorf("unexpected messages: %+v", gotBody.Messages)
+	}
+	o := gotBody.Options
+	if o.Seed == nil || *o.Seed != 42 || o.NumCtx != 8192 || o.TopK != 40 || o.MinP != 0.05 ||
+		o.Mirostat != 2 || len(o.Stop) != 1 {
+		t.Errorf("options not passed through: %+v", o)
+	}
+
+	if finish != FinishLength {
+		t.Errorf("expected finish reason length, got %q", finish)
+	}
+	if total != "Hello" || len(deltas) != 2 {
+		t.Errorf("unexpected stream result %q (%d deltas)", total, len(deltas))
+	}
+	if usage.TotalTokens != 7 || usage.TotalDuration != 3*time.Second || usage.EvalDuration != time.Second {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if usage.TokensPerSecond() != 2 {
+		t.Errorf("expected 2 tokens/s, got %v", usage.TokensPerSecond())
+	}
+}
+
+func TestOllamaGenerateIsDefault(t *testing.T) {
+	path, body := buildOllamaRequest(CompletionArgs{Model: "m", Prompt: "p", System: "s"}, false)
+	if path != "/api/generate" {
+		t.Errorf("expected /api/generate, got %s", path)
+	}
+	req := body.(ollamaGenerateRequest)
+	if req.System != "s" || req.Prompt != "p" {
+		t.Errorf("unexpected generate request %+v", req)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -25,7 +26,7 @@ func NewOpenAIProvider(apiKey, baseURL string) Provider {
 	return &openAIProvider{
 		apiKey:  apiKey,
 		baseURL: strings.TrimRight(baseURL, "/"),
-		client:  &http.Client{Timeout: 0}, // use context timeouts per request
+		client:  newHTTPClient(),
 	}
 }
 
@@ -40,19 +41,39 @@ type oaChatRequest struct {
 	Model       string      `json:"model"`
 	Messages    []oaChatMsg `json:"messages"`
 	MaxTokens   int         `json:"max_tokens,omitempty"`
-	Temperature float64     `json:"temperature,omitempty"`
+	Temperature *float64    `json:"temperature,omitempty"` // nil for reasoning models; 0 is sent
 	TopP        float64     `json:"top_p,omitempty"`
+	Seed        *int        `json:"seed,omitempty"`
+	Stop        []string    `json:"stop,omitempty"`
 	Stream      bool        `json:"stream,omitempty"`
+
+	StreamOptions *oaStreamOptions `json:"stream_options,omitempty"`
+
+	// Reasoning models take max_completion_tokens and reasoning_effort instead
+	MaxCompletionTokens int    `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string `json:"reasoning_effort,omitempty"`
+	Verbosity           string `json:"verbosity,omitempty"`
 }
 
 // Responses API request shape (simplified)
 type oaResponsesRequest struct {
-	Model           string  `json:"model"`
-	Input           string  `json:"input"`
-	MaxOutputTokens int     `json:"max_output_tokens,omitempty"`
-	Temperature     float64 `json:"temperature,omitempty"`
-	TopP            float64 `json:"top_p,omitempty"`
-	Stream          bool    `json:"stream,omitempty"`
+	Model           string           `json:"model"`
+	Instructions    string           `json:"instructions,omitempty"`
+	Input           any              `json:"input"` // string, or []oaChatMsg for continuations
+	MaxOutputTokens int              `json:"max_output_tokens,omitempty"`
+	Temperature     *float64         `json:"temperature,omitempty"`
+	TopP            float64          `json:"top_p,omitempty"`
+	Reasoning       *oaReasoning     `json:"reasoning,omitempty"`
+	Text            *oaResponsesText `json:"text,omitempty"`
+	Stream          bool             `json:"stream,omitempty"`
+}
+
+type oaReasoning struct {
+	Effort string `json:"effort,omitempty"`
+}
+
+type oaResponsesText struct {
+	Verbosity string `json:"verbosity,omitempty"`
 }
 
 type oaUsage struct {
@@ -61,11 +82,20 @@ type oaUsage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+func (u *oaUsage) toUsage() Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
 type oaChoice struct {
 	Message struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
 	} `json:"message"`
+	FinishReason string `json:"finish_reason"`
 }
 
 type oaResp struct {
@@ -86,26 +116,120 @@ type oaStreamChoice struct {
 
 type oaStreamChunk struct {
 	Choices []oaStreamChoice `json:"choices"`
+	Usage   *oaUsage         `json:"usage,omitempty"` // final chunk when include_usage is set
+	// Error is set when the server fails after the stream has started
+	Error *struct {
+		Message string `json:"message"`
+		Code    any    `json:"code,omitempty"`
+	} `json:"error,omitempty"`
 }
 
-// Simplified Responses API streaming chunk
-type oaResponsesStreamChunk struct {
-	Output []struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text,omitempty"`
-		} `json:"content"`
-	} `json:"output"`
+type oaStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
-// Simplified Responses API non-stream response
+type oaResponsesContent struct {
+	Type    string `json:"type"`
+	Text    string `json:"text,omitempty"`
+	Refusal string `json:"refusal,omitempty"`
+}
+
+type oaResponsesOutput struct {
+	Type    string               `json:"type"`
+	Content []oaResponsesContent `json:"content,omitempty"`
+}
+
+type oaResponsesUsage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+type oaResponsesError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Responses API response object (also embedded in response.* stream events)
 type oaResponsesResp struct {
-	Output []struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text,omitempty"`
-		} `json:"content"`
-	} `json:"output"`
+	Status            string              `json:"status"`
+	Output            []oaResponsesOutput `json:"output"`
+	Usage             *oaResponsesUsage   `json:"usage,omitempty"`
+	Error             *oaResponsesError   `json:"error,omitempty"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details,omitempty"`
+}
+
+// outputText concatenates the output_text parts of all message output items,
+// skipping reasoning and tool-call items.
+func (r *oaResponsesResp) outputText() string {
+	var sb strings.Builder
+	for _, out := range r.Output {
+		if out.Type != "" && out.Type != "message" {
+			continue
+		}
+		for _, c := range out.Content {
+			switch c.Type {
+			case "output_text", "":
+				sb.WriteString(c.Text)
+			case "refusal":
+				sb.WriteString(c.Refusal)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// finishReason maps the response status onto the chat-completions finish reasons
+func (r *oaResponsesResp) finishReason() string {
+	for _, out := range r.Output {
+		if out.Type == "function_call" || out.Type == "custom_tool_call" {
+			return FinishToolCalls
+		}
+	}
+	if r.Status == "incomplete" && r.IncompleteDetails != nil {
+		switch r.IncompleteDetails.Reason {
+		case "max_output_tokens":
+			return FinishLength
+		case "content_filter":
+			return FinishContentFilter
+		}
+	}
+	return FinishStop
+}
+
+func (r *oaResponsesResp) usage() Usage {
+	if r.Usage == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens:     r.Usage.InputTokens,
+		CompletionTokens: r.Usage.OutputTokens,
+		TotalTokens:      r.Usage.TotalTokens,
+		ReasoningTokens:  r.Usage.OutputTokensDetails.ReasoningTokens,
+	}
+}
+
+// Typed server-sent event from a streaming Responses API call
+type oaResponsesEvent struct {
+	Type     string           `json:"type"`
+	Delta    string           `json:"delta,omitempty"`
+	Response *oaResponsesResp `json:"response,omitempty"`
+	// Set on "error" events
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func responsesError(e *oaResponsesError) error {
+	code := e.Code
+	if code == "" {
+		code = "error"
+	}
+	return &APIError{Op: "openai responses", Status: code, Body: e.Message}
 }
 
 // Decide which models should use the Responses API
@@ -124,36 +248,84 @@ func modelUsesResponsesAPI(model string) bool {
 	return false
 }
 
-// Some Responses API models (observed: gpt-5 family) don't accept sampling params
+// isReasoningModel reports whether model is a gpt-5 or o-series reasoning model that
+// accepts reasoning effort instead of sampling params.
+func isReasoningModel(model string) bool {
+	m := strings.TrimSpace(strings.ToLower(model))
+	for _, p := range []string{"gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(m, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reasoning models (gpt-5 family, o-series) don't accept sampling params
 // like temperature/top_p. Return true when the model supports sampling.
 func responsesSupportsSampling(model string) bool {
-	m := strings.TrimSpace(strings.ToLower(model))
-	if m == "" {
-		return true
+	return !isReasoningModel(model)
+}
+
+func buildChatRequest(args CompletionArgs, stream bool) oaChatRequest {
+	body := oaChatRequest{
+		Model:    args.Model,
+		Messages: chatMessages(args),
+		Seed:     args.Seed,
+		Stop:     args.Stop,
+		Stream:   stream,
 	}
-	// If model is gpt-5 family, don't send sampling params
-	if strings.HasPrefix(m, "gpt-5") {
-		return false
+	if stream {
+		// Ask for a final usage chunk so streamed calls report token counts
+		body.StreamOptions = &oaStreamOptions{IncludeUsage: true}
+	}
+	if isReasoningModel(args.Model) {
+		body.MaxCompletionTokens = args.MaxTokens
+		body.ReasoningEffort = args.ReasoningEffort
+		body.Verbosity = args.Verbosity
+	} else {
+		body.MaxTokens = args.MaxTokens
+		body.Temperature = &args.Temperature
+		body.TopP = args.TopP
+	}
+	return body
+}
+
+func buildResponsesRequest(args CompletionArgs, stream bool) oaResponsesRequest {
+	body := oaResponsesRequest{
+		Model:           args.Model,
+		Instructions:    strings.TrimSpace(args.System),
+		Input:           buildUserContent(args.Prompt, args.Data),
+		MaxOutputTokens: args.MaxTokens,
+		Stream:          stream,
 	}
-	// default: assume sampling params are supported
-	return true
+	if args.Continuation != "" {
+		body.Input = []oaChatMsg{
+			{Role: "user", Content: buildUserContent(args.Prompt, args.Data)},
+			{Role: "assistant", Content: args.Continuation},
+			{Role: "user", Content: continuePrompt},
+		}
+	}
+	if responsesSupportsSampling(args.Model) {
+		body.Temperature = &args.Temperature
+		body.TopP = args.TopP
+	} else {
+		if args.ReasoningEffort != "" {
+			body.Reasoning = &oaReasoning{Effort: args.ReasoningEffort}
+		}
+		if args.Verbosity != "" {
+			body.Text = &oaResponsesText{Verbosity: args.Verbosity}
+		}
+	}
+	return body
 }
 
-func (p *openAIProvider) Complete(ctx context.Context, args CompletionArgs) (string, Usage, error) {
+func (p *openAIProvider) Complete(ctx context.Context, args CompletionArgs) (
+	string, Usage, string, error,
+) {
 	useResponses := modelUsesResponsesAPI(args.Model)
 
 	if useResponses {
-		var body oaResponsesRequest
-		body.Model = args.Model
-		body.Input = buildUserContent(args.Prompt, args.Data)
-		body.MaxOutputTokens = args.MaxTokens
-		body.Stream = false
-
-		if responsesSupportsSampling(args.Model) {
-			body.Temperature = args.Temperature
-			body.TopP = args.TopP
-		}
-
+		body := buildResponsesRequest(args, false)
 		buf, _ := json.Marshal(body)
 
 		if vb, _ := ctx.Value("nuro_verbose").(bool); vb {
@@ -171,106 +343,80 @@ func (p *openAIProvider) Complete(ctx context.Context, args CompletionArgs) (str
 			ctx, "POST", p.baseURL+"/responses", bytes.NewReader(buf),
 		)
 		if err != nil {
-			return "", Usage{}, err
+			return "", Usage{}, "", err
 		}
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 		req.Header.Set("Content-Type", "application/json")
 
 		resp, err := p.client.Do(req)
 		if err != nil {
-			return "", Usage{}, err
+			return "", Usage{}, "", err
 		}
 		defer func() { _ = resp.Body.Close() }()
 
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			b, _ := io.ReadAll(resp.Body)
-			return "", Usage{}, fmt.Errorf(
-				"openai responses error: %s - %s", resp.Status, trimBody(b),
-			)
+			return "", Usage{}, "", newAPIError("openai responses", resp, b)
 		}
 
 		var r oaResponsesResp
 		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-			return "", Usage{}, err
+			return "", Usage{}, "", err
 		}
 
-		var sb strings.Builder
-		for _, out := range r.Output {
-			for _, c := range out.Content {
-				if c.Text != "" {
-					sb.WriteString(c.Text)
-				}
-			}
+		if r.Error != nil {
+			return "", r.usage(), "", responsesError(r.Error)
 		}
 
-		return sb.String(), Usage{}, nil
+		return r.outputText(), r.usage(), r.finishReason(), nil
 	}
 
 	// Fallback to chat completions API
-	body := oaChatRequest{
-		Model:       args.Model,
-		Messages:    assembleMessages(args.Prompt, args.Data),
-		MaxTokens:   args.MaxTokens,
-		Temperature: args.Temperature,
-		TopP:        args.TopP,
-		Stream:      false,
-	}
+	body := buildChatRequest(args, false)
 	buf, _ := json.Marshal(body)
 
 	req, err := http.NewRequestWithContext(
 		ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(buf),
 	)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return "", Usage{}, fmt.Errorf("openai error: %s - %s", resp.Status, trimBody(b))
+		return "", Usage{}, "", newAPIError("openai", resp, b)
 	}
 
 	var r oaResp
 	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	if len(r.Choices) == 0 {
-		return "", Usage{}, fmt.Errorf("openai: no choices returned")
+		return "", Usage{}, "", fmt.Errorf("openai: no choices returned")
 	}
 	text := r.Choices[0].Message.Content
 	usage := Usage{}
 	if r.Usage != nil {
-		usage = Usage{
-			PromptTokens:     r.Usage.PromptTokens,
-			CompletionTokens: r.Usage.CompletionTokens,
-			TotalTokens:      r.Usage.TotalTokens,
-		}
+		usage = r.Usage.toUsage()
 	}
-	return text, usage, nil
+	return text, usage, r.Choices[0].FinishReason, nil
 }
 
 func (p *openAIProvider) Stream(
 	ctx context.Context, args CompletionArgs, onDelta func(string),
-) (string, Usage, error) {
+) (string, Usage, string, error) {
 	useResponses := modelUsesResponsesAPI(args.Model)
 
 	if useResponses {
-		var body oaResponsesRequest
-		body.Model = args.Model
-		body.Input = buildUserContent(args.Prompt, args.Data)
-		body.MaxOutputTokens = args.MaxTokens
-		body.Stream = true
-		if responsesSupportsSampling(args.Model) {
-			body.Temperature = args.Temperature
-			body.TopP = args.TopP
-		}
+		body := buildResponsesRequest(args, true)
 		buf, _ := json.Marshal(body)
 
 		if vb, _ := ctx.Value("nuro_verbose").(bool); vb {
@@ -288,7 +434,7 @@ func (p *openAIProvider) Stream(
 			ctx, "POST", p.baseURL+"/responses", bytes.NewReader(buf),
 		)
 		if err != nil {
-			return "", Usage{}, err
+			return "", Usage{}, "", err
 		}
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 		req.Header.Set("Content-Type", "application/json")
@@ -299,88 +445,27 @@ func (p *openAIProvider) Stream(
 
 		resp, err := p.client.Do(req)
 		if err != nil {
-			return "", Usage{}, err
+			return "", Usage{}, "", err
 		}
 		defer func() { _ = resp.Body.Close() }()
 
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			b, _ := io.ReadAll(resp.Body)
-			return "", Usage{}, fmt.Errorf(
-				"openai responses error: %s - %s", resp.Status, trimBody(b),
-			)
-		}
-
-		reader := bufio.NewReader(resp.Body)
-		var total strings.Builder
-		for {
-			line, err := reader.ReadString('\n')
-			if len(line) > 0 {
-				l := strings.TrimSpace(line)
-				if strings.HasPrefix(l, "data: ") {
-					payload := strings.TrimPrefix(l, "data: ")
-					if payload == "[DONE]" {
-						break
-					}
-					var chunk oaResponsesStreamChunk
-					if err := json.Unmarshal([]byte(payload), &chunk); err == nil {
-						for _, out := range chunk.Output {
-							for _, c := range out.Content {
-								if c.Text != "" {
-									onDelta(c.Text)
-									total.WriteString(c.Text)
-								}
-							}
-						}
-						continue
-					}
-					var chatChunk oaStreamChunk
-					if err := json.Unmarshal([]byte(payload), &chatChunk); err == nil {
-						for _, ch := range chatChunk.Choices {
-							d := ch.Delta.Content
-							if d != "" {
-								onDelta(d)
-								total.WriteString(d)
-							}
-						}
-					}
-				}
-			}
-
-			if err != nil {
-				if errorsIsEOF(err) {
-					break
-				}
-				if ctx.Err() != nil {
-					return total.String(), Usage{}, ctx.Err()
-				}
-				if err == io.ErrUnexpectedEOF {
-					continue
-				}
-				if err != nil && err != io.EOF {
-					return total.String(), Usage{}, err
-				}
-			}
+			return "", Usage{}, "", newAPIError("openai responses", resp, b)
 		}
 
-		return total.String(), Usage{}, nil
+		return decodeResponsesStream(ctx, resp.Body, onDelta)
 	}
 
 	// Chat completions streaming path
-	body := oaChatRequest{
-		Model:       args.Model,
-		Messages:    assembleMessages(args.Prompt, args.Data),
-		MaxTokens:   args.MaxTokens,
-		Temperature: args.Temperature,
-		TopP:        args.TopP,
-		Stream:      true,
-	}
+	body := buildChatRequest(args, true)
 	buf, _ := json.Marshal(body)
 
 	req, err := http.NewRequestWithContext(
 		ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(buf),
 	)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("Content-Type", "application/json")
@@ -391,17 +476,29 @@ func (p *openAIProvider) Stream(
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", Usage{}, err
+		return "", Usage{}, "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return "", Usage{}, fmt.Errorf("openai error: %s - %s", resp.Status, trimBody(b))
+		return "", Usage{}, "", newAPIError("openai", resp, b)
 	}
 
-	reader := bufio.NewReader(resp.Body)
+	return decodeChatStream(ctx, resp.Body, onDelta)
+}
+
+// decodeChatStream reads chat completion server-sent events, forwarding content deltas
+// to onDelta. It returns the usage from the final usage chunk (sent when
+// stream_options.include_usage is set) and the last finish_reason seen. Malformed
+// frames are skipped; an error frame ends the stream with an *APIError.
+func decodeChatStream(ctx context.Context, body io.Reader, onDelta func(string)) (
+	string, Usage, string, error,
+) {
+	reader := bufio.NewReader(body)
 	var total strings.Builder
+	var usage Usage
+	var finish string
 
 	for {
 		line, err := reader.ReadString('\n')
@@ -414,12 +511,23 @@ func (p *openAIProvider) Stream(
 				}
 				var chunk oaStreamChunk
 				if err := json.Unmarshal([]byte(payload), &chunk); err == nil {
+					if chunk.Error != nil {
+						return total.String(), usage, finish, &APIError{
+							Op: "openai", Status: streamErrorStatus, Body: chunk.Error.Message,
+						}
+					}
 					for _, ch := range chunk.Choices {
 						d := ch.Delta.Content
 						if d != "" {
 							onDelta(d)
 							total.WriteString(d)
 						}
+						if ch.FinishReason != nil && *ch.FinishReason != "" {
+							finish = *ch.FinishReason
+						}
+					}
+					if chunk.Usage != nil {
+						usage = chunk.Usage.toUsage()
 					}
 				}
 			}
@@ -429,18 +537,200 @@ func (p *openAIProvider) Stream(
 				break
 			}
 			if ctx.Err() != nil {
-				return total.String(), Usage{}, ctx.Err()
+				return total.String(), usage, finish, ctx.Err()
 			}
 			if err == io.ErrUnexpectedEOF {
 				continue
 			}
 			if err != nil && err != io.EOF {
-				return total.String(), Usage{}, err
+				return total.String(), usage, finish, err
+			}
+		}
+	}
+
+	return total.String(), usage, finish, nil
+}
+
+type oaEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type oaEmbeddingResp struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Usage *oaUsage `json:"usage,omitempty"`
+}
+
+func (p *openAIProvider) Embed(ctx context.Context, model string, inputs []string) (
+	[][]float64, Usage, error,
+) {
+	buf, _ := json.Marshal(oaEmbeddingRequest{Model: model, Input: inputs})
+
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", p.baseURL+"/embeddings", bytes.NewReader(buf),
+	)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, Usage{}, newAPIError("openai embeddings", resp, b)
+	}
+
+	var r oaEmbeddingResp
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, Usage{}, err
+	}
+	if len(r.Data) != len(inputs) {
+		return nil, Usage{}, fmt.Errorf(
+			"openai embeddings: expected %d vectors, got %d", len(inputs), len(r.Data),
+		)
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for _, d := range r.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, Usage{}, fmt.Errorf("openai embeddings: bad index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	usage := Usage{}
+	if r.Usage != nil {
+		usage = Usage{PromptTokens: r.Usage.PromptTokens, TotalTokens: r.Usage.TotalTokens}
+	}
+	return vectors, usage, nil
+}
+
+type oaModelsResp struct {
+	Data []struct {
+		ID      string `json:"id"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
+
+func (p *openAIProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("openai models", resp, b)
+	}
+
+	var r oaModelsResp
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(r.Data))
+	for _, m := range r.Data {
+		endpoint := "/chat/completions"
+		if modelUsesResponsesAPI(m.ID) {
+			endpoint = "/responses"
+		}
+		models = append(models, ModelInfo{ID: m.ID, OwnedBy: m.OwnedBy, Endpoint: endpoint})
+	}
+	return models, nil
+}
+
+// decodeResponsesStream reads typed Responses API server-sent events, forwarding text
+// deltas to onDelta. Usage is taken from the final response.completed (or
+// response.incomplete) event along with the finish reason. Malformed events are
+// skipped, as in decodeChatStream; error events are returned as *APIError.
+func decodeResponsesStream(ctx context.Context, body io.Reader, onDelta func(string)) (
+	string, Usage, string, error,
+) {
+	reader := bufio.NewReader(body)
+	var total strings.Builder
+	var usage Usage
+	for {
+		line, err := reader.ReadString('\n')
+		if l := strings.TrimSpace(line); strings.HasPrefix(l, "data:") {
+			payload := strings.TrimSpace(strings.TrimPrefix(l, "data:"))
+			if payload == "[DONE]" {
+				break
+			}
+			var ev oaResponsesEvent
+			if json.Unmarshal([]byte(payload), &ev) != nil {
+				// the next read returns the same EOF or error, so none is lost
+				continue
+			}
+			switch ev.Type {
+			case "response.output_text.delta", "response.refusal.delta":
+				if ev.Delta != "" {
+					onDelta(ev.Delta)
+					total.WriteString(ev.Delta)
+				}
+			case "response.completed", "response.incomplete":
+				finish := FinishStop
+				if ev.Response != nil {
+					usage = ev.Response.usage()
+					finish = ev.Response.finishReason()
+				}
+				return total.String(), usage, finish, nil
+			case "response.failed":
+				if ev.Response != nil && ev.Response.Error != nil {
+					return total.String(), ev.Response.usage(), "", responsesError(ev.Response.Error)
+				}
+				return total.String(), usage, "", responsesError(
+					&oaResponsesError{Message: "response failed"},
+				)
+			case "error", "response.error":
+				return total.String(), usage, "", responsesError(
+					&oaResponsesError{Code: ev.Code, Message: ev.Message},
+				)
 			}
 		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return total.String(), usage, "", ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return total.String(), usage, "", err
+		}
 	}
+	return total.String(), usage, "", nil
+}
 
-	return total.String(), Usage{}, nil
+// chatMessages prepends the optional system prompt to the assembled user message,
+// followed by the answer so far and a request to continue when args.Continuation is set.
+func chatMessages(args CompletionArgs) []oaChatMsg {
+	msgs := assembleMessages(args.Prompt, args.Data)
+	if args.Continuation != "" {
+		msgs = append(
+			msgs,
+			oaChatMsg{Role: "assistant", Content: args.Continuation},
+			oaChatMsg{Role: "user", Content: continuePrompt},
+		)
+	}
+	if s := strings.TrimSpace(args.System); s != "" {
+		msgs = append([]oaChatMsg{{Role: "system", Content: s}}, msgs...)
+	}
+	return msgs
 }
 
 func assembleMessages(prompt, data string) []oaChatMsg {
@@ -465,7 +755,7 @@ func buildUserContent(prompt, data string) string {
 
 	// If only data, present it clearly
 	if d != "" {
-		return fmt.Sprintf("Data:\n```\n%s\n```", d)
+		return fmt.Sprintf("Data:\n%s", fenced(d))
 	}
 
 	// If both are empty, still send an empty string to satisfy API
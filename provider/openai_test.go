@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const responsesStream = `event: response.created
+data: {"type":"response.created","response":{"status":"in_progress","output":[]}}
+
+event: response.output_text.delta
+data: {"type":"response.output_text.delta","item_id":"msg_1","output_index":1,"content_index":0,"delta":"Hello"}
+
+event: response.output_text.delta
+data: {"type":"response.output_text.delta","item_id":"msg_1","output_index":1,"content_index":0,"delta":", world"}
+
+event: response.output_text.done
+data: {"type":"response.output_text.done","text":"Hello, world"}
+
+event: response.completed
+data: {"type":"response.completed","response":{"status":"completed","output":[{"type":"reasoning"},{"type":"message","content":[{"type":"output_text","text":"Hello, world"}]}],"usage":{"input_tokens":12,"output_tokens":40,"total_tokens":52,"output_tokens_details":{"reasoning_tokens":30}}}}
+
+`
+
+func TestDecodeResponsesStream(t *testing.T) {
+	var deltas []string
+	text, usage, finish, err := decodeResponsesStream(
+		context.Background(), strings.NewReader(responsesStream),
+		func(d string) { deltas = append(deltas, d) },
+	)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if finish != FinishStop {
+		t.Errorf("expected finish reason stop, got %q", finish)
+	}
+	if text != "Hello, world" || len(deltas) != 2 {
+		t.Errorf("unexpected text %q (%d deltas)", text, len(deltas))
+	}
+	if usage.PromptTokens != 12 || usage.CompletionTokens != 40 || usage.TotalTokens != 52 ||
+		usage.ReasoningTokens != 30 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestDecodeResponsesStreamErrorEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		message string
+	}{
+		{
+			name:    "error event",
+			stream:  "event: error\ndata: {\"type\":\"error\",\"code\":\"rate_limit_exceeded\",\"message\":\"slow down\"}\n\n",
+			message: "openai responses error: rate_limit_exceeded - slow down",
+		},
+		{
+			name:    "response.failed",
+			stream:  "data: {\"type\":\"response.output_text.delta\",\"delta\":\"par\"}\n\ndata: {\"type\":\"response.failed\",\"response\":{\"status\":\"failed\",\"error\":{\"code\":\"server_error\",\"message\":\"boom\"}}}\n\n",
+			message: "openai responses error: server_error - boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, _, _, err := decodeResponsesStream(
+					context.Background(), strings.NewReader(tt.stream), func(string) {},
+				)
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("expected *APIError, got %v", err)
+				}
+				if err.Error() != tt.message {
+					t.Errorf("expected %q, got %q", tt.message, err.Error())
+				}
+			},
+		)
+	}
+}
+
+func TestResponsesCompleteUsageAndReasoning(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/responses" {
+					http.NotFound(w, r)
+					return
+				}
+				b, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(b, &body)
+				_, _ = w.Write([]byte(`{"status":"completed","output":[{"type":"reasoning","summary":[]},{"type":"message","content":[{"type":"output_text","text":"42"}]}],"usage":{"input_tokens":5,"output_tokens":7,"total_tokens":12}}`))
+			},
+		),
+	)
+	defer srv.Close()
+
+	text, usage, finish, err := NewOpenAIProvider("k", srv.URL).Complete(
+		context.Background(), CompletionArgs{
+			Model: "gpt-5-mini", Prompt: "answer", Temperature: 0.7, TopP: 1,
+			ReasoningEffort: "low", Verbosity: "high",
+		},
+	)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if text != "42" || usage.TotalTokens != 12 || finish != FinishStop {
+		t.Errorf("unexpected result %q %+v", text, usage)
+	}
+	if _, ok := body["temperature"]; ok {
+		t.Errorf("temperature must not be sent to reasoning models: %v", body)
+	}
+	if r, _ := body["reasoning"].(map[string]any); r["effort"] != "low" {
+		t.Errorf("expected reasoning.effort=low, got %v", body["reasoning"])
+	}
+	if tx, _ := body["text"].(map[string]any); tx["verbosity"] != "high" {
+		t.Errorf("expected text.verbosity=high, got %v", body["text"])
+	}
+}
+
+func TestBuildChatRequestForReasoningModel(t *testing.T) {
+	args := CompletionArgs{Model: "o4-mini", MaxTokens: 100, Temperature: 0.7, ReasoningEffort: "high"}
+	body := buildChatRequest(args, false)
+	if body.MaxTokens != 0 || body.Temperature != nil || body.MaxCompletionTokens != 100 ||
+		body.ReasoningEffort != "high" {
+		t.Errorf("unexpected reasoning chat request %+v", body)
+	}
+
+	body = buildChatRequest(CompletionArgs{Model: "gpt-4o-mini", MaxTokens: 100, Temperature: 0.7}, false)
+	if body.MaxTokens != 100 || body.Temperature == nil || *body.Temperature != 0.7 ||
+		body.MaxCompletionTokens != 0 {
+		t.Errorf("unexpected chat request %+v", body)
+	}
+
+	body = buildChatRequest(CompletionArgs{Model: "gpt-4o-mini", MaxTokens: 100}, false)
+	if b, _ := json.Marshal(body); !strings.Contains(string(b), `"temperature":0`) {
+		t.Errorf("expected temperature 0 to be sent, got %s", b)
+	}
+}
+
+func TestDecodeChatStreamUsageAndFinishReason(t *testing.T) {
+	stream := `data: {"choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"choices":[{"index":0,"delta":{"content":"one two"},"finish_reason":null}]}
+
+data: {"choices":[{"index":0,"delta":{},"finish_reason":"length"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":2,"total_tokens":11}}
+
+data: [DONE]
+
+`
+	text, usage, finish, err := decodeChatStream(
+		context.Background(), strings.NewReader(stream), func(string) {},
+	)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if text != "one two" {
+		t.Errorf("unexpected text %q", text)
+	}
+	if finish != "length" {
+		t.Errorf("expected finish_reason length, got %q", finish)
+	}
+	if usage.PromptTokens != 9 || usage.CompletionTokens != 2 || usage.TotalTokens != 11 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestBuildChatRequestStreamIncludesUsage(t *testing.T) {
+	buf, _ := json.Marshal(buildChatRequest(CompletionArgs{Model: "gpt-4o-mini"}, true))
+	if !strings.Contains(string(buf), `"stream_options":{"include_usage":true}`) {
+		t.Errorf("stream request missing include_usage: %s", buf)
+	}
+	buf, _ = json.Marshal(buildChatRequest(CompletionArgs{Model: "gpt-4o-mini"}, false))
+	if strings.Contains(string(buf), "stream_options") {
+		t.Errorf("non-stream request must not send stream_options: %s", buf)
+	}
+}
+
+func TestResponsesFinishReason(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`{"status":"completed","output":[]}`, FinishStop},
+		{`{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"}}`, FinishLength},
+		{`{"status":"incomplete","incomplete_details":{"reason":"content_filter"}}`, FinishContentFilter},
+		{`{"status":"completed","output":[{"type":"function_call"}]}`, FinishToolCalls},
+	}
+	for _, tt := range tests {
+		var r oaResponsesResp
+		if err := json.Unmarshal([]byte(tt.body), &r); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.finishReason(); got != tt.expected {
+			t.Errorf("finishReason(%s) = %q, expected %q", tt.body, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package provider
+
+import "strings"
+
+// Price is a model's list price in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// prices are list prices of common hosted models, matched by the longest model id
+// prefix so dated snapshots (gpt-4o-mini-2024-07-18) use their family's price. They are
+// estimates for comparing models, not billing figures.
+var prices = map[string]Price{
+	"gpt-5":             {1.25, 10},
+	"gpt-5-mini":        {0.25, 2},
+	"gpt-5-nano":        {0.05, 0.40},
+	"gpt-4.1":           {2, 8},
+	"gpt-4.1-mini":      {0.40, 1.60},
+	"gpt-4.1-nano":      {0.10, 0.40},
+	"gpt-4o":            {2.50, 10},
+	"gpt-4o-mini":       {0.15, 0.60},
+	"gpt-4-turbo":       {10, 30},
+	"gpt-3.5-turbo":     {0.50, 1.50},
+	"o1":                {15, 60},
+	"o1-mini":           {1.10, 4.40},
+	"o3":                {2, 8},
+	"o3-mini":           {1.10, 4.40},
+	"o4-mini":           {1.10, 4.40},
+	"claude-3-5-sonnet": {3, 15},
+	"claude-3-7-sonnet": {3, 15},
+	"claude-sonnet-4":   {3, 15},
+	"claude-3-5-haiku":  {0.80, 4},
+	"claude-3-opus":     {15, 75},
+	"claude-opus-4":     {15, 75},
+	"gemini-1.5-pro":    {1.25, 5},
+	"gemini-1.5-flash":  {0.075, 0.30},
+	"gemini-2.0-flash":  {0.10, 0.40},
+}
+
+// LookupPrice returns the list price of model on providerName. Local providers (Ollama,
+// the mock) are free; ok is false when the price is unknown.
+func LookupPrice(providerName, model string) (Price, bool) {
+	switch providerName {
+	case "ollama", "mock":
+		return Price{}, true
+	}
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:] // e.g. openrouter's "openai/gpt-4o"
+	}
+	best, found := "", false
+	for prefix := range prices {
+		if strings.HasPrefix(m, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return prices[best], found
+}
+
+// EstimateCost estimates the cost in USD of usage on model; ok is false when the
+// model's price is unknown.
+func EstimateCost(providerName, model string, usage Usage) (cost float64, ok bool) {
+	p, ok := LookupPrice(providerName, model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6, true
+}
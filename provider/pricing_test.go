@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	usage := Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000}
+	tests := []struct {
+		provider, model string
+		want            float64
+		ok              bool
+	}{
+		{"openai", "gpt-4o-mini", 0.15 + 0.30, true},
+		{"openai", "gpt-4o-mini-2024-07-18", 0.15 + 0.30, true},
+		{"openai", "gpt-4o", 2.50 + 5, true},
+		{"openrouter", "anthropic/claude-3-5-sonnet", 3 + 7.5, true},
+		{"ollama", "llama3.1:8b", 0, true},
+		{"openai", "my-finetune", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.model, func(t *testing.T) {
+				got, ok := EstimateCost(tt.provider, tt.model, usage)
+				if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+					t.Errorf("EstimateCost(%s, %s) = %v, %t; want %v, %t", tt.provider, tt.model, got, ok, tt.want, tt.ok)
+				}
+			},
+		)
+	}
+}
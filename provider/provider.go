@@ -2,7 +2,10 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -10,6 +13,48 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens,omitempty"`
 	CompletionTokens int `json:"completion_tokens,omitempty"`
 	TotalTokens      int `json:"total_tokens,omitempty"`
+	ReasoningTokens  int `json:"reasoning_tokens,omitempty"`
+
+	// Timings reported by the provider (Ollama), in nanoseconds when encoded
+	TotalDuration      time.Duration `json:"total_duration,omitempty"`
+	LoadDuration       time.Duration `json:"load_duration,omitempty"`
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"`
+	EvalDuration       time.Duration `json:"eval_duration,omitempty"`
+}
+
+// Add sums token counts and timings, e.g. across continuation requests.
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		PromptTokens:       u.PromptTokens + o.PromptTokens,
+		CompletionTokens:   u.CompletionTokens + o.CompletionTokens,
+		TotalTokens:        u.TotalTokens + o.TotalTokens,
+		ReasoningTokens:    u.ReasoningTokens + o.ReasoningTokens,
+		TotalDuration:      u.TotalDuration + o.TotalDuration,
+		LoadDuration:       u.LoadDuration + o.LoadDuration,
+		PromptEvalDuration: u.PromptEvalDuration + o.PromptEvalDuration,
+		EvalDuration:       u.EvalDuration + o.EvalDuration,
+	}
+}
+
+// continuePrompt is sent after a truncated answer to request the rest of it
+const continuePrompt = "Continue exactly where your previous answer stopped. Do not repeat any of it."
+
+// fenced wraps text in a code fence longer than any backtick run in it, so that fences
+// inside (the labeled sources of several data files, code in an answer) cannot close it.
+func fenced(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + text + "\n" + fence
+}
+
+// TokensPerSecond returns the generation speed when the provider reported eval timing.
+func (u Usage) TokensPerSecond() float64 {
+	if u.EvalDuration <= 0 || u.CompletionTokens == 0 {
+		return 0
+	}
+	return float64(u.CompletionTokens) / u.EvalDuration.Seconds()
 }
 
 type ProviderResolution struct {
@@ -21,10 +66,38 @@ type ProviderResolution struct {
 }
 
 type JSONResult struct {
-	Provider string `json:"provider"`
-	Model    string `json:"model"`
-	Usage    Usage  `json:"usage,omitempty"`
-	Text     string `json:"text"`
+	Provider     string   `json:"provider"`
+	Model        string   `json:"model"`
+	Usage        Usage    `json:"usage,omitempty"`
+	Text         string   `json:"text"`
+	FinishReason string   `json:"finish_reason,omitempty"`
+	Sources      []string `json:"sources,omitempty"`
+	Cached       bool     `json:"cached,omitempty"`
+	// Fallback lists the providers (provider:model) that failed before this one answered
+	Fallback []string `json:"fallback,omitempty"`
+}
+
+// Stream event types emitted with --output ndjson
+const (
+	EventDelta = "delta"
+	EventDone  = "done"
+	EventError = "error"
+)
+
+// StreamEvent is one line of --output ndjson: a text delta, the final "done" event
+// with usage and finish reason, or an "error" event.
+type StreamEvent struct {
+	Type         string   `json:"type"`
+	Text         string   `json:"text,omitempty"`
+	Provider     string   `json:"provider,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Usage        *Usage   `json:"usage,omitempty"`
+	FinishReason string   `json:"finish_reason,omitempty"`
+	Sources      []string `json:"sources,omitempty"`
+	Cached       bool     `json:"cached,omitempty"`
+	Fallback     []string `json:"fallback,omitempty"`
+	Error        string   `json:"error,omitempty"`
+	ExitCode     int      `json:"exit_code,omitempty"`
 }
 
 type CompletionArgs struct {
@@ -37,25 +110,129 @@ type CompletionArgs struct {
 	Stream      bool
 	JSONOut     bool
 	Timeout     time.Duration
+	KeepAlive   string // Ollama: how long the model stays loaded ("10m", "-1" = forever)
+
+	System string   // optional system prompt
+	Seed   *int     // nil leaves the provider default (random)
+	Stop   []string // stop sequences
+
+	// Ollama model options; zero values leave the model default
+	NumCtx        int
+	RepeatPenalty float64
+	TopK          int
+	MinP          float64
+	Mirostat      int
+	OllamaAPI     string // "generate" (default) or "chat"
+
+	// Continuation is the partial answer of a response cut off by the token limit;
+	// when set, providers ask the model to carry on from where it stopped.
+	Continuation string
+
+	// OpenAI reasoning models (gpt-5, o-series)
+	ReasoningEffort string // minimal, low, medium, high
+	Verbosity       string // low, medium, high
+}
+
+// APIError is returned when a provider answers with a non-2xx HTTP status.
+type APIError struct {
+	Op         string // e.g. "openai", "openai responses", "ollama"
+	StatusCode int
+	Status     string
+	Body       string
 }
 
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s error: %s - %s", e.Op, e.Status, e.Body)
+}
+
+// streamErrorStatus is the Status of an APIError reported in-band after a stream started.
+const streamErrorStatus = "stream error"
+
+func newAPIError(op string, resp *http.Response, body []byte) *APIError {
+	return &APIError{Op: op, StatusCode: resp.StatusCode, Status: resp.Status, Body: trimBody(body)}
+}
+
+// IsNotFound reports whether err is an APIError with HTTP status 404.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Finish reasons reported by Complete and Stream, normalized across providers
+const (
+	FinishStop          = "stop"
+	FinishLength        = "length"
+	FinishContentFilter = "content_filter"
+	FinishToolCalls     = "tool_calls"
+)
+
 type Provider interface {
 	Name() string
-	Complete(ctx context.Context, args CompletionArgs) (text string, usage Usage, err error)
+	Complete(ctx context.Context, args CompletionArgs) (
+		text string, usage Usage, finishReason string, err error,
+	)
 	Stream(ctx context.Context, args CompletionArgs, onDelta func(delta string)) (
-		total string, usage Usage, err error,
+		total string, usage Usage, finishReason string, err error,
 	)
 }
 
+// Degrader is implemented by providers that wrap others and may answer with a substitute,
+// like a fallback chain. Degraded reports whether the last request was answered that way;
+// wrappers of a Degrader should pass it on.
+type Degrader interface {
+	Degraded() bool
+}
+
+// Embedder is implemented by providers that can turn text into vector embeddings.
+type Embedder interface {
+	Embed(ctx context.Context, model string, inputs []string) (vectors [][]float64, usage Usage, err error)
+}
+
+// ModelInfo describes a model offered by a provider.
+type ModelInfo struct {
+	ID       string `json:"id"`
+	OwnedBy  string `json:"owned_by,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Details  string `json:"details,omitempty"`
+	Endpoint string `json:"endpoint"` // API endpoint nuro routes this model to
+}
+
+// ModelLister is implemented by providers that can enumerate their available models.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// DefaultEmbeddingModel returns the embedding model used when none is specified.
+func DefaultEmbeddingModel(providerName string) string {
+	switch providerName {
+	case "ollama":
+		return "nomic-embed-text"
+	case "mock":
+		return "mock"
+	default:
+		return "text-embedding-3-small"
+	}
+}
+
+// HTTPTransport, when set, is used by the HTTP clients of providers created afterwards,
+// e.g. to record or replay provider traffic. nil uses http.DefaultTransport.
+var HTTPTransport http.RoundTripper
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 0, Transport: HTTPTransport} // use context timeouts per request
+}
+
 func BuildProvider(res *ProviderResolution) (Provider, error) {
 	switch res.ProviderName {
 	case "openai":
 		return NewOpenAIProvider(res.APIKey, res.BaseURL), nil
 	case "ollama":
 		return NewOllamaProvider(res.BaseURL), nil
+	case "mock":
+		return NewMockProvider(res.BaseURL)
 	default:
 		return nil, fmt.Errorf(
-			"provider '%s' not implemented yet; set NURO_PROVIDER=openai/ollama or provide OPENAI_API_KEY",
+			"provider '%s' not implemented yet; set NURO_PROVIDER=openai/ollama/mock or provide OPENAI_API_KEY",
 			res.ProviderName,
 		)
 	}
@@ -0,0 +1,466 @@
+// Package providertest provides an httptest server that emulates the OpenAI chat
+// completions and Responses APIs and Ollama's /api/generate and /api/chat, including
+// SSE and NDJSON streaming, API errors, slow chunks and malformed frames. It lets
+// provider adapters be tested end to end without network access.
+package providertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reply scripts how the server answers one request.
+type Reply struct {
+	// Text is the generated answer.
+	Text string
+	// Chunks are the streamed deltas; by default Text is split into words.
+	Chunks []string
+	// FinishReason is "stop" (default), "length", "content_filter" or "tool_calls",
+	// translated into each API's own representation.
+	FinishReason string
+
+	PromptTokens     int
+	CompletionTokens int
+	ReasoningTokens  int
+
+	// Status, when 400 or higher, fails the request with that HTTP status and an error
+	// body in the API's format carrying ErrorMessage.
+	Status       int
+	ErrorMessage string
+
+	// Delay is slept before the server answers (before the headers are sent).
+	Delay time.Duration
+	// StreamError ends a stream with an in-band error frame after the chunks.
+	StreamError string
+	// ChunkDelay is slept before each streamed chunk.
+	ChunkDelay time.Duration
+	// Malformed inserts a frame that is not valid JSON before the first chunk.
+	Malformed bool
+	// Truncate ends a stream after the chunks without the final frame.
+	Truncate bool
+}
+
+func (r Reply) chunks() []string {
+	if r.Chunks != nil {
+		return r.Chunks
+	}
+	var out []string
+	for i, w := range strings.Fields(r.Text) {
+		if i > 0 {
+			w = " " + w
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+func (r Reply) finish() string {
+	if r.FinishReason == "" {
+		return "stop"
+	}
+	return r.FinishReason
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   map[string]any // decoded JSON body, nil when the body is not JSON
+}
+
+// Server is a fake OpenAI-compatible and Ollama server. OpenAI routes are served with
+// and without the /v1 prefix, so the server URL works as an OpenAI base URL either way.
+type Server struct {
+	*httptest.Server
+
+	// Models are listed by /models and /api/tags.
+	Models []string
+
+	mu       sync.Mutex
+	replies  []Reply
+	requests []Request
+}
+
+// NewServer starts a server that answers requests with replies in order; the last
+// reply is repeated once the others are used up. Callers must Close it.
+func NewServer(replies ...Reply) *Server {
+	s := &Server{replies: replies, Models: []string{"gpt-4o-mini", "llama3.1:8b"}}
+	mux := http.NewServeMux()
+	for _, prefix := range []string{"", "/v1"} {
+		mux.HandleFunc("POST "+prefix+"/chat/completions", s.chatCompletions)
+		mux.HandleFunc("POST "+prefix+"/responses", s.responses)
+		mux.HandleFunc("GET "+prefix+"/models", s.models)
+	}
+	mux.HandleFunc("POST /api/generate", s.ollama)
+	mux.HandleFunc("POST /api/chat", s.ollama)
+	mux.HandleFunc("GET /api/tags", s.tags)
+	s.Server = httptest.NewServer(s.record(mux))
+	return s
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// LastRequest returns the most recent request, or the zero Request.
+func (s *Server) LastRequest() Request {
+	reqs := s.Requests()
+	if len(reqs) == 0 {
+		return Request{}
+	}
+	return reqs[len(reqs)-1]
+}
+
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			req := Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone()}
+			_ = json.Unmarshal(b, &req.Body)
+			s.mu.Lock()
+			s.requests = append(s.requests, req)
+			s.mu.Unlock()
+			r.Body = io.NopCloser(strings.NewReader(string(b)))
+			next.ServeHTTP(w, r)
+		},
+	)
+}
+
+func (s *Server) next() Reply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.replies) == 0 {
+		return Reply{Text: "ok"}
+	}
+	r := s.replies[0]
+	if len(s.replies) > 1 {
+		s.replies = s.replies[1:]
+	}
+	return r
+}
+
+// reply takes the next reply and waits out its Delay, returning early if the client goes
+// away.
+func (s *Server) reply(r *http.Request) Reply {
+	reply := s.next()
+	if reply.Delay > 0 {
+		select {
+		case <-time.After(reply.Delay):
+		case <-r.Context().Done():
+		}
+	}
+	return reply
+}
+
+func streamRequested(r *http.Request, def bool) bool {
+	var body struct {
+		Stream *bool `json:"stream"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	if body.Stream == nil {
+		return def
+	}
+	return *body.Stream
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func openAIError(w http.ResponseWriter, reply Reply) {
+	writeJSON(
+		w, reply.Status, map[string]any{
+			"error": map[string]any{
+				"message": reply.ErrorMessage, "type": "invalid_request_error", "code": nil,
+			},
+		},
+	)
+}
+
+// streamWriter writes frames, flushing each one and honoring ChunkDelay.
+type streamWriter struct {
+	w     http.ResponseWriter
+	delay time.Duration
+}
+
+func (sw streamWriter) frame(s string, delayed bool) {
+	if delayed && sw.delay > 0 {
+		time.Sleep(sw.delay)
+	}
+	_, _ = io.WriteString(sw.w, s)
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func sse(v any) string {
+	b, _ := json.Marshal(v)
+	return "data: " + string(b) + "\n\n"
+}
+
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model         string `json:"model"`
+		Stream        bool   `json:"stream"`
+		StreamOptions *struct {
+			IncludeUsage bool `json:"include_usage"`
+		} `json:"stream_options"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	reply := s.reply(r)
+	if reply.Status >= 400 {
+		openAIError(w, reply)
+		return
+	}
+	usage := map[string]any{
+		"prompt_tokens":     reply.PromptTokens,
+		"completion_tokens": reply.CompletionTokens,
+		"total_tokens":      reply.PromptTokens + reply.CompletionTokens,
+		"completion_tokens_details": map[string]any{
+			"reasoning_tokens": reply.ReasoningTokens,
+		},
+	}
+
+	if !req.Stream {
+		writeJSON(
+			w, http.StatusOK, map[string]any{
+				"id": "chatcmpl-test", "object": "chat.completion", "created": time.Now().Unix(),
+				"model": req.Model,
+				"choices": []any{
+					map[string]any{
+						"index":         0,
+						"message":       map[string]any{"role": "assistant", "content": reply.Text},
+						"finish_reason": reply.finish(),
+					},
+				},
+				"usage": usage,
+			},
+		)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	sw := streamWriter{w: w, delay: reply.ChunkDelay}
+	chunk := func(delta map[string]any, finish any) map[string]any {
+		return map[string]any{
+			"id": "chatcmpl-test", "object": "chat.completion.chunk", "model": req.Model,
+			"choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": finish}},
+		}
+	}
+	sw.frame(sse(chunk(map[string]any{"role": "assistant", "content": ""}, nil)), false)
+	if reply.Malformed {
+		sw.frame("data: {\"choices\": [\n\n", false)
+	}
+	for _, c := range reply.chunks() {
+		sw.frame(sse(chunk(map[string]any{"content": c}, nil)), true)
+	}
+	if reply.StreamError != "" {
+		sw.frame(sse(map[string]any{"error": map[string]any{"message": reply.StreamError}}), false)
+		return
+	}
+	if reply.Truncate {
+		return
+	}
+	sw.frame(sse(chunk(map[string]any{}, reply.finish())), false)
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		sw.frame(
+			sse(map[string]any{"id": "chatcmpl-test", "choices": []any{}, "usage": usage}), false,
+		)
+	}
+	sw.frame("data: [DONE]\n\n", false)
+}
+
+// responsesObject builds a Responses API response object for reply.
+func responsesObject(model string, reply Reply) map[string]any {
+	obj := map[string]any{
+		"id": "resp_test", "object": "response", "model": model, "status": "completed",
+		"usage": map[string]any{
+			"input_tokens":          reply.PromptTokens,
+			"output_tokens":         reply.CompletionTokens,
+			"total_tokens":          reply.PromptTokens + reply.CompletionTokens,
+			"output_tokens_details": map[string]any{"reasoning_tokens": reply.ReasoningTokens},
+		},
+	}
+	output := []any{
+		map[string]any{"type": "reasoning", "summary": []any{}},
+		map[string]any{
+			"type": "message", "role": "assistant",
+			"content": []any{map[string]any{"type": "output_text", "text": reply.Text}},
+		},
+	}
+	switch reply.finish() {
+	case "length":
+		obj["status"] = "incomplete"
+		obj["incomplete_details"] = map[string]any{"reason": "max_output_tokens"}
+	case "content_filter":
+		obj["status"] = "incomplete"
+		obj["incomplete_details"] = map[string]any{"reason": "content_filter"}
+	case "tool_calls":
+		output = append(
+			output, map[string]any{"type": "function_call", "name": "lookup", "arguments": "{}"},
+		)
+	}
+	obj["output"] = output
+	return obj
+}
+
+func (s *Server) responses(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	reply := s.reply(r)
+	if reply.Status >= 400 {
+		openAIError(w, reply)
+		return
+	}
+	if !req.Stream {
+		writeJSON(w, http.StatusOK, responsesObject(req.Model, reply))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	sw := streamWriter{w: w, delay: reply.ChunkDelay}
+	event := func(typ string, v map[string]any) string {
+		v["type"] = typ
+		b, _ := json.Marshal(v)
+		return "event: " + typ + "\ndata: " + string(b) + "\n\n"
+	}
+	inProgress := responsesObject(req.Model, Reply{})
+	inProgress["status"] = "in_progress"
+	sw.frame(event("response.created", map[string]any{"response": inProgress}), false)
+	if reply.Malformed {
+		sw.frame("event: response.output_text.delta\ndata: {\"delta\": \n\n", false)
+	}
+	for _, c := range reply.chunks() {
+		sw.frame(event("response.output_text.delta", map[string]any{"delta": c}), true)
+	}
+	if reply.StreamError != "" {
+		sw.frame(
+			event("error", map[string]any{"code": "server_error", "message": reply.StreamError}), false,
+		)
+		return
+	}
+	if reply.Truncate {
+		return
+	}
+	final := responsesObject(req.Model, reply)
+	typ := "response.completed"
+	if final["status"] == "incomplete" {
+		typ = "response.incomplete"
+	}
+	sw.frame(event(typ, map[string]any{"response": final}), false)
+}
+
+func (s *Server) models(w http.ResponseWriter, _ *http.Request) {
+	data := make([]any, 0, len(s.Models))
+	for _, m := range s.Models {
+		data = append(data, map[string]any{"id": m, "object": "model", "owned_by": "providertest"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": data})
+}
+
+func (s *Server) tags(w http.ResponseWriter, _ *http.Request) {
+	models := make([]any, 0, len(s.Models))
+	for _, m := range s.Models {
+		models = append(
+			models, map[string]any{
+				"name": m, "model": m, "size": 4_920_753_328,
+				"details": map[string]any{
+					"family": "llama", "parameter_size": "8.0B", "quantization_level": "Q4_K_M",
+				},
+			},
+		)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"models": models})
+}
+
+// ollama serves /api/generate and /api/chat. Like Ollama, it streams unless the request
+// sets "stream": false.
+func (s *Server) ollama(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	b, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(b, &req)
+	r.Body = io.NopCloser(strings.NewReader(string(b)))
+	stream := streamRequested(r, true)
+	chat := strings.HasSuffix(r.URL.Path, "/chat")
+
+	reply := s.reply(r)
+	if reply.Status >= 400 {
+		writeJSON(w, reply.Status, map[string]any{"error": reply.ErrorMessage})
+		return
+	}
+
+	doneReason := "stop"
+	if reply.finish() == "length" {
+		doneReason = "length"
+	}
+	msg := func(text string, done bool) map[string]any {
+		m := map[string]any{
+			"model": req.Model, "created_at": time.Now().UTC().Format(time.RFC3339Nano), "done": done,
+		}
+		if chat {
+			m["message"] = map[string]any{"role": "assistant", "content": text}
+		} else {
+			m["response"] = text
+		}
+		if done {
+			m["done_reason"] = doneReason
+			m["total_duration"] = int64(2 * time.Second)
+			m["load_duration"] = int64(100 * time.Millisecond)
+			m["prompt_eval_count"] = reply.PromptTokens
+			m["prompt_eval_duration"] = int64(400 * time.Millisecond)
+			m["eval_count"] = reply.CompletionTokens
+			m["eval_duration"] = int64(time.Second)
+		}
+		return m
+	}
+
+	if !stream {
+		writeJSON(w, http.StatusOK, msg(reply.Text, true))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	sw := streamWriter{w: w, delay: reply.ChunkDelay}
+	line := func(v any) string {
+		b, _ := json.Marshal(v)
+		return string(b) + "\n"
+	}
+	if reply.Malformed {
+		sw.frame("{\"response\": \"unterminated\n", false)
+	}
+	for _, c := range reply.chunks() {
+		sw.frame(line(msg(c, false)), true)
+	}
+	if reply.StreamError != "" {
+		sw.frame(line(map[string]any{"error": reply.StreamError}), false)
+		return
+	}
+	if reply.Truncate {
+		return
+	}
+	sw.frame(line(msg("", true)), false)
+}
+
+// String describes the reply queue, for test failure messages.
+func (s *Server) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("providertest.Server{%s, %d replies queued}", s.URL, len(s.replies))
+}
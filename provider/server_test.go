@@ -0,0 +1,348 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heather7532/nuro/provider/providertest"
+)
+
+// serverCase is one scripted exchange run against a providertest.Server, streamed and
+// not streamed unless streamOnly is set.
+type serverCase struct {
+	name       string
+	reply      providertest.Reply
+	streamOnly bool
+	timeout    time.Duration
+
+	wantText   string
+	wantFinish string
+	wantUsage  Usage
+	wantStatus int    // expected APIError.StatusCode; -1 for an in-band stream error
+	wantErr    string // substring of the expected error
+	wantCtxErr bool
+}
+
+var serverCases = []serverCase{
+	{
+		name:       "stop",
+		reply:      providertest.Reply{Text: "Hello there, world", PromptTokens: 7, CompletionTokens: 3},
+		wantText:   "Hello there, world",
+		wantFinish: FinishStop,
+		wantUsage:  Usage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10},
+	},
+	{
+		name:       "length",
+		reply:      providertest.Reply{Text: "cut short", FinishReason: "length", CompletionTokens: 2},
+		wantText:   "cut short",
+		wantFinish: FinishLength,
+		wantUsage:  Usage{CompletionTokens: 2, TotalTokens: 2},
+	},
+	{
+		name:       "unauthorized",
+		reply:      providertest.Reply{Status: 401, ErrorMessage: "Incorrect API key provided"},
+		wantStatus: 401,
+		wantErr:    "Incorrect API key provided",
+	},
+	{
+		name:       "rate limited",
+		reply:      providertest.Reply{Status: 429, ErrorMessage: "Rate limit reached"},
+		wantStatus: 429,
+		wantErr:    "Rate limit reached",
+	},
+	{
+		name:       "server error",
+		reply:      providertest.Reply{Status: 503, ErrorMessage: "overloaded"},
+		wantStatus: 503,
+		wantErr:    "overloaded",
+	},
+	{
+		name:       "error mid-stream",
+		reply:      providertest.Reply{Text: "partial answer", StreamError: "model crashed"},
+		streamOnly: true,
+		wantText:   "partial answer",
+		wantStatus: -1,
+		wantErr:    "model crashed",
+	},
+	{
+		name:       "slow chunks",
+		reply:      providertest.Reply{Text: "one two three four", ChunkDelay: 100 * time.Millisecond},
+		streamOnly: true,
+		timeout:    150 * time.Millisecond,
+		wantCtxErr: true,
+	},
+	{
+		name:       "truncated stream",
+		reply:      providertest.Reply{Text: "no final frame", Truncate: true},
+		streamOnly: true,
+		wantText:   "no final frame",
+	},
+}
+
+// runServerCases runs serverCases through build against a fresh server each time.
+// malformed is the expected outcome for a stream with a malformed frame: "" when the
+// frame is skipped, otherwise a substring of the error.
+func runServerCases(
+	t *testing.T, build func(url string) Provider, args CompletionArgs, malformed string,
+) {
+	t.Helper()
+	cases := append(
+		serverCases[:len(serverCases):len(serverCases)], serverCase{
+			name:       "malformed frame",
+			reply:      providertest.Reply{Text: "still fine", Malformed: true},
+			streamOnly: true,
+			wantText:   "still fine",
+			wantFinish: FinishStop,
+			wantErr:    malformed,
+		},
+	)
+	for _, tt := range cases {
+		for _, stream := range []bool{false, true} {
+			if tt.streamOnly && !stream {
+				continue
+			}
+			name := tt.name + "/complete"
+			if stream {
+				name = tt.name + "/stream"
+			}
+			t.Run(
+				name, func(t *testing.T) {
+					srv := providertest.NewServer(tt.reply)
+					defer srv.Close()
+					p := build(srv.URL)
+
+					ctx := context.Background()
+					if tt.timeout > 0 {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+						defer cancel()
+					}
+
+					var (
+						text, finish string
+						usage        Usage
+						err          error
+						deltas       strings.Builder
+					)
+					if stream {
+						text, usage, finish, err = p.Stream(ctx, args, func(d string) { deltas.WriteString(d) })
+						if deltas.String() != text {
+							t.Errorf("deltas %q do not add up to text %q", deltas.String(), text)
+						}
+					} else {
+						text, usage, finish, err = p.Complete(ctx, args)
+					}
+
+					switch {
+					case tt.wantCtxErr:
+						if !errors.Is(err, context.DeadlineExceeded) {
+							t.Fatalf("expected deadline exceeded, got %v", err)
+						}
+						return
+					case tt.wantErr != "":
+						if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+							t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+						}
+						var apiErr *APIError
+						if tt.wantStatus != 0 && !errors.As(err, &apiErr) {
+							t.Fatalf("expected *APIError, got %T", err)
+						}
+						if tt.wantStatus > 0 && apiErr.StatusCode != tt.wantStatus {
+							t.Errorf("expected status %d, got %d", tt.wantStatus, apiErr.StatusCode)
+						}
+						if text != tt.wantText && tt.wantStatus < 0 {
+							t.Errorf("expected partial text %q, got %q", tt.wantText, text)
+						}
+						return
+					case err != nil:
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if text != tt.wantText {
+						t.Errorf("expected text %q, got %q", tt.wantText, text)
+					}
+					if finish != tt.wantFinish {
+						t.Errorf("expected finish %q, got %q", tt.wantFinish, finish)
+					}
+					if tt.wantUsage != (Usage{}) {
+						got := Usage{
+							PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens,
+							TotalTokens: usage.TotalTokens,
+						}
+						if got != tt.wantUsage {
+							t.Errorf("expected usage %+v, got %+v", tt.wantUsage, got)
+						}
+					}
+				},
+			)
+		}
+	}
+}
+
+func TestOpenAIChatAgainstServer(t *testing.T) {
+	runServerCases(
+		t, func(url string) Provider { return NewOpenAIProvider("sk-test-key", url) },
+		CompletionArgs{Model: "gpt-4o-mini", Prompt: "hi"}, "",
+	)
+}
+
+func TestOpenAIResponsesAgainstServer(t *testing.T) {
+	runServerCases(
+		t, func(url string) Provider { return NewOpenAIProvider("sk-test-key", url) },
+		CompletionArgs{Model: "gpt-5", Prompt: "hi"}, "",
+	)
+}
+
+func TestOllamaGenerateAgainstServer(t *testing.T) {
+	runServerCases(
+		t, func(url string) Provider { return NewOllamaProvider(url) },
+		CompletionArgs{Model: "llama3.1:8b", Prompt: "hi"}, "",
+	)
+}
+
+func TestOllamaChatAgainstServer(t *testing.T) {
+	runServerCases(
+		t, func(url string) Provider { return NewOllamaProvider(url) },
+		CompletionArgs{Model: "llama3.1:8b", Prompt: "hi", OllamaAPI: "chat"}, "",
+	)
+}
+
+func TestServerRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(url string) Provider
+		args     CompletionArgs
+		wantPath string
+		wantKey  string // a request body field that must be present
+	}{
+		{
+			name:     "openai chat",
+			build:    func(url string) Provider { return NewOpenAIProvider("sk-test-key", url) },
+			args:     CompletionArgs{Model: "gpt-4o-mini", Prompt: "hi"},
+			wantPath: "/chat/completions",
+			wantKey:  "messages",
+		},
+		{
+			name:     "openai responses",
+			build:    func(url string) Provider { return NewOpenAIProvider("sk-test-key", url) },
+			args:     CompletionArgs{Model: "gpt-5", Prompt: "hi"},
+			wantPath: "/responses",
+			wantKey:  "input",
+		},
+		{
+			name:     "ollama generate",
+			build:    func(url string) Provider { return NewOllamaProvider(url) },
+			args:     CompletionArgs{Model: "llama3.1:8b", Prompt: "hi"},
+			wantPath: "/api/generate",
+			wantKey:  "prompt",
+		},
+		{
+			name:     "ollama chat",
+			build:    func(url string) Provider { return NewOllamaProvider(url) },
+			args:     CompletionArgs{Model: "llama3.1:8b", Prompt: "hi", OllamaAPI: "chat"},
+			wantPath: "/api/chat",
+			wantKey:  "messages",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				srv := providertest.NewServer(providertest.Reply{Text: "first"}, providertest.Reply{Text: "second"})
+				defer srv.Close()
+				p := tt.build(srv.URL)
+				for _, want := range []string{"first", "second", "second"} {
+					text, _, _, err := p.Complete(context.Background(), tt.args)
+					if err != nil {
+						t.Fatalf("complete: %v", err)
+					}
+					if text != want {
+						t.Errorf("expected %q, got %q", want, text)
+					}
+				}
+				req := srv.LastRequest()
+				if req.Path != tt.wantPath {
+					t.Errorf("expected path %s, got %s", tt.wantPath, req.Path)
+				}
+				if req.Body["model"] != tt.args.Model {
+					t.Errorf("expected model %s in body, got %v", tt.args.Model, req.Body["model"])
+				}
+				if _, ok := req.Body[tt.wantKey]; !ok {
+					t.Errorf("expected %q in request body %v", tt.wantKey, req.Body)
+				}
+				if len(srv.Requests()) != 3 {
+					t.Errorf("expected 3 recorded requests, got %d", len(srv.Requests()))
+				}
+			},
+		)
+	}
+}
+
+func TestContinuationRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(url string) Provider
+		args  CompletionArgs
+		key   string // the request body field holding the messages
+	}{
+		{
+			name:  "openai chat",
+			build: func(url string) Provider { return NewOpenAIProvider("sk-test-key", url) },
+			args:  CompletionArgs{Model: "gpt-4o-mini", Prompt: "count", System: "be terse"},
+			key:   "messages",
+		},
+		{
+			name:  "openai responses",
+			build: func(url string) Provider { return NewOpenAIProvider("sk-test-key", url) },
+			args:  CompletionArgs{Model: "gpt-5", Prompt: "count"},
+			key:   "input",
+		},
+		{
+			name:  "ollama chat",
+			build: func(url string) Provider { return NewOllamaProvider(url) },
+			args:  CompletionArgs{Model: "llama3.1:8b", Prompt: "count", OllamaAPI: "chat"},
+			key:   "messages",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				srv := providertest.NewServer(
+					providertest.Reply{Text: "one two", FinishReason: "length"},
+					providertest.Reply{Text: " three"},
+				)
+				defer srv.Close()
+				p := tt.build(srv.URL)
+				first, _, _, err := p.Complete(context.Background(), tt.args)
+				if err != nil {
+					t.Fatalf("complete: %v", err)
+				}
+				args := tt.args
+				args.Continuation = first
+				if _, _, _, err := p.Complete(context.Background(), args); err != nil {
+					t.Fatalf("continue: %v", err)
+				}
+
+				msgs, _ := srv.LastRequest().Body[tt.key].([]any)
+				var roles, last []string
+				for _, m := range msgs {
+					msg, _ := m.(map[string]any)
+					role, _ := msg["role"].(string)
+					content, _ := msg["content"].(string)
+					roles = append(roles, role)
+					last = append(last, content)
+				}
+				if n := len(roles); n < 3 || strings.Join(roles[n-3:], ",") != "user,assistant,user" {
+					t.Fatalf("expected the answer so far and a continue message, got roles %v", roles)
+				}
+				if got := last[len(last)-2]; got != "one two" {
+					t.Errorf("expected the partial answer %q as assistant message, got %q", "one two", got)
+				}
+				if got := last[len(last)-1]; got != continuePrompt {
+					t.Errorf("expected the continue prompt last, got %q", got)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,346 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+// IndexFileName is the file written into an indexed directory by Build/Save.
+const IndexFileName = ".nuro-index.json"
+
+const indexVersion = 1
+
+// Defaults for chunking and embedding batches
+const (
+	DefaultChunkSize = 1200 // characters per chunk (approximate, split on line boundaries)
+	DefaultTopK      = 4
+	embedBatchSize   = 64
+	binarySniffBytes = 8000
+	maxFileSize      = 2 * 1024 * 1024 // skip files larger than 2MB
+)
+
+// Chunk is a contiguous range of lines from a source file and its embedding.
+type Chunk struct {
+	Source    string    `json:"source"`
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Vector    []float64 `json:"vector"`
+}
+
+// Citation formats the chunk location as path:start-end
+func (c Chunk) Citation() string {
+	if c.StartLine == c.EndLine {
+		return fmt.Sprintf("%s:%d", c.Source, c.StartLine)
+	}
+	return fmt.Sprintf("%s:%d-%d", c.Source, c.StartLine, c.EndLine)
+}
+
+// Index is a file-based vector index over the text files of a directory.
+type Index struct {
+	Version   int       `json:"version"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Root      string    `json:"root"`
+	CreatedAt time.Time `json:"created_at"`
+	Chunks    []Chunk   `json:"chunks"`
+}
+
+// Result is a chunk returned from a search together with its similarity score.
+type Result struct {
+	Chunk
+	Score float64 `json:"score"`
+}
+
+// BuildOptions controls how files are chunked when building an index.
+type BuildOptions struct {
+	ChunkSize int
+	// Progress, if set, is called after each embedding batch with done/total chunk counts.
+	Progress func(done, total int)
+	// Check, if set, is called with every chunk before any is embedded; an error
+	// stops the build so nothing is sent.
+	Check func(chunks []Chunk) error
+}
+
+// IndexPath returns the index file location for a directory or explicit index file path.
+func IndexPath(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, IndexFileName)
+	}
+	return path
+}
+
+// Build walks root, chunks every text file and embeds the chunks with the given model.
+func Build(
+	ctx context.Context, emb provider.Embedder, providerName, model, root string,
+	opts BuildOptions,
+) (*Index, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+
+	files, err := CollectFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []Chunk
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f, err)
+		}
+		rel, err := filepath.Rel(root, f)
+		if err != nil {
+			rel = f
+		}
+		chunks = append(chunks, ChunkText(filepath.ToSlash(rel), string(b), opts.ChunkSize)...)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no text files found under %s", root)
+	}
+	if opts.Check != nil {
+		if err := opts.Check(chunks); err != nil {
+			return nil, err
+		}
+	}
+
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		inputs := make([]string, 0, end-start)
+		for _, c := range chunks[start:end] {
+			inputs = append(inputs, c.Text)
+		}
+		vectors, _, err := emb.Embed(ctx, model, inputs)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range vectors {
+			chunks[start+i].Vector = v
+		}
+		if opts.Progress != nil {
+			opts.Progress(end, len(chunks))
+		}
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		absRoot = root
+	}
+	return &Index{
+		Version:   indexVersion,
+		Provider:  providerName,
+		Model:     model,
+		Root:      absRoot,
+		CreatedAt: time.Now().UTC(),
+		Chunks:    chunks,
+	}, nil
+}
+
+// CollectFiles returns the text files under root, skipping hidden entries and binaries.
+func CollectFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(
+		root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := d.Name()
+			if path != root && strings.HasPrefix(name, ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 || fi.Size() > maxFileSize {
+				return nil
+			}
+			if isTextFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isTextFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	buf := make([]byte, binarySniffBytes)
+	n, _ := f.Read(buf)
+	return !bytes.Contains(buf[:n], []byte{0})
+}
+
+// ChunkText splits text on line boundaries into chunks of roughly size characters.
+// Blank-only chunks are dropped.
+func ChunkText(source, text string, size int) []Chunk {
+	lines := strings.Split(text, "\n")
+	var chunks []Chunk
+	var sb strings.Builder
+	start := 1
+
+	flush := func(end int) {
+		t := strings.TrimSpace(sb.String())
+		if t != "" {
+			chunks = append(
+				chunks, Chunk{Source: source, StartLine: start, EndLine: end, Text: t},
+			)
+		}
+		sb.Reset()
+		start = end + 1
+	}
+
+	for i, line := range lines {
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+		// Prefer breaking at a blank line once half full, force a break when full
+		if sb.Len() >= size || (sb.Len() >= size/2 && strings.TrimSpace(line) == "") {
+			flush(i + 1)
+		}
+	}
+	if sb.Len() > 0 {
+		flush(len(lines))
+	}
+	return chunks
+}
+
+// Save writes the index as JSON, replacing any previous file atomically.
+func (ix *Index) Save(path string) error {
+	b, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".nuro-index-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads an index from a directory (using IndexFileName) or an explicit file path.
+func Load(path string) (*Index, error) {
+	p := IndexPath(path)
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf(
+				"no index found at %s; run 'nuro index build %s' first", p, path,
+			)
+		}
+		return nil, err
+	}
+	var ix Index
+	if err := json.Unmarshal(b, &ix); err != nil {
+		return nil, fmt.Errorf("failed to parse index %s: %w", p, err)
+	}
+	if ix.Version != indexVersion {
+		return nil, fmt.Errorf(
+			"index %s has unsupported version %d; rebuild it with 'nuro index build'", p,
+			ix.Version,
+		)
+	}
+	return &ix, nil
+}
+
+// Search returns the k chunks most similar to the query vector.
+func (ix *Index) Search(query []float64, k int) []Result {
+	if k <= 0 {
+		k = DefaultTopK
+	}
+	results := make([]Result, 0, len(ix.Chunks))
+	for _, c := range ix.Chunks {
+		results = append(results, Result{Chunk: c, Score: cosine(query, c.Vector)})
+	}
+	sort.SliceStable(
+		results, func(i, j int) bool { return results[i].Score > results[j].Score },
+	)
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// Query embeds text with the index's model and returns the top k chunks.
+func (ix *Index) Query(ctx context.Context, emb provider.Embedder, text string, k int) (
+	[]Result, error,
+) {
+	vectors, _, err := emb.Embed(ctx, ix.Model, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 query vector, got %d", len(vectors))
+	}
+	return ix.Search(vectors[0], k), nil
+}
+
+// FormatContext renders retrieved chunks as numbered, fenced sections with citations.
+func FormatContext(results []Result) string {
+	if len(results) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Context (cite sources by their [n] marker):\n")
+	for i, r := range results {
+		_, _ = fmt.Fprintf(&sb, "\n[%d] %s\n```\n%s\n```\n", i+1, r.Citation(), r.Text)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
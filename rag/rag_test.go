@@ -0,0 +1,156 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+// fakeEmbedder embeds text as letter counts for a fixed set of keywords.
+type fakeEmbedder struct {
+	calls int
+}
+
+var fakeKeywords = []string{"key", "rotate", "deploy", "database"}
+
+func (f *fakeEmbedder) Embed(_ context.Context, _ string, inputs []string) (
+	[][]float64, provider.Usage, error,
+) {
+	f.calls++
+	out := make([][]float64, len(inputs))
+	for i, in := range inputs {
+		v := make([]float64, len(fakeKeywords))
+		lower := strings.ToLower(in)
+		for j, k := range fakeKeywords {
+			v[j] = float64(strings.Count(lower, k))
+		}
+		out[i] = v
+	}
+	return out, provider.Usage{}, nil
+}
+
+func TestChunkTextSplitsOnLines(t *testing.T) {
+	text := strings.Repeat("line of text\n", 20)
+	chunks := ChunkText("a.md", text, 60)
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %d", len(chunks))
+	}
+	if chunks[0].StartLine != 1 {
+		t.Errorf("first chunk should start at line 1, got %d", chunks[0].StartLine)
+	}
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].StartLine != chunks[i-1].EndLine+1 {
+			t.Errorf("chunk %d not contiguous: %+v after %+v", i, chunks[i], chunks[i-1])
+		}
+	}
+}
+
+func TestChunkTextDropsBlankChunks(t *testing.T) {
+	chunks := ChunkText("a.md", "\n\n\n", 10)
+	if len(chunks) != 0 {
+		t.Errorf("expected no chunks for blank text, got %d", len(chunks))
+	}
+}
+
+func TestCitation(t *testing.T) {
+	c := Chunk{Source: "docs/a.md", StartLine: 3, EndLine: 9}
+	if c.Citation() != "docs/a.md:3-9" {
+		t.Errorf("unexpected citation %q", c.Citation())
+	}
+	c.EndLine = 3
+	if c.Citation() != "docs/a.md:3" {
+		t.Errorf("unexpected single-line citation %q", c.Citation())
+	}
+}
+
+func TestBuildSaveLoadQuery(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"keys.md":        "How to rotate the API key.\nRotate key monthly.",
+		"deploy.md":      "Deploy with make release and deploy again.",
+		"db.txt":         "The database is backed up nightly.",
+		".hidden/x.md":   "key key key",
+		"bin/binary.dat": "abc\x00def",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	emb := &fakeEmbedder{}
+	ix, err := Build(context.Background(), emb, "ollama", "fake", root, BuildOptions{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(ix.Chunks) != 3 {
+		t.Fatalf("expected 3 chunks (hidden and binary skipped), got %d", len(ix.Chunks))
+	}
+
+	if err := ix.Save(IndexPath(root)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Model != "fake" || loaded.Provider != "ollama" {
+		t.Errorf("metadata not preserved: %+v", loaded)
+	}
+
+	results, err := loaded.Query(context.Background(), emb, "how do we rotate keys?", 1)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 || results[0].Source != "keys.md" {
+		t.Fatalf("expected keys.md as top result, got %+v", results)
+	}
+
+	ctx := FormatContext(results)
+	if !strings.Contains(ctx, "[1] keys.md:1-2") {
+		t.Errorf("context missing citation header: %q", ctx)
+	}
+}
+
+func TestLoadMissingIndex(t *testing.T) {
+	_, err := Load(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "nuro index build") {
+		t.Errorf("expected actionable error for missing index, got %v", err)
+	}
+}
+
+func TestBuildCheckStopsBeforeEmbedding(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "secrets.md"), []byte("password: hunter2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	emb := &fakeEmbedder{}
+	var checked []Chunk
+	_, err := Build(
+		context.Background(), emb, "ollama", "fake", root, BuildOptions{
+			Check: func(chunks []Chunk) error {
+				checked = chunks
+				return errors.New("forbidden")
+			},
+		},
+	)
+	if err == nil || err.Error() != "forbidden" {
+		t.Fatalf("expected the check error, got %v", err)
+	}
+	if len(checked) != 1 || checked[0].Text != "password: hunter2" {
+		t.Errorf("expected the check to see every chunk, got %+v", checked)
+	}
+	if emb.calls != 0 {
+		t.Errorf("expected nothing to be embedded, got %d calls", emb.calls)
+	}
+}
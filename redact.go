@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/heather7532/nuro/config"
+	"github.com/heather7532/nuro/redact"
+	"github.com/spf13/pflag"
+)
+
+// newRedactor returns the Redactor for this run, or nil when redaction is off. It is on
+// with --redact or --redact-check, or when the redact section of .nuro enables it or a
+// policy requires it;
+// --redact-restore and the section's "restore" put the original values back into the
+// answer.
+func newRedactor(f *cliFlags) (*redact.Redactor, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var section config.Redaction
+	if cfg != nil && cfg.Redact != nil {
+		section = *cfg.Redact
+	}
+	changed := pflag.CommandLine.Changed
+	if !changed("redact") {
+		f.redact = section.Enabled || redactionRequiredBy() != ""
+	}
+	if !changed("redact-restore") {
+		f.redactRestore = section.Restore
+	}
+	r, err := redact.New(section.Detectors, section.Patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redact section in .nuro: %w", err)
+	}
+	if !f.redact && !f.redactCheck {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// printRedactCheck writes what would be sent with redaction applied, for --redact-check:
+// the placeholders and the values they replace to stderr, and the system prompt and
+// message to stdout.
+func printRedactCheck(r *redact.Redactor, system, content string, rag bool) {
+	if r.Total() == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "nuro: redact-check: nothing to redact")
+	} else {
+		_, _ = fmt.Fprintf(os.Stderr, "nuro: redact-check: %d values redacted: %s\n", r.Total(), r.Summary())
+		for _, p := range r.Placeholders() {
+			_, _ = fmt.Fprintf(os.Stderr, "nuro:   %s = %s\n", p[0], oneLine(p[1]))
+		}
+	}
+	if rag {
+		_, _ = fmt.Fprintln(
+			os.Stderr, "nuro: redact-check: context retrieved with --rag is redacted too but not shown",
+		)
+	}
+	if system != "" {
+		fmt.Printf("--- system ---\n%s\n", system)
+	}
+	fmt.Printf("--- user ---\n%s\n", content)
+}
+
+// oneLine shortens a value for display on one line.
+func oneLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 60 {
+		return s[:57] + "..."
+	}
+	return s
+}
@@ -0,0 +1,303 @@
+// Package redact replaces sensitive values (emails, IP addresses, JWTs, cloud keys, ...)
+// in text sent to a model with stable placeholders such as <EMAIL_1>, and restores the
+// original values in the answer.
+package redact
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Detector finds one kind of sensitive value. When its pattern has a capturing group,
+// only the first group is replaced (e.g. the value after "password=").
+type Detector struct {
+	Name    string // name used in .nuro, e.g. "email"
+	Label   string // placeholder label, e.g. "EMAIL"
+	Pattern *regexp.Regexp
+	Valid   func(string) bool // optional check of a match
+}
+
+// Builtin are the built-in detectors, applied in order so that specific ones (a JWT,
+// a private key block) win over general ones.
+var Builtin = []Detector{
+	{
+		Name:    "private_key",
+		Label:   "PRIVATE_KEY",
+		Pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	},
+	{
+		Name:    "jwt",
+		Label:   "JWT",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`),
+	},
+	{
+		Name:    "aws_key",
+		Label:   "AWS_KEY",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[A-Z0-9]{16}\b`),
+	},
+	{
+		Name:    "aws_secret",
+		Label:   "AWS_SECRET",
+		Pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	},
+	{
+		Name:  "api_key",
+		Label: "API_KEY",
+		Pattern: regexp.MustCompile(
+			`\b(?:sk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`,
+		),
+	},
+	{
+		Name:    "email",
+		Label:   "EMAIL",
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	},
+	{
+		Name:    "ipv4",
+		Label:   "IP",
+		Pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+		Valid:   func(s string) bool { return net.ParseIP(s) != nil },
+	},
+	{
+		Name:    "ipv6",
+		Label:   "IP",
+		Pattern: regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`),
+		Valid:   validIPv6,
+	},
+}
+
+// validIPv6 accepts addresses with at least two groups and a digit, so that "std::"
+// and "::" are not taken for addresses.
+func validIPv6(s string) bool {
+	if net.ParseIP(s) == nil || !strings.ContainsAny(s, "0123456789") {
+		return false
+	}
+	groups := 0
+	for _, g := range strings.Split(s, ":") {
+		if g != "" {
+			groups++
+		}
+	}
+	return groups >= 2
+}
+
+// DetectorNames returns the names of the built-in detectors.
+func DetectorNames() []string {
+	names := make([]string, 0, len(Builtin))
+	for _, d := range Builtin {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+// Redactor replaces sensitive values with placeholders. The same value always gets the
+// same placeholder, across every text redacted by one Redactor.
+type Redactor struct {
+	detectors []Detector
+	byValue   map[string]string // original value to placeholder
+	byHolder  map[string]string // placeholder to original value
+	next      map[string]int    // last number used per label
+	counts    map[string]int    // matches replaced per label
+}
+
+// New returns a Redactor using the named built-in detectors (all when names is empty)
+// followed by custom patterns, which map a placeholder label to a regular expression.
+func New(names []string, patterns map[string]string) (*Redactor, error) {
+	r := &Redactor{
+		byValue: map[string]string{}, byHolder: map[string]string{}, next: map[string]int{},
+		counts: map[string]int{},
+	}
+	if len(names) == 0 {
+		r.detectors = append(r.detectors, Builtin...)
+	}
+	for _, name := range names {
+		found := false
+		for _, d := range Builtin {
+			if d.Name == strings.ToLower(name) {
+				r.detectors, found = append(r.detectors, d), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf(
+				"unknown detector '%s' (detectors: %s)", name, strings.Join(DetectorNames(), ", "),
+			)
+		}
+	}
+
+	labels := make([]string, 0, len(patterns))
+	for label := range patterns {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if !labelRe.MatchString(label) {
+			return nil, fmt.Errorf("pattern name '%s' must be letters, digits and underscores", label)
+		}
+		re, err := regexp.Compile(patterns[label])
+		if err != nil {
+			return nil, fmt.Errorf("pattern '%s': %w", label, err)
+		}
+		upper := strings.ToUpper(label)
+		r.detectors = append(r.detectors, Detector{Name: strings.ToLower(label), Label: upper, Pattern: re})
+	}
+	return r, nil
+}
+
+var (
+	labelRe       = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	placeholderRe = regexp.MustCompile(`<[A-Z][A-Z0-9_]*_[0-9]+>`)
+)
+
+// Redact returns s with every detected value replaced by its placeholder.
+func (r *Redactor) Redact(s string) string {
+	for _, d := range r.detectors {
+		s = r.apply(d, s)
+	}
+	return s
+}
+
+func (r *Redactor) apply(d Detector, s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range d.Pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3] // the first group is the value
+		}
+		value := s[start:end]
+		if value == "" || start < last || placeholderRe.MatchString(value) ||
+			(d.Valid != nil && !d.Valid(value)) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(r.placeholder(d.Label, value))
+		r.counts[d.Label]++
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (r *Redactor) placeholder(label, value string) string {
+	if p, ok := r.byValue[value]; ok {
+		return p
+	}
+	r.next[label]++
+	p := "<" + label + "_" + strconv.Itoa(r.next[label]) + ">"
+	r.byValue[value], r.byHolder[p] = p, value
+	return p
+}
+
+// Restore returns s with the placeholders this Redactor produced replaced by the
+// original values. Other text that looks like a placeholder is left alone.
+func (r *Redactor) Restore(s string) string {
+	if len(r.byHolder) == 0 {
+		return s
+	}
+	return placeholderRe.ReplaceAllStringFunc(
+		s, func(p string) string {
+			if v, ok := r.byHolder[p]; ok {
+				return v
+			}
+			return p
+		},
+	)
+}
+
+// Counts returns the number of values replaced per placeholder label.
+func (r *Redactor) Counts() map[string]int { return r.counts }
+
+// Total returns the number of values replaced.
+func (r *Redactor) Total() int {
+	n := 0
+	for _, c := range r.counts {
+		n += c
+	}
+	return n
+}
+
+// Summary formats the counts as "EMAIL=2 IP=1 (2 distinct)", sorted by label.
+func (r *Redactor) Summary() string {
+	labels := make([]string, 0, len(r.counts))
+	for label := range r.counts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	parts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%d", label, r.counts[label]))
+	}
+	return fmt.Sprintf("%s (%d distinct)", strings.Join(parts, " "), len(r.byHolder))
+}
+
+// Placeholders returns the placeholders in use with the values they stand for, sorted.
+func (r *Redactor) Placeholders() [][2]string {
+	out := make([][2]string, 0, len(r.byHolder))
+	for p, v := range r.byHolder {
+		out = append(out, [2]string{p, v})
+	}
+	sort.Slice(out, func(i, j int) bool { return placeholderLess(out[i][0], out[j][0]) })
+	return out
+}
+
+// placeholderLess orders placeholders by label, then numerically (<IP_2> before <IP_10>).
+func placeholderLess(a, b string) bool {
+	la, na := splitPlaceholder(a)
+	lb, nb := splitPlaceholder(b)
+	if la != lb {
+		return la < lb
+	}
+	return na < nb
+}
+
+func splitPlaceholder(p string) (string, int) {
+	p = strings.Trim(p, "<>")
+	i := strings.LastIndexByte(p, '_')
+	n, _ := strconv.Atoi(p[i+1:])
+	return p[:i], n
+}
+
+// Restorer restores placeholders in text that arrives in pieces, such as a streamed
+// answer, holding back a trailing partial placeholder until the next piece completes it.
+type Restorer struct {
+	r       *Redactor
+	write   func(string)
+	pending string
+}
+
+// NewRestorer returns a Restorer that writes restored text to write.
+func (r *Redactor) NewRestorer(write func(string)) *Restorer {
+	return &Restorer{r: r, write: write}
+}
+
+// Write restores the placeholders in text, keeping back a possible partial one.
+func (s *Restorer) Write(text string) {
+	text = s.pending + text
+	s.pending = ""
+	if i := strings.LastIndexByte(text, '<'); i >= 0 && !strings.Contains(text[i:], ">") &&
+		len(text)-i <= maxPlaceholderLen && partialRe.MatchString(text[i:]) {
+		text, s.pending = text[:i], text[i:]
+	}
+	if text != "" {
+		s.write(s.r.Restore(text))
+	}
+}
+
+// Flush writes any text held back.
+func (s *Restorer) Flush() {
+	if s.pending != "" {
+		s.write(s.pending)
+		s.pending = ""
+	}
+}
+
+const maxPlaceholderLen = 40
+
+var partialRe = regexp.MustCompile(`^<[A-Z0-9_]*$`)
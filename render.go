@@ -0,0 +1,444 @@
+package main
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Values accepted by --render
+const (
+	renderAuto   = "auto"
+	renderAlways = "always"
+	renderNever  = "never"
+)
+
+// ANSI styles used by the terminal renderer
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiBoldOff   = "\x1b[22m"
+	ansiDim       = "\x1b[2m"
+	ansiItalic    = "\x1b[3m"
+	ansiItalicOff = "\x1b[23m"
+	ansiUnder     = "\x1b[4m"
+	ansiUnderOff  = "\x1b[24m"
+	ansiStrike    = "\x1b[9m"
+	ansiStrikeOff = "\x1b[29m"
+	ansiFgOff     = "\x1b[39m"
+	ansiCyan      = "\x1b[36m"
+	ansiMagenta   = "\x1b[35m"
+	ansiGreen     = "\x1b[32m"
+	ansiYellow    = "\x1b[33m"
+	ansiGray      = "\x1b[90m"
+)
+
+// shouldRender decides whether text answers are rendered as ANSI Markdown. In auto mode
+// this only happens when stdout is a terminal, so pipes and redirects keep the raw text.
+func shouldRender(mode string) bool {
+	switch mode {
+	case renderAlways:
+		return true
+	case renderNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth returns the width of the terminal on stdout, then $COLUMNS, then 80.
+func terminalWidth() int {
+	if w := ttyWidth(os.Stdout); w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return 80
+}
+
+// mdRenderer is an io.Writer that renders Markdown to ANSI-styled text. Input is rendered
+// line by line as soon as each line is complete, so it can sit behind a --stream; table
+// rows are held back until the table ends so the columns can be aligned.
+type mdRenderer struct {
+	out   io.Writer
+	width int
+	buf   string   // incomplete trailing line
+	fence string   // opening fence of the code block being rendered, "" outside code
+	lang  string   // info string of that code block
+	table []string // buffered table rows
+}
+
+func newMarkdownRenderer(out io.Writer, width int) *mdRenderer {
+	if width < 20 {
+		width = 20
+	}
+	return &mdRenderer{out: out, width: width}
+}
+
+func (r *mdRenderer) Write(p []byte) (int, error) {
+	r.buf += string(p)
+	for {
+		i := strings.IndexByte(r.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := r.buf[:i]
+		r.buf = r.buf[i+1:]
+		r.line(strings.TrimSuffix(line, "\r"))
+	}
+	return len(p), nil
+}
+
+// Flush renders any incomplete line and pending table.
+func (r *mdRenderer) Flush() {
+	if r.buf != "" {
+		r.line(r.buf)
+		r.buf = ""
+	}
+	r.flushTable()
+}
+
+func (r *mdRenderer) emit(s string) {
+	_, _ = io.WriteString(r.out, s+"\n")
+}
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdRule    = regexp.MustCompile(`^(?:-\s*){3,}$|^(?:\*\s*){3,}$|^(?:_\s*){3,}$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrdered = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	mdQuote   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	ansiCode  = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+func (r *mdRenderer) line(l string) {
+	trimmed := strings.TrimSpace(l)
+	if r.fence != "" {
+		if f := fenceOf(trimmed); f != "" && f[0] == r.fence[0] && len(f) >= len(r.fence) &&
+			strings.TrimSpace(trimmed[len(f):]) == "" {
+			r.fence = ""
+			return
+		}
+		r.emit("  " + highlightCode(strings.ReplaceAll(l, "\t", "    "), r.lang))
+		return
+	}
+
+	if strings.HasPrefix(trimmed, "|") {
+		r.table = append(r.table, trimmed)
+		return
+	}
+	r.flushTable()
+
+	if f := fenceOf(strings.TrimLeft(l, " ")); f != "" && len(l)-len(strings.TrimLeft(l, " ")) <= 3 {
+		r.fence = f
+		r.lang = strings.ToLower(strings.TrimSpace(strings.TrimLeft(l, " ")[len(f):]))
+		return
+	}
+
+	switch {
+	case trimmed == "":
+		r.emit("")
+	case mdRule.MatchString(trimmed):
+		r.emit(ansiDim + strings.Repeat("─", r.width) + ansiReset)
+	case mdHeading.MatchString(trimmed):
+		m := mdHeading.FindStringSubmatch(trimmed)
+		style := ansiBold
+		switch len(m[1]) {
+		case 1:
+			style = ansiBold + ansiUnder + ansiMagenta
+		case 2:
+			style = ansiBold + ansiMagenta
+		}
+		for _, w := range r.wrap(renderInline(m[2]), "", "") {
+			r.emit(style + w + ansiReset)
+		}
+	case mdQuote.MatchString(l):
+		bar := ansiDim + "│ " + ansiReset
+		for _, w := range r.wrap(renderInline(mdQuote.FindStringSubmatch(l)[1]), bar, bar) {
+			r.emit(w)
+		}
+	case mdBullet.MatchString(l):
+		m := mdBullet.FindStringSubmatch(l)
+		indent := strings.Repeat(" ", len(m[1]))
+		for _, w := range r.wrap(renderInline(m[2]), indent+"  • ", indent+"    ") {
+			r.emit(w)
+		}
+	case mdOrdered.MatchString(l):
+		m := mdOrdered.FindStringSubmatch(l)
+		indent := strings.Repeat(" ", len(m[1]))
+		marker := indent + "  " + m[2] + " "
+		rest := strings.Repeat(" ", utf8.RuneCountInString(marker))
+		for _, w := range r.wrap(renderInline(m[3]), marker, rest) {
+			r.emit(w)
+		}
+	default:
+		for _, w := range r.wrap(renderInline(trimmed), "", "") {
+			r.emit(w)
+		}
+	}
+}
+
+// wrap breaks styled text into lines of at most r.width visible characters.
+func (r *mdRenderer) wrap(text, first, rest string) []string {
+	return wrapWords(text, r.width, first, rest)
+}
+
+// wrapWords breaks styled text into lines of at most width visible characters, starting
+// the first line with first and the others with rest. Words longer than a line are left
+// whole.
+func wrapWords(text string, width int, first, rest string) []string {
+	var lines []string
+	cur, curWidth := first, visibleWidth(first)
+	empty := true
+	for _, word := range strings.Fields(text) {
+		ww := visibleWidth(word)
+		if !empty && curWidth+1+ww > width {
+			lines = append(lines, cur)
+			cur, curWidth, empty = rest, visibleWidth(rest), true
+		}
+		if !empty {
+			cur += " "
+			curWidth++
+		}
+		cur += word
+		curWidth += ww
+		empty = false
+	}
+	return append(lines, cur)
+}
+
+func (r *mdRenderer) flushTable() {
+	if len(r.table) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(r.table))
+	header := -1
+	for _, raw := range r.table {
+		cells := splitTableRow(raw)
+		if isTableSeparator(cells) {
+			if header < 0 && len(rows) == 1 {
+				header = 0
+			}
+			continue
+		}
+		for i, c := range cells {
+			cells[i] = renderInline(c)
+		}
+		rows = append(rows, cells)
+	}
+	r.table = nil
+
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], visibleWidth(c))
+		}
+	}
+	sep := ansiDim + " │ " + ansiReset
+	for ri, row := range rows {
+		cells := make([]string, len(widths))
+		for i := range widths {
+			c := ""
+			if i < len(row) {
+				c = row[i]
+			}
+			if ri == header {
+				c = ansiBold + c + ansiBoldOff
+			}
+			cells[i] = c + strings.Repeat(" ", widths[i]-visibleWidth(c))
+		}
+		r.emit(strings.TrimRight(" "+strings.Join(cells, sep), " "))
+		if ri == header {
+			parts := make([]string, len(widths))
+			for i, w := range widths {
+				parts[i] = strings.Repeat("─", w)
+			}
+			r.emit(ansiDim + "─" + strings.Join(parts, "─┼─") + "─" + ansiReset)
+		}
+	}
+}
+
+func splitTableRow(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	cells := strings.Split(row, "|")
+	for i, c := range cells {
+		cells[i] = strings.TrimSpace(c)
+	}
+	return cells
+}
+
+func isTableSeparator(cells []string) bool {
+	for _, c := range cells {
+		if strings.Trim(c, ":-") != "" || !strings.Contains(c, "-") {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+// renderInline styles code spans, bold, italic, strikethrough and links.
+func renderInline(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '`':
+			if j := strings.IndexByte(rest[1:], '`'); j >= 0 {
+				sb.WriteString(ansiCyan + rest[1:1+j] + ansiFgOff)
+				i += j + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if j := strings.Index(rest[2:], rest[:2]); j > 0 {
+				sb.WriteString(ansiBold + renderInline(rest[2:2+j]) + ansiBoldOff)
+				i += j + 4
+				continue
+			}
+		case strings.HasPrefix(rest, "~~"):
+			if j := strings.Index(rest[2:], "~~"); j > 0 {
+				sb.WriteString(ansiStrike + renderInline(rest[2:2+j]) + ansiStrikeOff)
+				i += j + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			if italicOpens(s, i) {
+				if j := strings.IndexByte(rest[1:], rest[0]); j > 0 && rest[j] != ' ' {
+					sb.WriteString(ansiItalic + renderInline(rest[1:1+j]) + ansiItalicOff)
+					i += j + 2
+					continue
+				}
+			}
+		case rest[0] == '[':
+			if m := mdLink.FindStringSubmatch(rest); m != nil {
+				sb.WriteString(ansiUnder + m[1] + ansiUnderOff)
+				if m[2] != m[1] {
+					sb.WriteString(ansiDim + " (" + m[2] + ")" + ansiReset)
+				}
+				i += len(m[0])
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(rest)
+		sb.WriteString(rest[:size])
+		i += size
+	}
+	return sb.String()
+}
+
+var mdLink = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+
+// italicOpens reports whether the * or _ at s[i] can start emphasis: it must be followed
+// by a non-space and, for _, not sit inside a word (snake_case stays as is).
+func italicOpens(s string, i int) bool {
+	if i+1 >= len(s) || s[i+1] == ' ' {
+		return false
+	}
+	if s[i] == '_' && i > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(s[:i])
+		return !unicode.IsLetter(prev) && !unicode.IsDigit(prev)
+	}
+	return true
+}
+
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiCode.ReplaceAllString(s, ""))
+}
+
+var codeKeywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"def": true, "defer": true, "do": true, "done": true, "elif": true, "else": true,
+	"esac": true, "export": true, "false": true, "fi": true, "fn": true, "for": true,
+	"from": true, "func": true, "function": true, "go": true, "if": true, "import": true,
+	"in": true, "interface": true, "let": true, "map": true, "match": true, "mut": true,
+	"new": true, "nil": true, "None": true, "null": true, "package": true, "pub": true,
+	"range": true, "return": true, "select": true, "self": true, "struct": true, "switch": true,
+	"then": true, "this": true, "throw": true, "true": true, "True": true, "False": true,
+	"try": true, "type": true, "use": true, "var": true, "while": true, "with": true,
+	"yield": true, "async": true, "await": true, "impl": true, "enum": true, "public": true,
+	"private": true, "static": true, "void": true, "lambda": true, "pass": true, "raise": true,
+	"except": true, "finally": true, "not": true, "and": true, "or": true, "is": true,
+	"SELECT": true, "FROM": true, "WHERE": true, "JOIN": true, "INSERT": true, "UPDATE": true,
+	"DELETE": true, "CREATE": true, "TABLE": true, "GROUP": true, "ORDER": true, "BY": true,
+}
+
+// commentPrefixes returns the line comment markers for a code block language.
+func commentPrefixes(lang string) []string {
+	switch lang {
+	case "sh", "bash", "zsh", "shell", "console", "python", "py", "ruby", "rb", "yaml", "yml",
+		"toml", "perl", "r", "dockerfile", "make", "makefile", "ini", "conf":
+		return []string{"#"}
+	case "sql", "lua", "haskell", "hs":
+		return []string{"--"}
+	case "":
+		return []string{"//", "#"}
+	}
+	return []string{"//"}
+}
+
+// highlightCode applies basic syntax colors to one line of code: keywords, strings,
+// numbers and line comments.
+func highlightCode(line, lang string) string {
+	comments := commentPrefixes(lang)
+	var sb strings.Builder
+	for i := 0; i < len(line); {
+		rest := line[i:]
+		for _, c := range comments {
+			if strings.HasPrefix(rest, c) && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+				sb.WriteString(ansiGray + rest + ansiFgOff)
+				return sb.String()
+			}
+		}
+		ch := rest[0]
+		switch {
+		case ch == '"' || ch == '\'' || ch == '`':
+			j := 1
+			for j < len(rest) && rest[j] != ch {
+				if rest[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(rest))
+			sb.WriteString(ansiGreen + rest[:j] + ansiFgOff)
+			i += j
+		case isIdentStart(ch):
+			j := 1
+			for j < len(rest) && (isIdentStart(rest[j]) || (rest[j] >= '0' && rest[j] <= '9')) {
+				j++
+			}
+			word := rest[:j]
+			if codeKeywords[word] {
+				sb.WriteString(ansiMagenta + word + ansiFgOff)
+			} else {
+				sb.WriteString(word)
+			}
+			i += j
+		case ch >= '0' && ch <= '9':
+			j := 1
+			for j < len(rest) && (isIdentStart(rest[j]) || (rest[j] >= '0' && rest[j] <= '9') || rest[j] == '.') {
+				j++
+			}
+			sb.WriteString(ansiYellow + rest[:j] + ansiFgOff)
+			i += j
+		default:
+			sb.WriteByte(ch)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func stripANSI(s string) string { return ansiCode.ReplaceAllString(s, "") }
+
+func TestMarkdownRenderer(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // with ANSI codes stripped
+	}{
+		{
+			name: "heading and inline styles",
+			in:   "# Title\nSome **bold**, *italic*, `code` and a [link](https://x.dev).\n",
+			want: "Title\nSome bold, italic,\ncode and a link\n(https://x.dev).\n",
+		},
+		{
+			name: "snake_case is not italic",
+			in:   "use my_var_name here\n",
+			want: "use my_var_name here\n",
+		},
+		{
+			name: "lists and quotes",
+			in:   "- one\n  - nested\n1. first\n> quoted\n",
+			want: "  • one\n    • nested\n  1. first\n│ quoted\n",
+		},
+		{
+			name: "code block keeps its text and drops fences",
+			in:   "```go\nfunc main() { // hi\n}\n```\nafter\n",
+			want: "  func main() { // hi\n  }\nafter\n",
+		},
+		{
+			name: "table is aligned",
+			in:   "| a | long header |\n|---|:---:|\n| 1 | 2 |\n",
+			want: " a │ long header\n───┼─────────────\n 1 │ 2\n",
+		},
+		{
+			name: "wraps to width",
+			in:   "alpha beta gamma delta epsilon\n",
+			want: "alpha beta gamma\ndelta epsilon\n",
+		},
+		{
+			name: "incomplete last line is rendered on flush",
+			in:   "no newline",
+			want: "no newline\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				r := newMarkdownRenderer(&buf, 20)
+				_, _ = r.Write([]byte(tt.in))
+				r.Flush()
+				if got := stripANSI(buf.String()); got != tt.want {
+					t.Errorf("rendered\n%q\nwant\n%q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestMarkdownRendererIncremental(t *testing.T) {
+	var buf bytes.Buffer
+	r := newMarkdownRenderer(&buf, 80)
+
+	// Deltas split mid-line and mid-marker, as they arrive from a stream
+	for _, d := range []string{"## He", "ading\nSome **bo", "ld** text", "\n| x |\n"} {
+		_, _ = r.Write([]byte(d))
+	}
+	if got := stripANSI(buf.String()); got != "Heading\nSome bold text\n" {
+		t.Errorf("complete lines should be rendered as they arrive, got %q", got)
+	}
+	if strings.Contains(stripANSI(buf.String()), " x") {
+		t.Error("table rows should be held until the table ends")
+	}
+	r.Flush()
+	if !strings.HasSuffix(stripANSI(buf.String()), " x\n") {
+		t.Errorf("table not flushed: %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), ansiBold+"bold"+ansiBoldOff) {
+		t.Errorf("expected bold styling, got %q", buf.String())
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	got := highlightCode(`x := "hi" // note`, "go")
+	if !strings.Contains(got, ansiGreen+`"hi"`) || !strings.Contains(got, ansiGray+"// note") {
+		t.Errorf("unexpected highlighting %q", got)
+	}
+	if got := highlightCode("echo $HOME # home", "bash"); !strings.Contains(got, ansiGray+"# home") {
+		t.Errorf("expected shell comment, got %q", got)
+	}
+	if got := highlightCode("return 42", "python"); !strings.Contains(got, ansiMagenta+"return") ||
+		!strings.Contains(got, ansiYellow+"42") {
+		t.Errorf("expected keyword and number, got %q", got)
+	}
+}
+
+func TestShouldRender(t *testing.T) {
+	if !shouldRender(renderAlways) || shouldRender(renderNever) {
+		t.Error("always/never should not depend on the terminal")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if shouldRender(renderAuto) {
+		t.Error("NO_COLOR should disable auto rendering")
+	}
+}
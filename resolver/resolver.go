@@ -2,7 +2,6 @@ package resolver
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 
@@ -37,13 +36,49 @@ var modelHints = []struct {
 	{"llama", "groq"},
 }
 
-func ResolveProviderAndModel(modelArg string) (*provider.ProviderResolution, error) {
+// Settings are everything a provider and model are resolved from. The NURO_* fields
+// come from a .nuro profile or the environment; Env holds the other variables that
+// resolution consults (provider keys like OPENAI_API_KEY, OPENAI_BASE_URL, OLLAMA_HOST,
+// and variables named by "-m $VAR"). Resolution never reads the process environment, so
+// callers decide what it sees.
+type Settings struct {
+	APIKey   string // NURO_API_KEY
+	BaseURL  string // NURO_BASE_URL
+	Provider string // NURO_PROVIDER
+	Model    string // NURO_MODEL
+
+	Env map[string]string
+}
+
+// EnvSettings returns the settings given by environ, in the form of os.Environ.
+func EnvSettings(environ []string) Settings {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return Settings{
+		APIKey:   env["NURO_API_KEY"],
+		BaseURL:  env["NURO_BASE_URL"],
+		Provider: env["NURO_PROVIDER"],
+		Model:    env["NURO_MODEL"],
+		Env:      env,
+	}
+}
+
+// hasNuro reports whether any NURO_* setting is present (e.g. a profile was applied).
+func (s Settings) hasNuro() bool {
+	return s.APIKey != "" || s.Provider != "" || s.Model != "" || s.BaseURL != ""
+}
+
+func ResolveProviderAndModel(s Settings, modelArg string) (*provider.ProviderResolution, error) {
 	// Handle model argument with $ENV indirection
 	var cliModel string
 	if modelArg != "" {
 		if strings.HasPrefix(modelArg, "$") {
 			ref := strings.TrimPrefix(modelArg, "$")
-			cliModel = os.Getenv(ref)
+			cliModel = s.Env[ref]
 			if cliModel == "" {
 				return nil, fmt.Errorf("model env '%s' is empty or unset", ref)
 			}
@@ -52,24 +87,20 @@ func ResolveProviderAndModel(modelArg string) (*provider.ProviderResolution, err
 		}
 	}
 
-	// If any NURO_ environment variables are present (profile applied), prefer them.
+	// If any NURO_* settings are present (profile applied), prefer them.
 	// This makes .nuro profile values take precedence over other system env vars,
 	// while still allowing an explicit CLI model to override the profile model.
-	if os.Getenv("NURO_API_KEY") != "" || os.Getenv("NURO_PROVIDER") != "" || os.Getenv("NURO_MODEL") != "" || os.Getenv("NURO_BASE_URL") != "" {
-		return resolveWithNuroVars(os.Getenv("NURO_API_KEY"), cliModel)
+	if s.hasNuro() {
+		return resolveWithNuroVars(s, cliModel)
 	}
 
 	// Auto-discover from common provider keys
-	return autoDiscoverProvider(cliModel)
+	return autoDiscoverProvider(s, cliModel)
 }
 
-func resolveWithNuroVars(nuroKey, cliModel string) (*provider.ProviderResolution, error) {
-	nuroModel := os.Getenv("NURO_MODEL")
-	nuroProv := strings.ToLower(os.Getenv("NURO_PROVIDER"))
-	nuroBase := os.Getenv("NURO_BASE_URL")
-
-	prov := nuroProv
-	model := firstNonEmpty(cliModel, nuroModel)
+func resolveWithNuroVars(s Settings, cliModel string) (*provider.ProviderResolution, error) {
+	prov := strings.ToLower(s.Provider)
+	model := firstNonEmpty(cliModel, s.Model)
 
 	if prov == "" {
 		prov = inferProviderFromModel(model)
@@ -79,9 +110,12 @@ func resolveWithNuroVars(nuroKey, cliModel string) (*provider.ProviderResolution
 	}
 
 	if model == "" {
-		if prov == "openai" {
+		switch prov {
+		case "openai":
 			model = "gpt-4o-mini"
-		} else {
+		case "mock":
+			model = "mock"
+		default:
 			return nil, fmt.Errorf("no model specified; set --model or NURO_MODEL")
 		}
 	}
@@ -89,16 +123,16 @@ func resolveWithNuroVars(nuroKey, cliModel string) (*provider.ProviderResolution
 	return &provider.ProviderResolution{
 		ProviderName: prov,
 		Model:        model,
-		APIKey:       nuroKey,
-		BaseURL:      firstNonEmpty(nuroBase, os.Getenv("OPENAI_BASE_URL")),
+		APIKey:       s.APIKey,
+		BaseURL:      firstNonEmpty(s.BaseURL, s.Env["OPENAI_BASE_URL"]),
 		KeySource:    "NURO_API_KEY",
 	}, nil
 }
 
-func autoDiscoverProvider(cliModel string) (*provider.ProviderResolution, error) {
+func autoDiscoverProvider(s Settings, cliModel string) (*provider.ProviderResolution, error) {
 	found := make([]string, 0, len(providerEnv))
 	for prov, env := range providerEnv {
-		if os.Getenv(env) != "" {
+		if s.Env[env] != "" {
 			found = append(found, prov)
 		}
 	}
@@ -129,7 +163,7 @@ func autoDiscoverProvider(cliModel string) (*provider.ProviderResolution, error)
 		}
 	}
 
-	key := os.Getenv(providerEnv[chosen])
+	key := s.Env[providerEnv[chosen]]
 	model := cliModel
 	if model == "" {
 		model = defaultModelFor(chosen)
@@ -137,7 +171,7 @@ func autoDiscoverProvider(cliModel string) (*provider.ProviderResolution, error)
 
 	baseURL := ""
 	if chosen == "openai" {
-		baseURL = os.Getenv("OPENAI_BASE_URL")
+		baseURL = s.Env["OPENAI_BASE_URL"]
 	}
 	return &provider.ProviderResolution{
 		ProviderName: chosen,
@@ -148,6 +182,64 @@ func autoDiscoverProvider(cliModel string) (*provider.ProviderResolution, error)
 	}, nil
 }
 
+// ResolveModelRef resolves a model for commands that address several models at once.
+// "provider:model" (e.g. "ollama:llama3.1:8b") picks the provider explicitly, using the
+// profile's settings when it selected that provider and the provider's usual environment
+// otherwise; any other reference resolves like -m.
+func ResolveModelRef(s Settings, ref string) (*provider.ProviderResolution, error) {
+	if prov, model, ok := strings.Cut(ref, ":"); ok {
+		prov = strings.ToLower(prov)
+		if _, known := providerEnv[prov]; known || prov == "mock" {
+			if strings.EqualFold(s.Provider, prov) {
+				return resolveWithNuroVars(s, model)
+			}
+			return ResolveFallback(s, prov, model, "", "")
+		}
+	}
+	return ResolveProviderAndModel(s, ref)
+}
+
+// ResolveFallback resolves one provider of a fallback chain. A missing key, base URL or
+// model is taken from the provider's usual environment variable (e.g. OPENAI_API_KEY,
+// OLLAMA_HOST) in s.Env and its default model.
+func ResolveFallback(
+	s Settings, providerName, model, apiKey, baseURL string,
+) (*provider.ProviderResolution, error) {
+	prov := strings.ToLower(providerName)
+	env, known := providerEnv[prov]
+	if !known && prov != "mock" {
+		return nil, fmt.Errorf("unknown fallback provider '%s'", providerName)
+	}
+	keySource := "fallback"
+	if apiKey == "" && env != "" && prov != "ollama" {
+		apiKey = s.Env[env]
+		keySource = env
+	}
+	switch {
+	case baseURL != "":
+	case prov == "openai":
+		baseURL = s.Env["OPENAI_BASE_URL"]
+	case prov == "ollama":
+		baseURL = s.Env["OLLAMA_HOST"]
+		if baseURL != "" && !strings.Contains(baseURL, "://") {
+			baseURL = "http://" + baseURL
+		}
+	}
+	if model == "" {
+		model = defaultModelFor(prov)
+		if prov == "mock" {
+			model = "mock"
+		}
+	}
+	return &provider.ProviderResolution{
+		ProviderName: prov,
+		Model:        model,
+		APIKey:       apiKey,
+		BaseURL:      baseURL,
+		KeySource:    keySource,
+	}, nil
+}
+
 func envList() string {
 	var parts []string
 	for _, env := range providerEnv {
@@ -208,4 +300,82 @@ func firstNonEmpty(a, b string) string {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
+
+// ValidateModel checks model against the models a provider reports. When it is not
+// available, the error lists close matches so typos can be fixed without a raw 404.
+func ValidateModel(model string, available []string) error {
+	for _, a := range available {
+		if a == model {
+			return nil
+		}
+	}
+	// Ollama tags default to ":latest" when no tag is given
+	if !strings.Contains(model, ":") && contains(available, model+":latest") {
+		return nil
+	}
+	if s := SuggestModels(model, available, 3); len(s) > 0 {
+		return fmt.Errorf("model '%s' not found; did you mean: %s?", model, strings.Join(s, ", "))
+	}
+	return fmt.Errorf("model '%s' not found; run 'nuro models' to list available models", model)
+}
+
+// SuggestModels returns up to max entries of available that are close to model,
+// best match first. Candidates are ranked by edit distance; prefix matches are included
+// even when the distance is large (e.g. "llama3" for "llama3.1:8b").
+func SuggestModels(model string, available []string, max int) []string {
+	m := strings.ToLower(model)
+	type scored struct {
+		name  string
+		score int
+	}
+	var candidates []scored
+	for _, a := range available {
+		al := strings.ToLower(a)
+		d := levenshtein(m, al)
+		limit := len(m) / 3
+		if limit < 2 {
+			limit = 2
+		}
+		if d <= limit || (m != "" && (strings.HasPrefix(al, m) || strings.HasPrefix(m, al))) {
+			candidates = append(candidates, scored{a, d})
+		}
+	}
+	sort.SliceStable(
+		candidates, func(i, j int) bool {
+			if candidates[i].score != candidates[j].score {
+				return candidates[i].score < candidates[j].score
+			}
+			return candidates[i].name < candidates[j].name
+		},
+	)
+	var out []string
+	for _, c := range candidates {
+		if len(out) == max {
+			break
+		}
+		out = append(out, c.name)
+	}
+	return out
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -1,39 +1,14 @@
 package resolver
 
 import (
-	"os"
 	"testing"
 )
 
 func TestOllamaIntegrationViaOpenAIAdapter(t *testing.T) {
-	// Save original env vars to restore later
-	origAPIKey := os.Getenv("NURO_API_KEY")
-	origBaseURL := os.Getenv("NURO_BASE_URL")
-	origProvider := os.Getenv("NURO_PROVIDER")
-	defer func() {
-		if origAPIKey == "" {
-			_ = os.Unsetenv("NURO_API_KEY")
-		} else {
-			_ = os.Setenv("NURO_API_KEY", origAPIKey)
-		}
-		if origBaseURL == "" {
-			_ = os.Unsetenv("NURO_BASE_URL")
-		} else {
-			_ = os.Setenv("NURO_BASE_URL", origBaseURL)
-		}
-		if origProvider == "" {
-			_ = os.Unsetenv("NURO_PROVIDER")
-		} else {
-			_ = os.Setenv("NURO_PROVIDER", origProvider)
-		}
-	}()
-
 	// Test Ollama configuration using OpenAI adapter
-	_ = os.Setenv("NURO_API_KEY", "ollama")
-	_ = os.Setenv("NURO_BASE_URL", "http://localhost:11434/v1")
-	_ = os.Setenv("NURO_PROVIDER", "openai")
+	settings := Settings{APIKey: "ollama", BaseURL: "http://localhost:11434/v1", Provider: "openai"}
 
-	res, err := ResolveProviderAndModel("llama3.1:8b")
+	res, err := ResolveProviderAndModel(settings, "llama3.1:8b")
 	if err != nil {
 		t.Fatalf("Failed to resolve Ollama config: %v", err)
 	}
@@ -61,32 +36,9 @@ func TestOllamaIntegrationViaOpenAIAdapter(t *testing.T) {
 
 func TestOllamaWithoutExplicitProvider(t *testing.T) {
 	// Test that Ollama works when NURO_PROVIDER is not set (should default based on model)
-	origAPIKey := os.Getenv("NURO_API_KEY")
-	origBaseURL := os.Getenv("NURO_BASE_URL")
-	origProvider := os.Getenv("NURO_PROVIDER")
-	defer func() {
-		if origAPIKey == "" {
-			_ = os.Unsetenv("NURO_API_KEY")
-		} else {
-			_ = os.Setenv("NURO_API_KEY", origAPIKey)
-		}
-		if origBaseURL == "" {
-			_ = os.Unsetenv("NURO_BASE_URL")
-		} else {
-			_ = os.Setenv("NURO_BASE_URL", origBaseURL)
-		}
-		if origProvider == "" {
-			_ = os.Unsetenv("NURO_PROVIDER")
-		} else {
-			_ = os.Setenv("NURO_PROVIDER", origProvider)
-		}
-	}()
-
-	_ = os.Setenv("NURO_API_KEY", "ollama")
-	_ = os.Setenv("NURO_BASE_URL", "http://localhost:11434/v1")
-	_ = os.Unsetenv("NURO_PROVIDER") // Unset to test default behavior
-
-	res, err := ResolveProviderAndModel("gpt-4o-mini")
+	settings := Settings{APIKey: "ollama", BaseURL: "http://localhost:11434/v1"}
+
+	res, err := ResolveProviderAndModel(settings, "gpt-4o-mini")
 	if err != nil {
 		t.Fatalf("Failed to resolve Ollama config without explicit provider: %v", err)
 	}
@@ -103,30 +55,7 @@ func TestOllamaWithoutExplicitProvider(t *testing.T) {
 
 func TestOllamaWithDifferentModels(t *testing.T) {
 	// Test with various Ollama model names
-	origAPIKey := os.Getenv("NURO_API_KEY")
-	origBaseURL := os.Getenv("NURO_BASE_URL")
-	origProvider := os.Getenv("NURO_PROVIDER")
-	defer func() {
-		if origAPIKey == "" {
-			_ = os.Unsetenv("NURO_API_KEY")
-		} else {
-			_ = os.Setenv("NURO_API_KEY", origAPIKey)
-		}
-		if origBaseURL == "" {
-			_ = os.Unsetenv("NURO_BASE_URL")
-		} else {
-			_ = os.Setenv("NURO_BASE_URL", origBaseURL)
-		}
-		if origProvider == "" {
-			_ = os.Unsetenv("NURO_PROVIDER")
-		} else {
-			_ = os.Setenv("NURO_PROVIDER", origProvider)
-		}
-	}()
-
-	_ = os.Setenv("NURO_API_KEY", "ollama")
-	_ = os.Setenv("NURO_BASE_URL", "http://localhost:11434/v1")
-	_ = os.Setenv("NURO_PROVIDER", "openai")
+	settings := Settings{APIKey: "ollama", BaseURL: "http://localhost:11434/v1", Provider: "openai"}
 
 	testModels := []string{
 		"llama3.1:8b",
@@ -139,7 +68,7 @@ func TestOllamaWithDifferentModels(t *testing.T) {
 	for _, model := range testModels {
 		t.Run(
 			"model_"+model, func(t *testing.T) {
-				res, err := ResolveProviderAndModel(model)
+				res, err := ResolveProviderAndModel(settings, model)
 				if err != nil {
 					t.Fatalf("Failed to resolve Ollama config for model %s: %v", model, err)
 				}
@@ -162,47 +91,12 @@ func TestOllamaWithDifferentModels(t *testing.T) {
 
 func TestOllamaEnvironmentPrecedence(t *testing.T) {
 	// Test that NURO_* variables take precedence over regular provider env vars
-	origAPIKey := os.Getenv("NURO_API_KEY")
-	origBaseURL := os.Getenv("NURO_BASE_URL")
-	origProvider := os.Getenv("NURO_PROVIDER")
-	origOpenAIKey := os.Getenv("OPENAI_API_KEY")
-	origOpenAIBase := os.Getenv("OPENAI_BASE_URL")
-	defer func() {
-		if origAPIKey == "" {
-			_ = os.Unsetenv("NURO_API_KEY")
-		} else {
-			_ = os.Setenv("NURO_API_KEY", origAPIKey)
-		}
-		if origBaseURL == "" {
-			_ = os.Unsetenv("NURO_BASE_URL")
-		} else {
-			_ = os.Setenv("NURO_BASE_URL", origBaseURL)
-		}
-		if origProvider == "" {
-			_ = os.Unsetenv("NURO_PROVIDER")
-		} else {
-			_ = os.Setenv("NURO_PROVIDER", origProvider)
-		}
-		if origOpenAIKey == "" {
-			_ = os.Unsetenv("OPENAI_API_KEY")
-		} else {
-			_ = os.Setenv("OPENAI_API_KEY", origOpenAIKey)
-		}
-		if origOpenAIBase == "" {
-			_ = os.Unsetenv("OPENAI_BASE_URL")
-		} else {
-			_ = os.Setenv("OPENAI_BASE_URL", origOpenAIBase)
-		}
-	}()
-
-	// Set both NURO_* and OPENAI_* vars to ensure NURO_* takes precedence
-	_ = os.Setenv("NURO_API_KEY", "ollama")
-	_ = os.Setenv("NURO_BASE_URL", "http://localhost:11434/v1")
-	_ = os.Setenv("NURO_PROVIDER", "openai")
-	_ = os.Setenv("OPENAI_API_KEY", "sk-real-openai-key")
-	_ = os.Setenv("OPENAI_BASE_URL", "https://api.openai.com/v1")
-
-	res, err := ResolveProviderAndModel("llama3.1:8b")
+	settings := Settings{
+		APIKey: "ollama", BaseURL: "http://localhost:11434/v1", Provider: "openai",
+		Env: map[string]string{"OPENAI_API_KEY": "sk-real-openai-key", "OPENAI_BASE_URL": "https://api.openai.com/v1"},
+	}
+
+	res, err := ResolveProviderAndModel(settings, "llama3.1:8b")
 	if err != nil {
 		t.Fatalf("Failed to resolve with precedence test: %v", err)
 	}
@@ -223,33 +117,9 @@ func TestOllamaEnvironmentPrecedence(t *testing.T) {
 
 func TestNativeOllamaProvider(t *testing.T) {
 	// Test native Ollama provider resolution
-	origAPIKey := os.Getenv("NURO_API_KEY")
-	origBaseURL := os.Getenv("NURO_BASE_URL")
-	origProvider := os.Getenv("NURO_PROVIDER")
-	defer func() {
-		if origAPIKey == "" {
-			_ = os.Unsetenv("NURO_API_KEY")
-		} else {
-			_ = os.Setenv("NURO_API_KEY", origAPIKey)
-		}
-		if origBaseURL == "" {
-			_ = os.Unsetenv("NURO_BASE_URL")
-		} else {
-			_ = os.Setenv("NURO_BASE_URL", origBaseURL)
-		}
-		if origProvider == "" {
-			_ = os.Unsetenv("NURO_PROVIDER")
-		} else {
-			_ = os.Setenv("NURO_PROVIDER", origProvider)
-		}
-	}()
-
-	// Test native Ollama configuration
-	_ = os.Setenv("NURO_API_KEY", "dummy") // Ollama doesn't need a real API key
-	_ = os.Setenv("NURO_BASE_URL", "http://localhost:11434")
-	_ = os.Setenv("NURO_PROVIDER", "ollama")
-
-	res, err := ResolveProviderAndModel("llama3.1:8b")
+	settings := Settings{APIKey: "dummy", BaseURL: "http://localhost:11434", Provider: "ollama"}
+
+	res, err := ResolveProviderAndModel(settings, "llama3.1:8b")
 	if err != nil {
 		t.Fatalf("Failed to resolve native Ollama config: %v", err)
 	}
@@ -277,32 +147,9 @@ func TestNativeOllamaProvider(t *testing.T) {
 
 func TestNativeOllamaDefaultModel(t *testing.T) {
 	// Test that native Ollama uses correct default model
-	origAPIKey := os.Getenv("NURO_API_KEY")
-	origProvider := os.Getenv("NURO_PROVIDER")
-	origModel := os.Getenv("NURO_MODEL")
-	defer func() {
-		if origAPIKey == "" {
-			_ = os.Unsetenv("NURO_API_KEY")
-		} else {
-			_ = os.Setenv("NURO_API_KEY", origAPIKey)
-		}
-		if origProvider == "" {
-			_ = os.Unsetenv("NURO_PROVIDER")
-		} else {
-			_ = os.Setenv("NURO_PROVIDER", origProvider)
-		}
-		if origModel == "" {
-			_ = os.Unsetenv("NURO_MODEL")
-		} else {
-			_ = os.Setenv("NURO_MODEL", origModel)
-		}
-	}()
-
-	_ = os.Setenv("NURO_API_KEY", "dummy")
-	_ = os.Setenv("NURO_PROVIDER", "ollama")
-	_ = os.Setenv("NURO_MODEL", "llama3.1:8b") // Set via env instead of CLI arg
-
-	res, err := ResolveProviderAndModel("") // No model specified via CLI
+	settings := Settings{APIKey: "dummy", Provider: "ollama", Model: "llama3.1:8b"}
+
+	res, err := ResolveProviderAndModel(settings, "") // No model specified via CLI
 	if err != nil {
 		t.Fatalf("Failed to resolve native Ollama config with env model: %v", err)
 	}
@@ -0,0 +1,129 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuggestModels(t *testing.T) {
+	available := []string{"gpt-4o", "gpt-4o-mini", "gpt-5", "llama3.1:8b", "mistral:7b"}
+
+	tests := []struct {
+		model    string
+		expected string
+	}{
+		{"gpt-4o-mnii", "gpt-4o-mini"},
+		{"llama3.1:8", "llama3.1:8b"},
+		{"mistrl:7b", "mistral:7b"},
+	}
+	for _, tt := range tests {
+		got := SuggestModels(tt.model, available, 3)
+		if len(got) == 0 || got[0] != tt.expected {
+			t.Errorf("SuggestModels(%q) = %v, expected first %q", tt.model, got, tt.expected)
+		}
+	}
+
+	if got := SuggestModels("completely-different", available, 3); len(got) != 0 {
+		t.Errorf("expected no suggestions, got %v", got)
+	}
+}
+
+func TestValidateModel(t *testing.T) {
+	available := []string{"gpt-4o-mini", "llama3.1:latest"}
+
+	if err := ValidateModel("gpt-4o-mini", available); err != nil {
+		t.Errorf("expected exact match to validate, got %v", err)
+	}
+	if err := ValidateModel("llama3.1", available); err != nil {
+		t.Errorf("expected implicit :latest tag to validate, got %v", err)
+	}
+
+	err := ValidateModel("gpt-4o-mni", available)
+	if err == nil || !strings.Contains(err.Error(), "did you mean: gpt-4o-mini") {
+		t.Errorf("expected suggestion in error, got %v", err)
+	}
+
+	err = ValidateModel("zzz", available)
+	if err == nil || !strings.Contains(err.Error(), "nuro models") {
+		t.Errorf("expected hint to run 'nuro models', got %v", err)
+	}
+}
+
+func TestResolveFallback(t *testing.T) {
+	settings := Settings{Env: map[string]string{"OPENAI_API_KEY": "sk-env-key", "OLLAMA_HOST": "gpu-box:11434"}}
+
+	tests := []struct {
+		name                        string
+		provider, model, key, base  string
+		wantModel, wantKey, wantURL string
+		wantErr                     bool
+	}{
+		{name: "openai from env", provider: "openai", model: "gpt-4o-mini", wantModel: "gpt-4o-mini", wantKey: "sk-env-key"},
+		{name: "explicit key", provider: "openai", key: "sk-own", wantModel: "gpt-4o-mini", wantKey: "sk-own"},
+		{name: "ollama host", provider: "ollama", model: "llama3.1:8b", wantModel: "llama3.1:8b", wantURL: "http://gpu-box:11434"},
+		{name: "explicit base url", provider: "ollama", base: "http://other:11434", wantModel: "llama3.1:8b", wantURL: "http://other:11434"},
+		{name: "mock", provider: "mock", wantModel: "mock"},
+		{name: "unknown", provider: "nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				res, err := ResolveFallback(settings, tt.provider, tt.model, tt.key, tt.base)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("expected an error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Model != tt.wantModel || res.APIKey != tt.wantKey || res.BaseURL != tt.wantURL {
+					t.Errorf("unexpected resolution %+v", res)
+				}
+			},
+		)
+	}
+}
+
+func TestResolveModelRef(t *testing.T) {
+	settings := Settings{Provider: "openai", APIKey: "sk-profile", Model: "gpt-4o-mini"}
+
+	tests := []struct {
+		ref                     string
+		wantProvider, wantModel string
+		wantKey                 string
+	}{
+		{"gpt-4o", "openai", "gpt-4o", "sk-profile"},
+		{"openai:gpt-4.1", "openai", "gpt-4.1", "sk-profile"},
+		{"ollama:llama3.1:8b", "ollama", "llama3.1:8b", ""},
+		{"mock:", "mock", "mock", ""},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.ref, func(t *testing.T) {
+				res, err := ResolveModelRef(settings, tt.ref)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.ProviderName != tt.wantProvider || res.Model != tt.wantModel || res.APIKey != tt.wantKey {
+					t.Errorf("unexpected resolution %+v", res)
+				}
+			},
+		)
+	}
+}
+
+func TestEnvSettings(t *testing.T) {
+	s := EnvSettings([]string{"NURO_PROVIDER=ollama", "NURO_MODEL=qwen2.5:7b", "OLLAMA_HOST=gpu:11434", "EMPTY="})
+	if s.Provider != "ollama" || s.Model != "qwen2.5:7b" || s.APIKey != "" || s.Env["OLLAMA_HOST"] != "gpu:11434" {
+		t.Errorf("unexpected settings %+v", s)
+	}
+	res, err := ResolveProviderAndModel(s, "")
+	if err != nil || res.ProviderName != "ollama" || res.Model != "qwen2.5:7b" {
+		t.Errorf("unexpected resolution %+v, %v", res, err)
+	}
+	if _, err := ResolveProviderAndModel(Settings{}, "$MODEL"); err == nil {
+		t.Error("expected an error for an unset model variable")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+import "os"
+
+// ttyWidth is not supported on this platform; terminalWidth falls back to $COLUMNS.
+func ttyWidth(*os.File) int { return 0 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// marshalYAML renders v (structs, pointers, slices, strings, numbers and bools) as a YAML
+// document. Field names and omitempty come from the json tags, so --output yaml always
+// mirrors --output json. Multi-line strings are written as literal blocks.
+func marshalYAML(v any) []byte {
+	var sb strings.Builder
+	writeYAMLValue(&sb, reflect.ValueOf(v), 0)
+	return []byte(sb.String())
+}
+
+func writeYAMLValue(sb *strings.Builder, v reflect.Value, indent int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	pad := strings.Repeat("  ", indent)
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			fv := v.Field(i)
+			name, omitEmpty := yamlFieldName(sf)
+			// Like encoding/json, omitempty never drops a struct
+			if name == "-" || (omitEmpty && fv.Kind() != reflect.Struct && fv.IsZero()) {
+				continue
+			}
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				writeYAMLValue(sb, fv, indent)
+				continue
+			}
+			sb.WriteString(pad + name + ":")
+			writeYAMLField(sb, fv, indent)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			sb.WriteString(pad + "-")
+			writeYAMLField(sb, v.Index(i), indent)
+		}
+	}
+}
+
+// writeYAMLField writes the value following a "key:" or "-" prefix.
+func writeYAMLField(sb *strings.Builder, v reflect.Value, indent int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			sb.WriteString(" null\n")
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		sb.WriteString("\n")
+		writeYAMLValue(sb, v, indent+1)
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			sb.WriteString(" []\n")
+			return
+		}
+		sb.WriteString("\n")
+		writeYAMLValue(sb, v, indent+1)
+	case reflect.String:
+		writeYAMLString(sb, v.String(), indent+1)
+	case reflect.Bool:
+		sb.WriteString(" " + strconv.FormatBool(v.Bool()) + "\n")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sb.WriteString(" " + strconv.FormatInt(v.Int(), 10) + "\n")
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		sb.WriteString(" " + strconv.FormatUint(v.Uint(), 10) + "\n")
+	case reflect.Float32, reflect.Float64:
+		sb.WriteString(" " + strconv.FormatFloat(v.Float(), 'g', -1, 64) + "\n")
+	default:
+		sb.WriteString(" null\n")
+	}
+}
+
+func writeYAMLString(sb *strings.Builder, s string, indent int) {
+	body := strings.TrimRight(s, "\n")
+	if strings.Contains(body, "\n") && !strings.HasPrefix(body, " ") && !strings.ContainsAny(body, "\r\t") {
+		chomp := "-"
+		if strings.HasSuffix(s, "\n") {
+			chomp = ""
+			if len(s)-len(body) > 1 {
+				chomp = "+"
+			}
+		}
+		pad := strings.Repeat("  ", indent)
+		sb.WriteString(" |" + chomp + "\n")
+		for _, line := range strings.Split(body, "\n") {
+			if line == "" {
+				sb.WriteString("\n")
+				continue
+			}
+			sb.WriteString(pad + line + "\n")
+		}
+		for i := len(body) + 1; i < len(s); i++ {
+			sb.WriteString("\n")
+		}
+		return
+	}
+	if yamlPlainSafe(s) {
+		sb.WriteString(" " + s + "\n")
+		return
+	}
+	// JSON string escapes are valid in double-quoted YAML scalars
+	sb.WriteString(" " + strconv.Quote(s) + "\n")
+}
+
+// yamlPlainSafe reports whether s can be written unquoted without changing its type.
+func yamlPlainSafe(s string) bool {
+	if s == "" {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_' || r == '.' || r == '/':
+		case (r == '-' || r == ':') && i > 0:
+		default:
+			return false
+		}
+	}
+	return !strings.HasSuffix(s, ":")
+}
+
+func yamlFieldName(sf reflect.StructField) (name string, omitEmpty bool) {
+	tag := sf.Tag.Get("json")
+	if tag == "" {
+		return sf.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = sf.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heather7532/nuro/provider"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	got := string(
+		marshalYAML(
+			provider.JSONResult{
+				Provider: "ollama",
+				Model:    "llama3.1:8b",
+				Usage: provider.Usage{
+					PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7, TotalDuration: time.Second,
+				},
+				Text:         "line one\n\n  indented: yes\n",
+				FinishReason: provider.FinishStop,
+				Sources:      []string{"docs/a.md:1-9", "true"},
+			},
+		),
+	)
+	want := `provider: ollama
+model: llama3.1:8b
+usage:
+  prompt_tokens: 3
+  completion_tokens: 4
+  total_tokens: 7
+  total_duration: 1000000000
+text: |
+  line one
+
+    indented: yes
+finish_reason: stop
+sources:
+  - docs/a.md:1-9
+  - "true"
+`
+	if got != want {
+		t.Errorf("marshalYAML() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestYAMLStringQuoting(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"gpt-4o", " gpt-4o\n"},
+		{"", " \"\"\n"},
+		{"42", " \"42\"\n"},
+		{"no", " \"no\"\n"},
+		{"a: b", " \"a: b\"\n"},
+		{"-dash", " \"-dash\"\n"},
+		{"one\ntwo", " |-\n  one\n  two\n"},
+		{"a\nb\n\n", " |+\n  a\n  b\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.in, func(t *testing.T) {
+				var sb strings.Builder
+				writeYAMLString(&sb, tt.in, 1)
+				if sb.String() != tt.want {
+					t.Errorf("writeYAMLString(%q) = %q, want %q", tt.in, sb.String(), tt.want)
+				}
+			},
+		)
+	}
+}